@@ -8,6 +8,7 @@ import (
 	"os/signal"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/gin-gonic/gin"
@@ -17,7 +18,10 @@ import (
 
 	"github.com/nexus-orderbook-dex/backend/internal/blockchain"
 	"github.com/nexus-orderbook-dex/backend/internal/config"
+	"github.com/nexus-orderbook-dex/backend/internal/domain"
 	"github.com/nexus-orderbook-dex/backend/internal/handler"
+	"github.com/nexus-orderbook-dex/backend/internal/hub"
+	"github.com/nexus-orderbook-dex/backend/internal/repository"
 	"github.com/nexus-orderbook-dex/backend/internal/repository/postgres"
 	redisRepo "github.com/nexus-orderbook-dex/backend/internal/repository/redis"
 	"github.com/nexus-orderbook-dex/backend/internal/service"
@@ -33,14 +37,24 @@ func main() {
 	}
 	defer db.Close()
 
-	// Run migration
-	migrationSQL, err := os.ReadFile("migrations/001_init.sql")
-	if err != nil {
-		log.Printf("Warning: could not read migration file: %v", err)
-	} else {
-		if _, err := db.Exec(string(migrationSQL)); err != nil {
-			log.Printf("Warning: migration may have already been applied: %v", err)
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(context.Background(), db, os.Args[2:]); err != nil {
+			log.Fatalf("Migration failed: %v", err)
 		}
+		return
+	}
+
+	// Bring the schema up to date; servers booting together wait for each other
+	migrator, err := newMigrator(db)
+	if err != nil {
+		log.Fatalf("Failed to load migrations: %v", err)
+	}
+	applied, err := migrator.Up(context.Background())
+	if err != nil {
+		log.Fatalf("Failed to migrate database: %v", err)
+	}
+	for _, m := range applied {
+		log.Printf("Applied migration %s", m)
 	}
 
 	// Redis
@@ -52,12 +66,20 @@ func main() {
 		log.Fatalf("Failed to connect to Redis: %v", err)
 	}
 
+	// Repos
+	store := postgres.NewStore(db)
+	cache := redisRepo.NewOrderbookCache(rdb)
+
+	// Delivers settlement jobs and WebSocket updates once their command
+	// commits; the settlement worker is started below
+	settleCh := make(chan blockchain.SettleJob, 100)
+	relay := service.NewOutboxRelay(store, cache, settleCh)
+
 	// Blockchain client
 	privateKey := strings.TrimPrefix(cfg.PrivateKey, "0x")
 	chainID, _ := new(big.Int).SetString(cfg.ChainID, 10)
 
 	var bcClient *blockchain.Client
-	var settleCh chan blockchain.SettleJob
 
 	if cfg.ContractAddress != "" && cfg.PrivateKey != "" {
 		bcClient, err = blockchain.NewClient(cfg.RPCUrl, privateKey, chainID.Int64(), cfg.ContractAddress)
@@ -66,7 +88,6 @@ func main() {
 		}
 
 		// Settlement worker
-		settleCh = make(chan blockchain.SettleJob, 100)
 		worker, err := blockchain.NewSettlementWorker(bcClient)
 		if err != nil {
 			log.Fatalf("Failed to create settlement worker: %v", err)
@@ -77,11 +98,10 @@ func main() {
 		go worker.Run(ctx, settleCh)
 
 		// Event indexer
-		indexer := blockchain.NewIndexer(bcClient, &noopHandler{})
+		indexer := blockchain.NewIndexer(bcClient, service.NewChainEvents(store, relay))
 		go indexer.Start(ctx)
 	} else {
 		log.Println("Warning: blockchain not configured, settlement disabled")
-		settleCh = make(chan blockchain.SettleJob, 100)
 		// Drain settlement channel
 		go func() {
 			for job := range settleCh {
@@ -90,37 +110,76 @@ func main() {
 		}()
 	}
 
-	// Repos
-	orderRepo := postgres.NewOrderRepo(db)
-	tradeRepo := postgres.NewTradeRepo(db)
-	cache := redisRepo.NewOrderbookCache(rdb)
-
 	// Service
 	contractAddr := common.HexToAddress(cfg.ContractAddress)
 	if chainID == nil {
 		chainID = big.NewInt(31337)
 	}
-	orderSvc := service.NewOrderService(orderRepo, tradeRepo, cache, chainID, contractAddr, settleCh)
+	orderSvc := service.NewOrderService(store, cache, relay, chainID, contractAddr, cfg.SnapshotDir)
+	authSvc := service.NewAuthService(store, redisRepo.NewAuthCache(rdb), cfg.AuthDomain, chainID)
+	orderSvc.SetRiskLimits(riskLimits(cfg))
+	orderSvc.SetCircuitBreaker(service.CircuitBreaker{
+		Move:    cfg.CircuitBreakerMove,
+		Window:  cfg.CircuitBreakerWindow,
+		Halt:    cfg.CircuitBreakerHalt,
+		Auction: cfg.CircuitBreakerAuction,
+	})
 
-	// Load existing open orders
-	if err := orderSvc.LoadOpenOrders(context.Background(), "TKA-TKB"); err != nil {
-		log.Printf("Warning: failed to load open orders: %v", err)
+	// Restore every book from its snapshot and the journal
+	if err := orderSvc.RestoreBooks(context.Background()); err != nil {
+		log.Fatalf("Failed to restore orderbooks: %v", err)
 	}
+	if err := orderSvc.BackfillCandles(context.Background()); err != nil {
+		log.Fatalf("Failed to backfill candles: %v", err)
+	}
+	if err := orderSvc.RestoreTickers(context.Background()); err != nil {
+		log.Fatalf("Failed to restore tickers: %v", err)
+	}
+
+	// Expire orders past their signed expiry, end automatic halts and
+	// snapshot books periodically
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	go orderSvc.RunExpiry(workerCtx, 5*time.Second)
+	go orderSvc.RunMarketSchedule(workerCtx, time.Second)
+	go orderSvc.RunSnapshots(workerCtx, time.Minute)
+	go relay.Run(workerCtx)
+
+	// Fan the updates stream out to this server's WebSocket clients
+	wsHub := hub.New(hub.DefaultBufferSize)
+	go func() {
+		err := cache.ConsumeUpdates(workerCtx, func(u redisRepo.Update) {
+			wsHub.Broadcast(hub.Topic(u.Channel, u.Key), u.Data)
+		})
+		if err != nil && workerCtx.Err() == nil {
+			log.Fatalf("Updates stream failed: %v", err)
+		}
+	}()
 
 	// Handlers
 	orderH := handler.NewOrderHandler(orderSvc)
 	orderbookH := handler.NewOrderbookHandler(orderSvc)
 	tradeH := handler.NewTradeHandler(orderSvc)
-	wsH := handler.NewWSHandler(cache)
+	groupH := handler.NewGroupHandler(orderSvc)
+	marketH := handler.NewMarketHandler(orderSvc)
+	wsH := handler.NewWSHandler(cache, wsHub, orderSvc)
+	authH := handler.NewAuthHandler(authSvc)
+	adminH := handler.NewAdminHandler(orderSvc)
 
-	// Router
+	// Router. Client addresses come from X-Forwarded-For only when the
+	// request arrives through a trusted proxy, so that callers cannot pick
+	// their own rate limit bucket.
 	r := gin.Default()
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
 
 	// CORS
 	r.Use(func(c *gin.Context) {
 		c.Header("Access-Control-Allow-Origin", "*")
 		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		c.Header("Access-Control-Allow-Headers", "Content-Type, "+strings.Join(handler.AuthHeaders, ", "))
+		c.Header("Access-Control-Expose-Headers", "X-Next-Cursor, "+strings.Join(handler.RateLimitHeaders, ", "))
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
 			return
@@ -128,16 +187,74 @@ func main() {
 		c.Next()
 	})
 
+	// Every request is charged to its IP before authentication and, once
+	// authenticated, to its API key and address
+	throttle := handler.NewThrottle(redisRepo.NewRateLimiter(rdb), handler.RateLimits{
+		IP:     repository.RateLimit{Rate: cfg.RateLimitIP, Burst: 2 * cfg.RateLimitIP},
+		APIKey: repository.RateLimit{Rate: cfg.RateLimitAPIKey, Burst: 2 * cfg.RateLimitAPIKey},
+		Maker:  repository.RateLimit{Rate: cfg.RateLimitMaker, Burst: 2 * cfg.RateLimitMaker},
+	})
+	private := func(auth gin.HandlerFunc, weight float64) []gin.HandlerFunc {
+		return []gin.HandlerFunc{throttle.ByIP(weight), auth, throttle.ByAccount(weight)}
+	}
+
 	api := r.Group("/api")
+
+	// Signing in costs a signature recovery, like an order
+	auth := api.Group("/auth", throttle.ByIP(handler.WeightOrderEntry))
 	{
-		api.POST("/orders", orderH.SubmitOrder)
-		api.GET("/orders/:address", orderH.GetUserOrders)
-		api.DELETE("/orders/:id", orderH.CancelOrder)
-		api.GET("/orderbook", orderbookH.GetOrderbook)
-		api.GET("/trades", tradeH.GetTrades)
+		auth.POST("/nonce", authH.Nonce)
+		auth.POST("/login", authH.Login)
+	}
+	keys := api.Group("/auth", private(handler.RequireSession(authSvc), handler.WeightMarketData)...)
+	{
+		keys.POST("/logout", authH.Logout)
+		keys.GET("/keys", authH.ListAPIKeys)
+		keys.POST("/keys", authH.CreateAPIKey)
+		keys.DELETE("/keys/:id", authH.RevokeAPIKey)
 	}
 
-	r.GET("/ws", wsH.Handle)
+	trade := api.Group("", private(handler.RequireAuth(authSvc, domain.ScopeTrade), handler.WeightOrderEntry)...)
+	{
+		trade.POST("/orders", orderH.SubmitOrder)
+		trade.PUT("/orders/:id", orderH.ReplaceOrder)
+		trade.POST("/groups", groupH.SubmitGroup)
+	}
+	cancel := api.Group("", private(handler.RequireAuth(authSvc, domain.ScopeCancel), handler.WeightOrderEntry)...)
+	{
+		cancel.DELETE("/orders/:id", orderH.CancelOrder)
+		cancel.DELETE("/groups/:id", groupH.CancelGroup)
+	}
+	read := api.Group("", private(handler.RequireAuth(authSvc, domain.ScopeRead), handler.WeightMarketData)...)
+	{
+		read.GET("/orders/:address", orderH.GetUserOrders)
+		read.GET("/groups/:id", groupH.GetGroup)
+		read.GET("/trades/:address", tradeH.GetUserTrades)
+	}
+
+	market := api.Group("", throttle.ByIP(handler.WeightMarketData))
+	{
+		market.GET("/orderbook", orderbookH.GetOrderbook)
+		market.GET("/orderbook/l3", orderbookH.GetL3)
+		market.GET("/trades", tradeH.GetTrades)
+		market.GET("/candles", marketH.GetCandles)
+		market.GET("/ticker", marketH.GetTicker)
+		market.GET("/markets", marketH.GetMarketStatus)
+	}
+
+	if len(cfg.AdminAddresses) == 0 {
+		log.Println("Warning: ADMIN_ADDRESSES not set, admin API disabled")
+	}
+	admin := api.Group("/admin", private(handler.RequireAdmin(authSvc, cfg.AdminAddresses), handler.WeightMarketData)...)
+	{
+		admin.GET("/risk", adminH.GetRiskLimits)
+		admin.GET("/risk/:address", adminH.GetAccountRiskLimits)
+		admin.PUT("/risk/:address", adminH.SetAccountRiskLimits)
+		admin.DELETE("/risk/:address", adminH.DeleteAccountRiskLimits)
+		admin.PUT("/markets/:pair", adminH.SetMarketState)
+	}
+
+	r.GET("/ws", throttle.ByIP(handler.WeightMarketData), wsH.Handle)
 
 	// Health check
 	r.GET("/health", func(c *gin.Context) {
@@ -155,19 +272,29 @@ func main() {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 	log.Println("Shutting down...")
+	orderSvc.SaveSnapshots()
 }
 
-// noopHandler is a placeholder event handler.
-type noopHandler struct{}
-
-func (h *noopHandler) OnDeposit(user, token common.Address, amount *big.Int) {
-	log.Printf("Event: Deposit user=%s token=%s amount=%s", user.Hex(), token.Hex(), amount.String())
-}
-
-func (h *noopHandler) OnWithdraw(user, token common.Address, amount *big.Int) {
-	log.Printf("Event: Withdraw user=%s token=%s amount=%s", user.Hex(), token.Hex(), amount.String())
+// riskLimits returns the configured limits of addresses without an override.
+func riskLimits(cfg *config.Config) domain.RiskLimits {
+	return domain.RiskLimits{
+		MaxOpenOrders:    cfg.RiskMaxOpenOrders,
+		PriceBand:        cfg.RiskPriceBand,
+		MaxOrderNotional: tokenAmounts("RISK_MAX_ORDER_NOTIONAL", cfg.RiskMaxOrderNotional),
+		MaxOpenNotional:  tokenAmounts("RISK_MAX_OPEN_NOTIONAL", cfg.RiskMaxOpenNotional),
+	}
 }
 
-func (h *noopHandler) OnTradeSettled(buyHash, sellHash common.Hash, buyer, seller common.Address, baseAmount, quoteAmount *big.Int) {
-	log.Printf("Event: TradeSettled buy=%s sell=%s", buyHash.Hex(), sellHash.Hex())
+// tokenAmounts parses token:amount entries of the variable name.
+func tokenAmounts(name string, entries []string) map[string]*big.Int {
+	amounts := make(map[string]*big.Int, len(entries))
+	for _, entry := range entries {
+		token, amount, found := strings.Cut(entry, ":")
+		limit, ok := new(big.Int).SetString(strings.TrimSpace(amount), 10)
+		if !found || !common.IsHexAddress(strings.TrimSpace(token)) || !ok || limit.Sign() < 0 {
+			log.Fatalf("Invalid %s entry %q, want token:amount", name, entry)
+		}
+		amounts[common.HexToAddress(strings.TrimSpace(token)).Hex()] = limit
+	}
+	return amounts
 }
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/nexus-orderbook-dex/backend/internal/migrate"
+	"github.com/nexus-orderbook-dex/backend/migrations"
+)
+
+const migrateUsage = "usage: server migrate [up | down [steps] | status]"
+
+func newMigrator(db *sqlx.DB) (*migrate.Migrator, error) {
+	migs, err := migrate.Load(migrations.Files)
+	if err != nil {
+		return nil, fmt.Errorf("load migrations: %w", err)
+	}
+	return migrate.New(db, migs), nil
+}
+
+// runMigrate implements the migrate subcommand.
+func runMigrate(ctx context.Context, db *sqlx.DB, args []string) error {
+	migrator, err := newMigrator(db)
+	if err != nil {
+		return err
+	}
+
+	cmd := "up"
+	if len(args) > 0 {
+		cmd, args = args[0], args[1:]
+	}
+	switch {
+	case cmd == "up" && len(args) == 0:
+		applied, err := migrator.Up(ctx)
+		for _, m := range applied {
+			fmt.Printf("applied  %s\n", m)
+		}
+		if err == nil && len(applied) == 0 {
+			fmt.Println("schema is up to date")
+		}
+		return err
+
+	case cmd == "down" && len(args) <= 1:
+		steps := 1
+		if len(args) == 1 {
+			if steps, err = strconv.Atoi(args[0]); err != nil || steps < 1 {
+				return fmt.Errorf("invalid number of steps %q", args[0])
+			}
+		}
+		reverted, err := migrator.Down(ctx, steps)
+		for _, m := range reverted {
+			fmt.Printf("reverted %s\n", m)
+		}
+		return err
+
+	case cmd == "status" && len(args) == 0:
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, st := range statuses {
+			applied := "pending"
+			if st.AppliedAt != nil {
+				applied = st.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%-30s %s\n", st.Migration, applied)
+		}
+		return nil
+	}
+	return errors.New(migrateUsage)
+}
@@ -0,0 +1,41 @@
+package domain
+
+import "time"
+
+// Scope is a permission an API key grants on its address.
+type Scope string
+
+const (
+	ScopeRead   Scope = "read"   // private history and group reads
+	ScopeTrade  Scope = "trade"  // submit, amend and group orders
+	ScopeCancel Scope = "cancel" // cancel orders and groups
+)
+
+// Scopes are all the scopes, which sessions hold.
+var Scopes = []Scope{ScopeRead, ScopeTrade, ScopeCancel}
+
+func (s Scope) Valid() bool {
+	switch s {
+	case ScopeRead, ScopeTrade, ScopeCancel:
+		return true
+	}
+	return false
+}
+
+// APIKey is an HMAC credential bound to an address. The secret is only
+// shown when the key is created.
+type APIKey struct {
+	ID        string     `json:"id" db:"id"`
+	Address   string     `json:"address" db:"address"`
+	Secret    string     `json:"-" db:"secret"`
+	Label     string     `json:"label" db:"label"`
+	Scopes    []Scope    `json:"scopes" db:"-"`
+	CreatedAt time.Time  `json:"createdAt" db:"created_at"`
+	RevokedAt *time.Time `json:"revokedAt,omitempty" db:"revoked_at"`
+}
+
+// Session is the sign-in of an address, identified by a bearer token.
+type Session struct {
+	Address   string    `json:"address"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
@@ -0,0 +1,101 @@
+package domain
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"time"
+)
+
+// CandleInterval is the period of an OHLCV bar. Bars open at multiples of
+// their period since the Unix epoch, in UTC.
+type CandleInterval string
+
+const (
+	Interval1m  CandleInterval = "1m"
+	Interval5m  CandleInterval = "5m"
+	Interval15m CandleInterval = "15m"
+	Interval1h  CandleInterval = "1h"
+	Interval4h  CandleInterval = "4h"
+	Interval1d  CandleInterval = "1d"
+)
+
+// CandleIntervals are the periods maintained for every pair.
+var CandleIntervals = []CandleInterval{Interval1m, Interval5m, Interval15m, Interval1h, Interval4h, Interval1d}
+
+var intervalDurations = map[CandleInterval]time.Duration{
+	Interval1m:  time.Minute,
+	Interval5m:  5 * time.Minute,
+	Interval15m: 15 * time.Minute,
+	Interval1h:  time.Hour,
+	Interval4h:  4 * time.Hour,
+	Interval1d:  24 * time.Hour,
+}
+
+func ParseCandleInterval(s string) (CandleInterval, error) {
+	interval := CandleInterval(s)
+	if _, ok := intervalDurations[interval]; !ok {
+		return "", fmt.Errorf("invalid interval %q", s)
+	}
+	return interval, nil
+}
+
+func (i CandleInterval) Duration() time.Duration {
+	return intervalDurations[i]
+}
+
+// OpenTime returns the open time of the bar that t falls in.
+func (i CandleInterval) OpenTime(t time.Time) time.Time {
+	return t.UTC().Truncate(i.Duration())
+}
+
+// Candle is one OHLCV bar of a pair. Prices are quote per base unit, as in
+// Trade; volumes are in token base units.
+type Candle struct {
+	Pair        string         `json:"pair"`
+	Interval    CandleInterval `json:"interval"`
+	OpenTime    time.Time      `json:"openTime"`
+	Open        float64        `json:"open"`
+	High        float64        `json:"high"`
+	Low         float64        `json:"low"`
+	Close       float64        `json:"close"`
+	BaseVolume  *big.Int       `json:"baseVolume"`
+	QuoteVolume *big.Int       `json:"quoteVolume"`
+	TradeCount  int            `json:"tradeCount"`
+}
+
+// NewCandle returns the bar of a single trade, at the price it marks the
+// market at.
+func NewCandle(interval CandleInterval, trade *Trade) *Candle {
+	price := trade.MarketPrice()
+	return &Candle{
+		Pair:        trade.Pair,
+		Interval:    interval,
+		OpenTime:    interval.OpenTime(trade.CreatedAt),
+		Open:        price,
+		High:        price,
+		Low:         price,
+		Close:       price,
+		BaseVolume:  new(big.Int).Set(trade.BaseAmount),
+		QuoteVolume: new(big.Int).Set(trade.QuoteAmount),
+		TradeCount:  1,
+	}
+}
+
+// Merge folds later trades of the same bar into c.
+func (c *Candle) Merge(later *Candle) {
+	c.High = math.Max(c.High, later.High)
+	c.Low = math.Min(c.Low, later.Low)
+	c.Close = later.Close
+	c.BaseVolume = new(big.Int).Add(c.BaseVolume, later.BaseVolume)
+	c.QuoteVolume = new(big.Int).Add(c.QuoteVolume, later.QuoteVolume)
+	c.TradeCount += later.TradeCount
+}
+
+// Clone returns a copy of c that shares no state with it.
+func (c *Candle) Clone() *Candle {
+	clone := *c
+	clone.BaseVolume = new(big.Int).Set(c.BaseVolume)
+	clone.QuoteVolume = new(big.Int).Set(c.QuoteVolume)
+	return &clone
+}
@@ -0,0 +1,48 @@
+package domain
+
+import "time"
+
+type GroupType string
+
+const (
+	// GroupTypeOCO links legs so that any fill on one leg shrinks the others
+	// by the same amount, and a full fill cancels them.
+	GroupTypeOCO GroupType = "oco"
+	// GroupTypeBracket is an entry order plus exit legs (take-profit and/or
+	// stop-loss) that only become active for the quantity the entry filled.
+	// The exits are one-cancels-other among themselves.
+	GroupTypeBracket GroupType = "bracket"
+)
+
+type GroupStatus string
+
+const (
+	GroupStatusActive    GroupStatus = "active"
+	GroupStatusCompleted GroupStatus = "completed"
+	GroupStatusCancelled GroupStatus = "cancelled"
+)
+
+type GroupRole string
+
+const (
+	GroupRoleLeg   GroupRole = "leg"   // OCO leg
+	GroupRoleEntry GroupRole = "entry" // bracket entry
+	GroupRoleExit  GroupRole = "exit"  // bracket take-profit / stop-loss
+)
+
+type OrderGroup struct {
+	ID        string      `json:"id" db:"id"`
+	Type      GroupType   `json:"type" db:"type"`
+	Pair      string      `json:"pair" db:"pair"`
+	Maker     string      `json:"maker" db:"maker"`
+	Status    GroupStatus `json:"status" db:"status"`
+	CreatedAt time.Time   `json:"createdAt" db:"created_at"`
+	UpdatedAt time.Time   `json:"updatedAt" db:"updated_at"`
+}
+
+// GroupSubmission is the JSON payload for submitting an order group.
+// For a bracket, the first leg is the entry and the remaining legs are exits.
+type GroupSubmission struct {
+	Type GroupType         `json:"type" binding:"required"`
+	Legs []OrderSubmission `json:"legs" binding:"required,min=2,dive"`
+}
@@ -0,0 +1,76 @@
+package domain
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// History pages are ordered newest first by (created_at, id).
+
+// Cursor marks the last item of a history page; the next page starts right
+// after it.
+type Cursor struct {
+	CreatedAt time.Time
+	ID        string
+}
+
+// String encodes the cursor as an opaque URL-safe token.
+func (c Cursor) String() string {
+	raw := strconv.FormatInt(c.CreatedAt.UnixNano(), 10) + ":" + c.ID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// ParseCursor decodes a token produced by Cursor.String.
+func ParseCursor(token string) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	nanos, id, ok := strings.Cut(string(raw), ":")
+	if !ok || id == "" {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	n, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	return &Cursor{CreatedAt: time.Unix(0, n), ID: id}, nil
+}
+
+// Before reports whether an item created at createdAt with the given ID
+// comes after the cursor in newest-first order.
+func (c *Cursor) Before(createdAt time.Time, id string) bool {
+	if !createdAt.Equal(c.CreatedAt) {
+		return createdAt.Before(c.CreatedAt)
+	}
+	return id < c.ID
+}
+
+// OrderQuery selects a page of a maker's orders. Empty fields do not filter;
+// From is inclusive and To exclusive.
+type OrderQuery struct {
+	Maker  string
+	Pair   string
+	Side   Side
+	Status OrderStatus
+	From   time.Time
+	To     time.Time
+	After  *Cursor
+	Limit  int
+}
+
+// TradeQuery selects a page of trades of a pair, of an address, or both.
+// With an Address, Side narrows to trades where it was the buyer (buy) or
+// the seller (sell).
+type TradeQuery struct {
+	Pair    string
+	Address string
+	Side    Side
+	From    time.Time
+	To      time.Time
+	After   *Cursor
+	Limit   int
+}
@@ -0,0 +1,50 @@
+package domain
+
+import "time"
+
+// MarketState is the trading phase of a pair.
+type MarketState string
+
+const (
+	MarketOpen       MarketState = "open"        // continuous matching
+	MarketHalted     MarketState = "halted"      // the book is frozen: no orders, amendments or cancels
+	MarketCancelOnly MarketState = "cancel_only" // orders can only be cancelled
+	MarketAuction    MarketState = "auction"     // orders rest without matching
+)
+
+func (s MarketState) Valid() bool {
+	switch s {
+	case MarketOpen, MarketHalted, MarketCancelOnly, MarketAuction:
+		return true
+	}
+	return false
+}
+
+// AcceptsOrders reports whether new orders and amendments may enter the book.
+func (s MarketState) AcceptsOrders() bool {
+	return s == MarketOpen || s == MarketAuction
+}
+
+// AcceptsCancels reports whether orders may leave the book at their maker's
+// request.
+func (s MarketState) AcceptsCancels() bool {
+	return s != MarketHalted
+}
+
+// Who or what put a pair in its state.
+const (
+	MarketSourceAdmin          = "admin"
+	MarketSourceCircuitBreaker = "circuit_breaker"
+	MarketSourceSchedule       = "schedule" // the end of an automatic halt or auction
+)
+
+// MarketStatus is the state of a pair and how it got there.
+type MarketStatus struct {
+	Pair   string      `json:"pair"`
+	State  MarketState `json:"state"`
+	Source string      `json:"source,omitempty"`
+	Reason string      `json:"reason,omitempty"`
+	Since  time.Time   `json:"since"`
+	// ResumeAt is when an automatic halt or auction ends on its own
+	ResumeAt *time.Time `json:"resumeAt,omitempty"`
+}
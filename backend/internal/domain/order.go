@@ -19,6 +19,16 @@ const (
 	OrderStatusPartiallyFilled OrderStatus = "partially_filled"
 	OrderStatusFilled          OrderStatus = "filled"
 	OrderStatusCancelled       OrderStatus = "cancelled"
+	OrderStatusPending         OrderStatus = "pending" // conditional order waiting for its trigger
+	OrderStatusExpired         OrderStatus = "expired"
+)
+
+type OrderType string
+
+const (
+	OrderTypeLimit      OrderType = "limit"
+	OrderTypeStopLoss   OrderType = "stop_loss"
+	OrderTypeTakeProfit OrderType = "take_profit"
 )
 
 type Order struct {
@@ -38,6 +48,25 @@ type Order struct {
 	Pair       string      `json:"pair" db:"pair"`
 	CreatedAt  time.Time   `json:"createdAt" db:"created_at"`
 	UpdatedAt  time.Time   `json:"updatedAt" db:"updated_at"`
+
+	// Conditional orders. The trigger is an off-chain attribute: the signed
+	// order is an ordinary limit order that is only released to the book
+	// once the last trade price crosses TriggerPrice.
+	Type         OrderType  `json:"type" db:"order_type"`
+	TriggerPrice float64    `json:"triggerPrice,omitempty" db:"trigger_price"`
+	TriggeredAt  *time.Time `json:"triggeredAt,omitempty" db:"triggered_at"`
+
+	// Order groups (OCO / bracket). BaseCap limits how much base the engine
+	// may fill in total, below the signed amount, as sibling legs fill.
+	GroupID   string    `json:"groupId,omitempty" db:"group_id"`
+	GroupRole GroupRole `json:"groupRole,omitempty" db:"group_role"`
+	BaseCap   *big.Int  `json:"baseCap,omitempty" db:"base_cap"`
+
+	// Iceberg orders only show DisplayBase of their remaining size in the book.
+	DisplayBase *big.Int `json:"displayBase,omitempty" db:"display_base"`
+
+	// ReplacesID links an amended order to the order it replaced.
+	ReplacesID string `json:"replacesId,omitempty" db:"replaces_id"`
 }
 
 // Price returns the price as a float64 (quote/base).
@@ -56,12 +85,104 @@ func (o *Order) Price() float64 {
 	return price
 }
 
+// Clone returns a deep copy of the order, safe to keep while the original
+// continues to be filled.
+func (o *Order) Clone() *Order {
+	c := *o
+	c.AmountSell = cloneBigInt(o.AmountSell)
+	c.AmountBuy = cloneBigInt(o.AmountBuy)
+	c.Salt = cloneBigInt(o.Salt)
+	c.FilledBase = cloneBigInt(o.FilledBase)
+	c.BaseCap = cloneBigInt(o.BaseCap)
+	c.DisplayBase = cloneBigInt(o.DisplayBase)
+	if o.TriggeredAt != nil {
+		t := *o.TriggeredAt
+		c.TriggeredAt = &t
+	}
+	return &c
+}
+
+func cloneBigInt(n *big.Int) *big.Int {
+	if n == nil {
+		return nil
+	}
+	return new(big.Int).Set(n)
+}
+
+// IsConditional reports whether the order waits for a trigger before entering the book.
+func (o *Order) IsConditional() bool {
+	return o.Type == OrderTypeStopLoss || o.Type == OrderTypeTakeProfit
+}
+
+// Triggered reports whether a trade at lastPrice releases the order.
+// A stop-loss fires when price moves against the position it protects
+// (down for sells, up for buys); a take-profit fires the other way.
+func (o *Order) Triggered(lastPrice float64) bool {
+	switch o.Type {
+	case OrderTypeStopLoss:
+		if o.Side == SideSell {
+			return lastPrice <= o.TriggerPrice
+		}
+		return lastPrice >= o.TriggerPrice
+	case OrderTypeTakeProfit:
+		if o.Side == SideSell {
+			return lastPrice >= o.TriggerPrice
+		}
+		return lastPrice <= o.TriggerPrice
+	}
+	return false
+}
+
+// BaseAmount returns the signed base token amount of the order.
+func (o *Order) BaseAmount() *big.Int {
+	if o.Side == SideBuy {
+		return o.AmountBuy
+	}
+	return o.AmountSell
+}
+
+// QuoteAmount returns the signed quote token amount of the order.
+func (o *Order) QuoteAmount() *big.Int {
+	if o.Side == SideBuy {
+		return o.AmountSell
+	}
+	return o.AmountBuy
+}
+
+// QuoteToken returns the address of the token the order is priced in.
+func (o *Order) QuoteToken() string {
+	if o.Side == SideBuy {
+		return o.TokenSell
+	}
+	return o.TokenBuy
+}
+
+// RemainingSell returns how much of TokenSell the order still offers, in
+// proportion to its remaining base.
+func (o *Order) RemainingSell() *big.Int {
+	remaining := o.RemainingBase()
+	if remaining.Sign() <= 0 {
+		return new(big.Int)
+	}
+	if o.Side == SideSell {
+		return remaining
+	}
+	sell := new(big.Int).Mul(o.AmountSell, remaining)
+	return sell.Quo(sell, o.AmountBuy)
+}
+
+// IsIceberg reports whether the order hides part of its size.
+func (o *Order) IsIceberg() bool {
+	return o.DisplayBase != nil && o.DisplayBase.Sign() > 0
+}
+
 // RemainingBase returns remaining base token amount to fill.
 func (o *Order) RemainingBase() *big.Int {
-	if o.Side == SideBuy {
-		return new(big.Int).Sub(o.AmountBuy, o.FilledBase)
+	size := o.BaseAmount()
+	if o.BaseCap != nil && o.BaseCap.Cmp(size) < 0 {
+		size = o.BaseCap
 	}
-	return new(big.Int).Sub(o.AmountSell, o.FilledBase)
+	return new(big.Int).Sub(size, o.FilledBase)
 }
 
 // OrderSubmission is the JSON payload for submitting a new order.
@@ -77,4 +198,11 @@ type OrderSubmission struct {
 	Signature  string `json:"signature" binding:"required"`
 	Side       Side   `json:"side" binding:"required"`
 	Pair       string `json:"pair" binding:"required"`
+
+	// Optional conditional order attributes; Type defaults to limit.
+	Type         OrderType `json:"type"`
+	TriggerPrice float64   `json:"triggerPrice"`
+
+	// Optional visible base amount for iceberg orders.
+	DisplayAmount string `json:"displayAmount"`
 }
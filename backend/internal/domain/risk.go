@@ -0,0 +1,110 @@
+package domain
+
+import (
+	"math/big"
+	"strings"
+	"time"
+)
+
+// RiskLimits bound what a maker may have in the market. A zero field means
+// no limit.
+type RiskLimits struct {
+	MaxOpenOrders int     `json:"maxOpenOrders"` // open and pending orders per pair
+	PriceBand     float64 `json:"priceBand"`     // distance from the last trade, as a fraction of it
+	// Quote amount of a single order, by quote token, and unfilled amount
+	// offered of a token, by token address. Each limit is in the base units
+	// of its own token; tokens without one are not capped.
+	MaxOrderNotional map[string]*big.Int `json:"maxOrderNotional"`
+	MaxOpenNotional  map[string]*big.Int `json:"maxOpenNotional"`
+}
+
+// OrderNotionalCap returns the limit on the quote amount of an order quoted
+// in token, nil if it has none. Addresses match in any case.
+func (l RiskLimits) OrderNotionalCap(token string) *big.Int {
+	return tokenCap(l.MaxOrderNotional, token)
+}
+
+// OpenNotionalCap returns the limit on the unfilled amount offered of token,
+// nil if it has none. Addresses match in any case.
+func (l RiskLimits) OpenNotionalCap(token string) *big.Int {
+	return tokenCap(l.MaxOpenNotional, token)
+}
+
+func tokenCap(caps map[string]*big.Int, token string) *big.Int {
+	for t, limit := range caps {
+		if strings.EqualFold(t, token) {
+			return limit
+		}
+	}
+	return nil
+}
+
+// RiskOverride replaces some of the global limits for one address. Nil
+// fields, and tokens missing from the notional maps, keep the global value;
+// zero lifts the limit.
+type RiskOverride struct {
+	Address          string              `json:"address"`
+	MaxOpenOrders    *int                `json:"maxOpenOrders,omitempty"`
+	MaxOrderNotional map[string]*big.Int `json:"maxOrderNotional,omitempty"`
+	MaxOpenNotional  map[string]*big.Int `json:"maxOpenNotional,omitempty"`
+	PriceBand        *float64            `json:"priceBand,omitempty"`
+	UpdatedAt        time.Time           `json:"updatedAt"`
+}
+
+// With returns the limits an override leaves in force.
+func (l RiskLimits) With(o *RiskOverride) RiskLimits {
+	if o == nil {
+		return l
+	}
+	if o.MaxOpenOrders != nil {
+		l.MaxOpenOrders = *o.MaxOpenOrders
+	}
+	l.MaxOrderNotional = mergeTokenCaps(l.MaxOrderNotional, o.MaxOrderNotional)
+	l.MaxOpenNotional = mergeTokenCaps(l.MaxOpenNotional, o.MaxOpenNotional)
+	if o.PriceBand != nil {
+		l.PriceBand = *o.PriceBand
+	}
+	return l
+}
+
+// mergeTokenCaps returns caps with the tokens of override replaced.
+func mergeTokenCaps(caps, override map[string]*big.Int) map[string]*big.Int {
+	if len(override) == 0 {
+		return caps
+	}
+	merged := make(map[string]*big.Int, len(caps)+len(override))
+	for t, limit := range caps {
+		merged[strings.ToLower(t)] = limit
+	}
+	for t, limit := range override {
+		merged[strings.ToLower(t)] = limit
+	}
+	return merged
+}
+
+// Clone returns a deep copy of the override.
+func (o *RiskOverride) Clone() *RiskOverride {
+	c := *o
+	if o.MaxOpenOrders != nil {
+		n := *o.MaxOpenOrders
+		c.MaxOpenOrders = &n
+	}
+	c.MaxOrderNotional = cloneTokenCaps(o.MaxOrderNotional)
+	c.MaxOpenNotional = cloneTokenCaps(o.MaxOpenNotional)
+	if o.PriceBand != nil {
+		band := *o.PriceBand
+		c.PriceBand = &band
+	}
+	return &c
+}
+
+func cloneTokenCaps(caps map[string]*big.Int) map[string]*big.Int {
+	if caps == nil {
+		return nil
+	}
+	c := make(map[string]*big.Int, len(caps))
+	for t, limit := range caps {
+		c[t] = cloneBigInt(limit)
+	}
+	return c
+}
@@ -0,0 +1,25 @@
+package domain
+
+import (
+	"math/big"
+	"time"
+)
+
+// Ticker is the rolling 24h market summary of a pair. Open is the price of
+// the first trade in the window; BestBid and BestAsk are nil when that side
+// of the book is empty.
+type Ticker struct {
+	Pair               string    `json:"pair"`
+	LastPrice          float64   `json:"lastPrice"`
+	BestBid            *float64  `json:"bestBid"`
+	BestAsk            *float64  `json:"bestAsk"`
+	Open               float64   `json:"open"`
+	High               float64   `json:"high"`
+	Low                float64   `json:"low"`
+	BaseVolume         *big.Int  `json:"baseVolume"`
+	QuoteVolume        *big.Int  `json:"quoteVolume"`
+	TradeCount         int       `json:"tradeCount"`
+	PriceChange        float64   `json:"priceChange"`
+	PriceChangePercent float64   `json:"priceChangePercent"`
+	Time               time.Time `json:"time"`
+}
@@ -15,7 +15,17 @@ type Trade struct {
 	BaseAmount    *big.Int `json:"baseAmount" db:"base_amount"`
 	QuoteAmount   *big.Int `json:"quoteAmount" db:"quote_amount"`
 	Price         float64  `json:"price" db:"price"`
+	ClearingPrice float64  `json:"clearingPrice,omitempty" db:"clearing_price"` // of the auction that made it, if any
 	TxHash        string   `json:"txHash" db:"tx_hash"`
 	SettledOnChain bool    `json:"settledOnChain" db:"settled_on_chain"`
 	CreatedAt     time.Time `json:"createdAt" db:"created_at"`
 }
+
+// MarketPrice returns the price the trade marks the market at: its
+// auction's clearing price, or else its own.
+func (t *Trade) MarketPrice() float64 {
+	if t.ClearingPrice > 0 {
+		return t.ClearingPrice
+	}
+	return t.Price
+}
@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nexus-orderbook-dex/backend/internal/domain"
+	"github.com/nexus-orderbook-dex/backend/internal/service"
+)
+
+// AdminHandler serves the exchange's controls, behind RequireAdmin.
+type AdminHandler struct {
+	svc *service.OrderService
+}
+
+func NewAdminHandler(svc *service.OrderService) *AdminHandler {
+	return &AdminHandler{svc: svc}
+}
+
+// GetRiskLimits returns the global risk limits and every override.
+func (h *AdminHandler) GetRiskLimits(c *gin.Context) {
+	overrides, err := h.svc.ListRiskOverrides(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if overrides == nil {
+		overrides = []*domain.RiskOverride{}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"limits":    h.svc.GlobalRiskLimits(),
+		"overrides": overrides,
+	})
+}
+
+// GetAccountRiskLimits returns the limits in force for an address and its
+// override, if any.
+func (h *AdminHandler) GetAccountRiskLimits(c *gin.Context) {
+	limits, override, err := h.svc.RiskLimits(c.Request.Context(), c.Param("address"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"limits":   limits,
+		"override": override,
+	})
+}
+
+// SetAccountRiskLimits replaces the override of an address. Omitted limits
+// keep their global value.
+func (h *AdminHandler) SetAccountRiskLimits(c *gin.Context) {
+	var override domain.RiskOverride
+	if err := c.ShouldBindJSON(&override); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	override.Address = c.Param("address")
+	if err := h.svc.SetRiskOverride(c.Request.Context(), &override); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	limits, _, err := h.svc.RiskLimits(c.Request.Context(), override.Address)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"limits":   limits,
+		"override": override,
+	})
+}
+
+// DeleteAccountRiskLimits puts an address back on the global limits.
+func (h *AdminHandler) DeleteAccountRiskLimits(c *gin.Context) {
+	deleted, err := h.svc.DeleteRiskOverride(c.Request.Context(), c.Param("address"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !deleted {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no override for this address"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "deleted"})
+}
+
+// SetMarketState moves a pair to another trading state by hand, ending any
+// automatic halt.
+func (h *AdminHandler) SetMarketState(c *gin.Context) {
+	var req struct {
+		State  domain.MarketState `json:"state" binding:"required"`
+		Reason string             `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !req.State.Valid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unknown state " + string(req.State)})
+		return
+	}
+	status, err := h.svc.SetMarketState(c.Request.Context(), c.Param("pair"), req.State, req.Reason)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gin-gonic/gin"
+
+	"github.com/nexus-orderbook-dex/backend/internal/blockchain"
+	"github.com/nexus-orderbook-dex/backend/internal/repository/memory"
+	"github.com/nexus-orderbook-dex/backend/internal/service"
+)
+
+func TestAdmin_RiskOverridesNeedAnAdminSession(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store, cache := memory.NewStore(), memory.NewCache()
+	relay := service.NewOutboxRelay(store, cache, make(chan blockchain.SettleJob))
+	svc := service.NewOrderService(store, cache, relay, big.NewInt(31337), common.Address{}, t.TempDir())
+	auth := service.NewAuthService(store, memory.NewAuthCache(), "localhost:3000", big.NewInt(31337))
+
+	admin, adminToken := signIn(t, auth)
+	trader, traderToken := signIn(t, auth)
+
+	adminH := NewAdminHandler(svc)
+	r := gin.New()
+	group := r.Group("/api/admin", RequireAdmin(auth, []string{strings.ToLower(admin)}))
+	group.GET("/risk/:address", adminH.GetAccountRiskLimits)
+	group.PUT("/risk/:address", adminH.SetAccountRiskLimits)
+
+	do := func(method, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/admin/risk/"+trader, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := do("PUT", traderToken, `{"maxOpenOrders": 1000}`); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected a trader to get 403, got %d", rec.Code)
+	}
+	if rec := do("PUT", adminToken, `{"maxOpenOrders": -1}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected a negative limit to be refused, got %d", rec.Code)
+	}
+	if rec := do("PUT", adminToken, `{"maxOpenOrders": 1000, "priceBand": 0.5}`); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+
+	rec := do("GET", adminToken, "")
+	var resp struct {
+		Limits struct {
+			MaxOpenOrders int     `json:"maxOpenOrders"`
+			PriceBand     float64 `json:"priceBand"`
+		} `json:"limits"`
+		Override struct {
+			Address string `json:"address"`
+		} `json:"override"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Limits.MaxOpenOrders != 1000 || resp.Limits.PriceBand != 0.5 || resp.Override.Address != trader {
+		t.Fatalf("unexpected limits %s", rec.Body)
+	}
+}
@@ -0,0 +1,152 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nexus-orderbook-dex/backend/internal/domain"
+	"github.com/nexus-orderbook-dex/backend/internal/service"
+)
+
+// Headers of requests signed with an API key.
+const (
+	apiKeyHeader       = "X-API-Key"
+	apiTimestampHeader = "X-API-Timestamp"
+	apiNonceHeader     = "X-API-Nonce"
+	apiSignatureHeader = "X-API-Signature"
+)
+
+// AuthHeaders are the request headers authentication reads, for CORS.
+var AuthHeaders = []string{"Authorization", apiKeyHeader, apiTimestampHeader, apiNonceHeader, apiSignatureHeader}
+
+// maxSignedBody bounds the body read to verify a signed request.
+const maxSignedBody = 1 << 20
+
+const principalKey = "principal"
+
+// RequireAuth lets a request through if it carries a session token
+// ("Authorization: Bearer <token>") or an API key signature and holds
+// scope. Without credentials it answers 401, without the scope 403.
+func RequireAuth(auth *service.AuthService, scope domain.Scope) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		p, ok := authenticate(c, auth)
+		if !ok {
+			return
+		}
+		if !p.Allows(scope) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "api key lacks the " + string(scope) + " scope"})
+			return
+		}
+		c.Set(principalKey, p)
+		c.Next()
+	}
+}
+
+// RequireSession lets a request through only with a session token, for
+// managing API keys, which keys cannot do themselves.
+func RequireSession(auth *service.AuthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		p, ok := authenticate(c, auth)
+		if !ok {
+			return
+		}
+		if p.KeyID != "" {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "requires a session"})
+			return
+		}
+		c.Set(principalKey, p)
+		c.Next()
+	}
+}
+
+// RequireAdmin lets a request through only with the session of one of the
+// admin addresses. API keys cannot administer the exchange.
+func RequireAdmin(auth *service.AuthService, admins []string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		p, ok := authenticate(c, auth)
+		if !ok {
+			return
+		}
+		for _, admin := range admins {
+			if p.KeyID == "" && p.Owns(admin) {
+				c.Set(principalKey, p)
+				c.Next()
+				return
+			}
+		}
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "requires an admin session"})
+	}
+}
+
+// authenticate resolves the request's credentials, aborting the request if
+// they are missing or rejected.
+func authenticate(c *gin.Context, auth *service.AuthService) (*service.Principal, bool) {
+	var (
+		p   *service.Principal
+		err error
+	)
+	ctx := c.Request.Context()
+	if keyID := c.GetHeader(apiKeyHeader); keyID != "" {
+		var body []byte
+		if c.Request.Body != nil {
+			body, err = io.ReadAll(io.LimitReader(c.Request.Body, maxSignedBody+1))
+			if err != nil || len(body) > maxSignedBody {
+				c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{"error": "request body too large"})
+				return nil, false
+			}
+			// Handlers read the body again
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		}
+		p, err = auth.VerifyRequest(ctx, service.SignedRequest{
+			KeyID:     keyID,
+			Timestamp: c.GetHeader(apiTimestampHeader),
+			Nonce:     c.GetHeader(apiNonceHeader),
+			Signature: c.GetHeader(apiSignatureHeader),
+			Method:    c.Request.Method,
+			Path:      c.Request.URL.RequestURI(),
+			Body:      body,
+		})
+	} else if token, ok := bearerToken(c); ok {
+		p, err = auth.Session(ctx, token)
+	} else {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "authentication required"})
+		return nil, false
+	}
+
+	if errors.Is(err, service.ErrUnauthorized) {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return p, true
+}
+
+func bearerToken(c *gin.Context) (string, bool) {
+	scheme, token, ok := strings.Cut(c.GetHeader("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+	return token, true
+}
+
+// principal returns the authenticated principal of a request that passed
+// RequireAuth or RequireSession.
+func principal(c *gin.Context) *service.Principal {
+	return c.MustGet(principalKey).(*service.Principal)
+}
+
+// requireOwner answers 403 unless the request acts for address.
+func requireOwner(c *gin.Context, address string) bool {
+	if !principal(c).Owns(address) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "not owned by the authenticated address"})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nexus-orderbook-dex/backend/internal/domain"
+	"github.com/nexus-orderbook-dex/backend/internal/service"
+)
+
+type AuthHandler struct {
+	auth *service.AuthService
+}
+
+func NewAuthHandler(auth *service.AuthService) *AuthHandler {
+	return &AuthHandler{auth: auth}
+}
+
+// Nonce issues a nonce for a sign-in message.
+func (h *AuthHandler) Nonce(c *gin.Context) {
+	nonce, err := h.auth.Nonce(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"nonce": nonce})
+}
+
+// Login exchanges a signed sign-in message for a session token.
+func (h *AuthHandler) Login(c *gin.Context) {
+	var req struct {
+		Message   string `json:"message" binding:"required"`
+		Signature string `json:"signature" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	token, session, err := h.auth.Login(c.Request.Context(), req.Message, req.Signature)
+	if errors.Is(err, service.ErrUnauthorized) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"token":     token,
+		"address":   session.Address,
+		"expiresAt": session.ExpiresAt,
+	})
+}
+
+// Logout ends the request's session.
+func (h *AuthHandler) Logout(c *gin.Context) {
+	token, _ := bearerToken(c)
+	if err := h.auth.Logout(c.Request.Context(), token); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "logged_out"})
+}
+
+// CreateAPIKey creates a key for the session's address. The response is the
+// only time the secret is shown.
+func (h *AuthHandler) CreateAPIKey(c *gin.Context) {
+	var req struct {
+		Label  string         `json:"label"`
+		Scopes []domain.Scope `json:"scopes" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	key, err := h.auth.CreateAPIKey(c.Request.Context(), principal(c).Address, req.Label, req.Scopes)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"key":    key,
+		"secret": key.Secret,
+	})
+}
+
+// ListAPIKeys lists the session address's keys, without secrets.
+func (h *AuthHandler) ListAPIKeys(c *gin.Context) {
+	keys, err := h.auth.ListAPIKeys(c.Request.Context(), principal(c).Address)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if keys == nil {
+		keys = []*domain.APIKey{}
+	}
+	c.JSON(http.StatusOK, keys)
+}
+
+func (h *AuthHandler) RevokeAPIKey(c *gin.Context) {
+	if err := h.auth.RevokeAPIKey(c.Request.Context(), principal(c).Address, c.Param("id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "revoked"})
+}
@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"context"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/gin-gonic/gin"
+
+	"github.com/nexus-orderbook-dex/backend/internal/blockchain"
+	"github.com/nexus-orderbook-dex/backend/internal/domain"
+	"github.com/nexus-orderbook-dex/backend/internal/repository/memory"
+	"github.com/nexus-orderbook-dex/backend/internal/service"
+	"github.com/nexus-orderbook-dex/backend/pkg/siwe"
+)
+
+// signIn logs a new account in and returns its address and session token.
+func signIn(t *testing.T, auth *service.AuthService) (string, string) {
+	t.Helper()
+	ctx := context.Background()
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce, err := auth.Nonce(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := (&siwe.Message{
+		Domain:   "localhost:3000",
+		Address:  crypto.PubkeyToAddress(key.PublicKey),
+		URI:      "http://localhost:3000",
+		Version:  "1",
+		ChainID:  31337,
+		Nonce:    nonce,
+		IssuedAt: time.Now(),
+	}).String()
+	sig, err := crypto.Sign(accounts.TextHash([]byte(msg)), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, session, err := auth.Login(ctx, msg, hexutil.Encode(sig))
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	return session.Address, token
+}
+
+func TestAuth_PrivateEndpointsBelongToTheirOwner(t *testing.T) {
+	ctx := context.Background()
+	gin.SetMode(gin.TestMode)
+	store, cache := memory.NewStore(), memory.NewCache()
+	relay := service.NewOutboxRelay(store, cache, make(chan blockchain.SettleJob))
+	svc := service.NewOrderService(store, cache, relay, big.NewInt(31337), common.Address{}, t.TempDir())
+	auth := service.NewAuthService(store, memory.NewAuthCache(), "localhost:3000", big.NewInt(31337))
+
+	orderH := NewOrderHandler(svc)
+	r := gin.New()
+	r.GET("/api/orders/:address", RequireAuth(auth, domain.ScopeRead), orderH.GetUserOrders)
+	r.DELETE("/api/orders/:id", RequireAuth(auth, domain.ScopeCancel), orderH.CancelOrder)
+
+	owner, ownerToken := signIn(t, auth)
+	_, otherToken := signIn(t, auth)
+	order := &domain.Order{
+		ID:         "o1",
+		Maker:      owner,
+		Pair:       "TKA-TKB",
+		Side:       domain.SideBuy,
+		AmountSell: big.NewInt(100),
+		AmountBuy:  big.NewInt(50),
+		FilledBase: big.NewInt(0),
+		Status:     domain.OrderStatusOpen,
+	}
+	if err := store.Orders().Create(ctx, order); err != nil {
+		t.Fatal(err)
+	}
+	// Only orders in the book can be cancelled
+	if err := svc.RestoreBooks(ctx); err != nil {
+		t.Fatal(err)
+	}
+	readKey, err := auth.CreateAPIKey(ctx, owner, "reader", []domain.Scope{domain.ScopeRead})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	do := func(method, path string, header http.Header) int {
+		req := httptest.NewRequest(method, path, nil)
+		for k, v := range header {
+			req.Header.Set(k, v[0])
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	bearer := func(token string) http.Header {
+		return http.Header{"Authorization": {"Bearer " + token}}
+	}
+	signed := func(method, path, nonce string) http.Header {
+		ts := strconv.FormatInt(time.Now().UnixMilli(), 10)
+		return http.Header{
+			apiKeyHeader:       {readKey.ID},
+			apiTimestampHeader: {ts},
+			apiNonceHeader:     {nonce},
+			apiSignatureHeader: {service.SignRequest(readKey.Secret, ts, nonce, method, path, nil)},
+		}
+	}
+
+	history := "/api/orders/" + owner
+	for _, c := range []struct {
+		name   string
+		method string
+		path   string
+		header http.Header
+		want   int
+	}{
+		{"anonymous history", "GET", history, nil, http.StatusUnauthorized},
+		{"bad token", "GET", history, bearer("nope"), http.StatusUnauthorized},
+		{"other's history", "GET", history, bearer(otherToken), http.StatusForbidden},
+		{"own history", "GET", history, bearer(ownerToken), http.StatusOK},
+		{"history with a read key", "GET", history, signed("GET", history, "n1"), http.StatusOK},
+		{"replayed key request", "GET", history, signed("GET", history, "n1"), http.StatusUnauthorized},
+		{"cancel with a read key", "DELETE", "/api/orders/o1", signed("DELETE", "/api/orders/o1", "n2"), http.StatusForbidden},
+		{"other's cancel", "DELETE", "/api/orders/o1", bearer(otherToken), http.StatusForbidden},
+		{"own cancel", "DELETE", "/api/orders/o1", bearer(ownerToken), http.StatusOK},
+	} {
+		if got := do(c.method, c.path, c.header); got != c.want {
+			t.Errorf("%s: expected %d, got %d", c.name, c.want, got)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nexus-orderbook-dex/backend/internal/domain"
+	"github.com/nexus-orderbook-dex/backend/internal/service"
+)
+
+type GroupHandler struct {
+	svc *service.OrderService
+}
+
+func NewGroupHandler(svc *service.OrderService) *GroupHandler {
+	return &GroupHandler{svc: svc}
+}
+
+func (h *GroupHandler) SubmitGroup(c *gin.Context) {
+	var sub domain.GroupSubmission
+	if err := c.ShouldBindJSON(&sub); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	for _, leg := range sub.Legs {
+		if !requireOwner(c, leg.Maker) {
+			return
+		}
+	}
+
+	group, legs, err := h.svc.SubmitGroup(c.Request.Context(), sub)
+	if err != nil {
+		writeOrderError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"group":  group,
+		"orders": legs,
+	})
+}
+
+func (h *GroupHandler) GetGroup(c *gin.Context) {
+	group, legs, err := h.svc.GetGroup(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if !requireOwner(c, group.Maker) {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"group":  group,
+		"orders": legs,
+	})
+}
+
+func (h *GroupHandler) CancelGroup(c *gin.Context) {
+	group, _, err := h.svc.GetGroup(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if !requireOwner(c, group.Maker) {
+		return
+	}
+	if err := h.svc.CancelGroup(c.Request.Context(), c.Param("id")); err != nil {
+		writeOrderError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "cancelled"})
+}
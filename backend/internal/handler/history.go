@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nexus-orderbook-dex/backend/internal/domain"
+)
+
+// nextCursorHeader carries the cursor of the next history page, so that
+// the response body stays a plain array. It is absent on the last page.
+const nextCursorHeader = "X-Next-Cursor"
+
+// pageParams are the query parameters shared by the history endpoints.
+type pageParams struct {
+	side  domain.Side
+	from  time.Time
+	to    time.Time
+	after *domain.Cursor
+	limit int
+}
+
+// parsePage reads side, from, to, cursor and limit. Times are unix seconds
+// or RFC 3339.
+func parsePage(c *gin.Context) (pageParams, error) {
+	var p pageParams
+	switch side := domain.Side(c.Query("side")); side {
+	case "", domain.SideBuy, domain.SideSell:
+		p.side = side
+	default:
+		return p, fmt.Errorf("invalid side %q", side)
+	}
+
+	var err error
+	if p.from, err = parseTime(c.Query("from")); err != nil {
+		return p, fmt.Errorf("invalid from: %w", err)
+	}
+	if p.to, err = parseTime(c.Query("to")); err != nil {
+		return p, fmt.Errorf("invalid to: %w", err)
+	}
+	if !p.from.IsZero() && !p.to.IsZero() && !p.from.Before(p.to) {
+		return p, fmt.Errorf("from must be before to")
+	}
+
+	if token := c.Query("cursor"); token != "" {
+		if p.after, err = domain.ParseCursor(token); err != nil {
+			return p, err
+		}
+	}
+	if raw := c.Query("limit"); raw != "" {
+		if p.limit, err = strconv.Atoi(raw); err != nil || p.limit <= 0 {
+			return p, fmt.Errorf("invalid limit %q", raw)
+		}
+	}
+	return p, nil
+}
+
+func parseTime(raw string) (time.Time, error) {
+	if raw == "" {
+		return time.Time{}, nil
+	}
+	if secs, err := strconv.ParseInt(raw, 10, 64); err == nil {
+		return time.Unix(secs, 0), nil
+	}
+	return time.Parse(time.RFC3339, raw)
+}
+
+// writePage responds with a history page and its next cursor.
+func writePage(c *gin.Context, items interface{}, next string) {
+	if next != "" {
+		c.Header(nextCursorHeader, next)
+	}
+	c.JSON(http.StatusOK, items)
+}
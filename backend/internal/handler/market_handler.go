@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nexus-orderbook-dex/backend/internal/domain"
+	"github.com/nexus-orderbook-dex/backend/internal/service"
+)
+
+type MarketHandler struct {
+	svc *service.OrderService
+}
+
+func NewMarketHandler(svc *service.OrderService) *MarketHandler {
+	return &MarketHandler{svc: svc}
+}
+
+// GetCandles returns the OHLCV bars of a pair, oldest first.
+func (h *MarketHandler) GetCandles(c *gin.Context) {
+	interval, err := domain.ParseCandleInterval(c.DefaultQuery("interval", string(domain.Interval1m)))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	from, err := parseTime(c.Query("from"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from: " + err.Error()})
+		return
+	}
+	to, err := parseTime(c.Query("to"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to: " + err.Error()})
+		return
+	}
+
+	candles, err := h.svc.GetCandles(c.Request.Context(), c.DefaultQuery("pair", "TKA-TKB"), interval, from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, candles)
+}
+
+// GetTicker returns the 24h ticker of one pair, or of every pair when no
+// pair is given.
+func (h *MarketHandler) GetTicker(c *gin.Context) {
+	pair := c.Query("pair")
+	if pair == "" {
+		c.JSON(http.StatusOK, h.svc.GetTickers())
+		return
+	}
+	ticker, ok := h.svc.GetTicker(pair)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "unknown pair " + pair})
+		return
+	}
+	c.JSON(http.StatusOK, ticker)
+}
+
+// GetMarketStatus returns the trading state of one pair, or of every pair
+// when no pair is given.
+func (h *MarketHandler) GetMarketStatus(c *gin.Context) {
+	pair := c.Query("pair")
+	if pair == "" {
+		c.JSON(http.StatusOK, h.svc.GetMarketStatuses())
+		return
+	}
+	c.JSON(http.StatusOK, h.svc.GetMarketStatus(pair))
+}
@@ -1,6 +1,8 @@
 package handler
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -22,10 +24,13 @@ func (h *OrderHandler) SubmitOrder(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if !requireOwner(c, sub.Maker) {
+		return
+	}
 
 	order, matches, err := h.svc.SubmitOrder(c.Request.Context(), sub)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		writeOrderError(c, err)
 		return
 	}
 
@@ -35,20 +40,96 @@ func (h *OrderHandler) SubmitOrder(c *gin.Context) {
 	})
 }
 
+func (h *OrderHandler) ReplaceOrder(c *gin.Context) {
+	var sub domain.OrderSubmission
+	if err := c.ShouldBindJSON(&sub); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	// The service checks that the replacement keeps the original's maker
+	if !requireOwner(c, sub.Maker) {
+		return
+	}
+
+	order, matches, keptPriority, err := h.svc.ReplaceOrder(c.Request.Context(), c.Param("id"), sub)
+	if err != nil {
+		writeOrderError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"order":        order,
+		"matches":      len(matches),
+		"keptPriority": keptPriority,
+	})
+}
+
+// writeOrderError answers a rejected order, group or cancel: 422 with the
+// code of the risk limit that rejected it, 409 when the pair's state does
+// not allow it, 400 for anything else.
+func writeOrderError(c *gin.Context, err error) {
+	var risk *service.RiskError
+	if errors.As(err, &risk) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": risk.Message, "code": risk.Code})
+		return
+	}
+	var market *service.MarketError
+	if errors.As(err, &market) {
+		c.JSON(http.StatusConflict, gin.H{"error": market.Error(), "code": "market_" + string(market.State)})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+}
+
+// GetUserOrders pages through a maker's orders, newest first, optionally
+// filtered by pair, side, status and creation time.
 func (h *OrderHandler) GetUserOrders(c *gin.Context) {
-	address := c.Param("address")
-	orders, err := h.svc.GetOrdersByMaker(c.Request.Context(), address)
+	if !requireOwner(c, c.Param("address")) {
+		return
+	}
+	page, err := parsePage(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	status := domain.OrderStatus(c.Query("status"))
+	switch status {
+	case "", domain.OrderStatusOpen, domain.OrderStatusPartiallyFilled, domain.OrderStatusFilled,
+		domain.OrderStatusCancelled, domain.OrderStatusPending, domain.OrderStatusExpired:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid status %q", status)})
+		return
+	}
+
+	orders, next, err := h.svc.ListOrders(c.Request.Context(), domain.OrderQuery{
+		Maker:  c.Param("address"),
+		Pair:   c.Query("pair"),
+		Side:   page.side,
+		Status: status,
+		From:   page.from,
+		To:     page.to,
+		After:  page.after,
+		Limit:  page.limit,
+	})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, orders)
+	writePage(c, orders, next)
 }
 
 func (h *OrderHandler) CancelOrder(c *gin.Context) {
 	id := c.Param("id")
+	order, err := h.svc.GetOrder(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if !requireOwner(c, order.Maker) {
+		return
+	}
 	if err := h.svc.CancelOrder(c.Request.Context(), id); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		writeOrderError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"status": "cancelled"})
@@ -2,8 +2,10 @@ package handler
 
 import (
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
+	ob "github.com/nexus-orderbook-dex/backend/internal/orderbook"
 	"github.com/nexus-orderbook-dex/backend/internal/service"
 )
 
@@ -15,8 +17,43 @@ func NewOrderbookHandler(svc *service.OrderService) *OrderbookHandler {
 	return &OrderbookHandler{svc: svc}
 }
 
+// GetOrderbook returns the aggregated book of a pair. depth keeps the best
+// N levels of each side and group merges levels into coarser price buckets;
+// without them the full book is returned.
 func (h *OrderbookHandler) GetOrderbook(c *gin.Context) {
 	pair := c.DefaultQuery("pair", "TKA-TKB")
-	snapshot := h.svc.GetOrderbook(pair)
+
+	var view ob.BookView
+	var err error
+	if raw := c.Query("depth"); raw != "" {
+		if view.Depth, err = strconv.Atoi(raw); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid depth " + strconv.Quote(raw)})
+			return
+		}
+	}
+	if raw := c.Query("group"); raw != "" {
+		if view.Group, err = strconv.ParseFloat(raw, 64); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid group " + strconv.Quote(raw)})
+			return
+		}
+	}
+	if err := view.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	snapshot := h.svc.GetOrderbook(c.Request.Context(), pair, view)
+	c.JSON(http.StatusOK, snapshot)
+}
+
+// GetL3 returns every resting order of a pair with the journal sequence the
+// snapshot reflects, for clients keeping an order-by-order replica.
+func (h *OrderbookHandler) GetL3(c *gin.Context) {
+	pair := c.DefaultQuery("pair", "TKA-TKB")
+	snapshot, err := h.svc.GetL3Snapshot(c.Request.Context(), pair)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
 	c.JSON(http.StatusOK, snapshot)
 }
@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nexus-orderbook-dex/backend/internal/repository"
+)
+
+// Request weights, in tokens. Order entry costs a signature recovery and a
+// write, so it weighs more than reading market data or history.
+const (
+	WeightMarketData = 1
+	WeightOrderEntry = 10
+)
+
+// Rate limit headers. Retry-After is only sent with 429 responses.
+const (
+	rateLimitHeader     = "X-RateLimit-Limit"
+	rateRemainingHeader = "X-RateLimit-Remaining"
+)
+
+// RateLimitHeaders are the response headers throttling sets, for CORS.
+var RateLimitHeaders = []string{rateLimitHeader, rateRemainingHeader, "Retry-After"}
+
+// RateLimits are the buckets of each client: per IP address, per API key
+// and per signed-in address.
+type RateLimits struct {
+	IP     repository.RateLimit
+	APIKey repository.RateLimit
+	Maker  repository.RateLimit
+}
+
+// Throttle rejects requests once their client's bucket is empty. If the
+// limiter fails, requests are let through rather than taking the API down
+// with it.
+type Throttle struct {
+	limiter repository.RateLimiter
+	limits  RateLimits
+}
+
+func NewThrottle(limiter repository.RateLimiter, limits RateLimits) *Throttle {
+	return &Throttle{limiter: limiter, limits: limits}
+}
+
+// ByIP charges weight to the client's IP address. It runs before
+// authentication, so that floods are turned away before costing a
+// signature check.
+func (t *Throttle) ByIP(weight float64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		t.charge(c, weight, repository.RateBucket{Key: "ip:" + c.ClientIP(), Limit: t.limits.IP})
+	}
+}
+
+// ByAccount charges weight to the API key and the address of a request
+// that passed RequireAuth or RequireSession. Every key of an address and
+// its sessions share the address's bucket.
+func (t *Throttle) ByAccount(weight float64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		p := principal(c)
+		buckets := []repository.RateBucket{{Key: "maker:" + strings.ToLower(p.Address), Limit: t.limits.Maker}}
+		if p.KeyID != "" {
+			buckets = append([]repository.RateBucket{{Key: "key:" + p.KeyID, Limit: t.limits.APIKey}}, buckets...)
+		}
+		t.charge(c, weight, buckets...)
+	}
+}
+
+// charge takes weight from every bucket at once, or from none of them and
+// answers 429 if one is short, so that a refused request costs nothing.
+// The headers report the short bucket, or else the emptiest one.
+func (t *Throttle) charge(c *gin.Context, weight float64, buckets ...repository.RateBucket) {
+	d, err := t.limiter.Take(c.Request.Context(), buckets, weight)
+	if err != nil {
+		log.Printf("Rate limiter: %v", err)
+		c.Next()
+		return
+	}
+	if math.IsInf(d.Remaining, 1) {
+		c.Next()
+		return
+	}
+	c.Header(rateLimitHeader, formatTokens(buckets[d.Bucket].Limit.Burst))
+	c.Header(rateRemainingHeader, formatTokens(d.Remaining))
+	if !d.Allowed {
+		retry := int(math.Ceil(d.RetryAfter.Seconds()))
+		if retry < 1 {
+			retry = 1
+		}
+		c.Header("Retry-After", strconv.Itoa(retry))
+		c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+			"error":      "rate limit exceeded",
+			"retryAfter": retry,
+		})
+		return
+	}
+	c.Next()
+}
+
+func formatTokens(n float64) string {
+	return strconv.FormatInt(int64(math.Floor(n)), 10)
+}
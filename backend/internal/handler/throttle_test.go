@@ -0,0 +1,182 @@
+package handler
+
+import (
+	"context"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/nexus-orderbook-dex/backend/internal/domain"
+	"github.com/nexus-orderbook-dex/backend/internal/repository"
+	"github.com/nexus-orderbook-dex/backend/internal/repository/memory"
+	"github.com/nexus-orderbook-dex/backend/internal/service"
+)
+
+// testClock is a clock that only moves when told to.
+type testClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *testClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func TestThrottle_WeighsOrderEntryAndRefills(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	clock := &testClock{now: time.Unix(1700000000, 0)}
+	limiter := memory.NewRateLimiter()
+	limiter.SetClock(clock.Now)
+	throttle := NewThrottle(limiter, RateLimits{IP: repository.RateLimit{Rate: 10, Burst: 20}})
+
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	r := gin.New()
+	r.GET("/ticker", throttle.ByIP(WeightMarketData), ok)
+	r.POST("/orders", throttle.ByIP(WeightOrderEntry), ok)
+
+	do := func(method, path, ip string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.RemoteAddr = ip + ":1234"
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Two orders empty the bucket that twenty market data reads would
+	for i := 0; i < 2; i++ {
+		if rec := do("POST", "/orders", "10.0.0.1"); rec.Code != http.StatusOK {
+			t.Fatalf("order %d: expected 200, got %d", i, rec.Code)
+		}
+	}
+	rec := do("GET", "/ticker", "10.0.0.1")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %d", rec.Code)
+	}
+	if rec.Header().Get("Retry-After") != "1" || rec.Header().Get(rateLimitHeader) != "20" || rec.Header().Get(rateRemainingHeader) != "0" {
+		t.Fatalf("unexpected headers %v", rec.Header())
+	}
+
+	// Other clients have their own bucket
+	if rec := do("GET", "/ticker", "10.0.0.2"); rec.Code != http.StatusOK || rec.Header().Get(rateRemainingHeader) != "19" {
+		t.Fatalf("expected another IP to pass with 19 tokens left, got %d %v", rec.Code, rec.Header())
+	}
+
+	// An order needs a full second of refill
+	clock.Advance(500 * time.Millisecond)
+	if rec := do("POST", "/orders", "10.0.0.1"); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 after half a second, got %d", rec.Code)
+	}
+	clock.Advance(500 * time.Millisecond)
+	if rec := do("POST", "/orders", "10.0.0.1"); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 after a second, got %d", rec.Code)
+	}
+}
+
+func TestThrottle_IgnoresForwardedForFromUntrustedPeers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	throttle := NewThrottle(memory.NewRateLimiter(), RateLimits{IP: repository.RateLimit{Rate: 10, Burst: 20}})
+
+	// As the server is set up with a proxy at 10.0.0.9 in TRUSTED_PROXIES
+	r := gin.New()
+	if err := r.SetTrustedProxies([]string{"10.0.0.9"}); err != nil {
+		t.Fatal(err)
+	}
+	r.POST("/orders", throttle.ByIP(WeightOrderEntry), func(c *gin.Context) { c.Status(http.StatusOK) })
+	do := func(peer, forwardedFor string) int {
+		req := httptest.NewRequest("POST", "/orders", nil)
+		req.RemoteAddr = peer + ":1234"
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// A caller naming a new address each time still drains its own bucket
+	for i, spoofed := range []string{"1.1.1.1", "2.2.2.2"} {
+		if code := do("10.0.0.1", spoofed); code != http.StatusOK {
+			t.Fatalf("order %d: expected 200, got %d", i, code)
+		}
+	}
+	if code := do("10.0.0.1", "3.3.3.3"); code != http.StatusTooManyRequests {
+		t.Fatalf("expected the spoofed header ignored, got %d", code)
+	}
+
+	// The trusted proxy's clients are told apart by the header
+	for _, client := range []string{"1.1.1.1", "2.2.2.2", "3.3.3.3"} {
+		if code := do("10.0.0.9", client); code != http.StatusOK {
+			t.Fatalf("expected %s behind the proxy to have its own bucket, got %d", client, code)
+		}
+	}
+}
+
+func TestThrottle_SharesTheMakerBucketAcrossKeys(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	auth := service.NewAuthService(memory.NewStore(), memory.NewAuthCache(), "localhost:3000", big.NewInt(31337))
+	limiter := memory.NewRateLimiter()
+	limits := RateLimits{
+		APIKey: repository.RateLimit{Rate: 1, Burst: 100},
+		Maker:  repository.RateLimit{Rate: 1, Burst: 15},
+	}
+	throttle := NewThrottle(limiter, limits)
+
+	r := gin.New()
+	r.GET("/orders/:address", RequireAuth(auth, domain.ScopeRead), throttle.ByAccount(WeightOrderEntry), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	// A session and an API key of the same address draw on one bucket
+	address, token := signIn(t, auth)
+	key, err := auth.CreateAPIKey(context.Background(), address, "bot", []domain.Scope{domain.ScopeRead})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := "/orders/" + address
+	do := func(header map[string]string) int {
+		req := httptest.NewRequest("GET", path, nil)
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	signed := func(nonce string) map[string]string {
+		ts := strconv.FormatInt(time.Now().UnixMilli(), 10)
+		return map[string]string{
+			apiKeyHeader:       key.ID,
+			apiTimestampHeader: ts,
+			apiNonceHeader:     nonce,
+			apiSignatureHeader: service.SignRequest(key.Secret, ts, nonce, "GET", path, nil),
+		}
+	}
+	if code := do(signed("n1")); code != http.StatusOK {
+		t.Fatalf("expected 200 with the key, got %d", code)
+	}
+	if code := do(map[string]string{"Authorization": "Bearer " + token}); code != http.StatusTooManyRequests {
+		t.Fatalf("expected the maker's bucket to be empty for the session, got %d", code)
+	}
+
+	// A request the maker's bucket refuses costs the key nothing
+	if code := do(signed("n2")); code != http.StatusTooManyRequests {
+		t.Fatalf("expected the maker's bucket to be empty for the key, got %d", code)
+	}
+	d, err := limiter.Take(context.Background(), []repository.RateBucket{{Key: "key:" + key.ID, Limit: limits.APIKey}}, 0)
+	if err != nil || d.Remaining < 90 || d.Remaining > 91 {
+		t.Fatalf("expected the key charged once, got %g left (%v)", d.Remaining, err)
+	}
+}
@@ -2,9 +2,9 @@ package handler
 
 import (
 	"net/http"
-	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/nexus-orderbook-dex/backend/internal/domain"
 	"github.com/nexus-orderbook-dex/backend/internal/service"
 )
 
@@ -16,13 +16,53 @@ func NewTradeHandler(svc *service.OrderService) *TradeHandler {
 	return &TradeHandler{svc: svc}
 }
 
+// GetTrades pages through the trades of a pair, newest first.
 func (h *TradeHandler) GetTrades(c *gin.Context) {
-	pair := c.DefaultQuery("pair", "TKA-TKB")
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
-	trades, err := h.svc.GetTrades(c.Request.Context(), pair, limit)
+	page, err := parsePage(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if page.side != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "side filters the trades of an address"})
+		return
+	}
+	h.list(c, domain.TradeQuery{
+		Pair:  c.DefaultQuery("pair", "TKA-TKB"),
+		From:  page.from,
+		To:    page.to,
+		After: page.after,
+		Limit: page.limit,
+	})
+}
+
+// GetUserTrades pages through the trades an address bought or sold in,
+// newest first. side=buy or side=sell keeps one side only.
+func (h *TradeHandler) GetUserTrades(c *gin.Context) {
+	if !requireOwner(c, c.Param("address")) {
+		return
+	}
+	page, err := parsePage(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	h.list(c, domain.TradeQuery{
+		Pair:    c.Query("pair"),
+		Address: c.Param("address"),
+		Side:    page.side,
+		From:    page.from,
+		To:      page.to,
+		After:   page.after,
+		Limit:   page.limit,
+	})
+}
+
+func (h *TradeHandler) list(c *gin.Context, q domain.TradeQuery) {
+	trades, next, err := h.svc.ListTrades(c.Request.Context(), q)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, trades)
+	writePage(c, trades, next)
 }
@@ -2,30 +2,61 @@ package handler
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
 	"sync"
 	"time"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
-	redisRepo "github.com/nexus-orderbook-dex/backend/internal/repository/redis"
+	"github.com/nexus-orderbook-dex/backend/internal/hub"
+	"github.com/nexus-orderbook-dex/backend/internal/repository"
+	"github.com/nexus-orderbook-dex/backend/internal/service"
 )
 
 var upgrader = websocket.Upgrader{
 	CheckOrigin: func(r *http.Request) bool { return true },
 }
 
+// Keepalive and write limits of a WebSocket connection.
+const (
+	wsPingInterval = 30 * time.Second
+	wsWriteWait    = 10 * time.Second
+)
+
+// WSHandler serves WebSocket clients from the server's hub, which the
+// updates stream feeds.
 type WSHandler struct {
-	cache *redisRepo.OrderbookCache
+	cache repository.SnapshotCache
+	hub   *hub.Hub
+	svc   *service.OrderService
 }
 
-func NewWSHandler(cache *redisRepo.OrderbookCache) *WSHandler {
-	return &WSHandler{cache: cache}
+func NewWSHandler(cache repository.SnapshotCache, h *hub.Hub, svc *service.OrderService) *WSHandler {
+	return &WSHandler{cache: cache, hub: h, svc: svc}
 }
 
+// Handle serves a multiplexed WebSocket connection. Clients subscribe to
+// and unsubscribe from channels of any pairs with JSON requests; see
+// wsRequest. A connection opened with a pair or channel query parameter
+// starts subscribed to it, the channel defaulting to the orderbook.
 func (h *WSHandler) Handle(c *gin.Context) {
-	pair := c.DefaultQuery("pair", "TKA-TKB")
+	var initial *wsRequest
+	if c.Query("pair") != "" || c.Query("channel") != "" {
+		initial = &wsRequest{
+			Type:    "subscribe",
+			Channel: c.DefaultQuery("channel", repository.ChannelOrderbook),
+			Pair:    c.DefaultQuery("pair", "TKA-TKB"),
+		}
+		if _, err := initial.topics(""); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Message})
+			return
+		}
+	}
 
 	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
@@ -33,56 +64,302 @@ func (h *WSHandler) Handle(c *gin.Context) {
 		return
 	}
 	defer conn.Close()
+	conn.SetReadLimit(maxWSMessageSize)
 
-	// Send initial snapshot
-	bids, asks, err := h.cache.GetSnapshot(c.Request.Context(), pair)
-	if err == nil {
-		conn.WriteJSON(map[string]interface{}{
-			"type": "snapshot",
-			"bids": bids,
-			"asks": asks,
-		})
-	}
-
-	// Subscribe to Redis pub/sub for updates
 	ctx, cancel := context.WithCancel(c.Request.Context())
 	defer cancel()
 
-	sub := h.cache.Subscribe(ctx, pair)
-	defer sub.Close()
-	ch := sub.Channel()
+	client := h.hub.NewClient()
+	defer client.Close()
+	s := &wsSession{
+		h:      h,
+		conn:   conn,
+		client: client,
+		topics: make(map[string]wsTopic),
+	}
+	if initial != nil {
+		if err := s.handle(ctx, *initial); err != nil {
+			return
+		}
+	}
 
-	// Read pump (detect disconnection)
+	// Read pump: detects disconnection and hands client frames to the loop
+	// below, the connection's only writer
+	frames := make(chan []byte, 16)
 	var once sync.Once
 	go func() {
 		for {
-			_, _, err := conn.ReadMessage()
+			_, data, err := conn.ReadMessage()
 			if err != nil {
 				once.Do(cancel)
 				return
 			}
+			select {
+			case frames <- data:
+			case <-ctx.Done():
+				return
+			}
 		}
 	}()
 
 	// Ping ticker
-	ticker := time.NewTicker(30 * time.Second)
+	ticker := time.NewTicker(wsPingInterval)
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-ctx.Done():
 			return
-		case msg, ok := <-ch:
-			if !ok {
+		case data := <-frames:
+			if err := s.handleFrame(ctx, data); err != nil {
 				return
 			}
-			if err := conn.WriteMessage(websocket.TextMessage, []byte(msg.Payload)); err != nil {
+		case msg := <-client.Messages():
+			if err := s.write(websocket.TextMessage, msg); err != nil {
 				return
 			}
+		case <-client.Done():
+			if client.Slow() {
+				// Fell a full buffer behind: the client reconnects and
+				// starts again from snapshots
+				s.write(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "slow consumer"))
+			}
+			return
 		case <-ticker.C:
-			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+			if err := s.write(websocket.PingMessage, nil); err != nil {
 				return
 			}
 		}
 	}
 }
+
+// wsSession is the subscription state of one connection. It is only used
+// from the connection's write loop.
+type wsSession struct {
+	h      *WSHandler
+	conn   *websocket.Conn
+	client *hub.Client
+
+	// topics are the active subscriptions by hub topic
+	topics map[string]wsTopic
+
+	// nonce is the outstanding login challenge, zero if none
+	nonce common.Hash
+	// user is the key of the logged in user, empty before login
+	user string
+}
+
+// handleFrame answers one client frame. Only write errors are returned;
+// invalid requests get an error frame.
+func (s *wsSession) handleFrame(ctx context.Context, data []byte) error {
+	var req wsRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		return s.reject(req, &wsError{Code: wsErrBadRequest, Message: "invalid JSON"})
+	}
+	return s.handle(ctx, req)
+}
+
+func (s *wsSession) handle(ctx context.Context, req wsRequest) error {
+	switch req.Type {
+	case "subscribe":
+		return s.subscribe(ctx, req)
+	case "unsubscribe":
+		return s.unsubscribe(ctx, req)
+	case "resync":
+		return s.resync(ctx, req)
+	case "challenge":
+		return s.challenge(req)
+	case "login":
+		return s.login(req)
+	}
+	return s.reject(req, &wsError{Code: wsErrUnknownType, Message: fmt.Sprintf("unknown request type %q", req.Type)})
+}
+
+func (s *wsSession) subscribe(ctx context.Context, req wsRequest) error {
+	topics, werr := req.topics(s.user)
+	if werr != nil {
+		return s.reject(req, werr)
+	}
+	var added []wsTopic
+	var keys []string
+	for _, t := range topics {
+		key := hub.Topic(t.Channel, t.Pair)
+		if _, ok := s.topics[key]; !ok {
+			added = append(added, t)
+			keys = append(keys, key)
+		}
+	}
+	if len(s.topics)+len(keys) > MaxWSSubscriptions {
+		return s.reject(req, &wsError{
+			Code:    wsErrSubscriptionLimit,
+			Message: fmt.Sprintf("at most %d subscriptions per connection", MaxWSSubscriptions),
+		})
+	}
+	s.client.Subscribe(keys...)
+	for _, t := range added {
+		s.topics[hub.Topic(t.Channel, t.Pair)] = t
+	}
+	if err := s.ack("subscribed", req, topics); err != nil {
+		return err
+	}
+
+	// Snapshots are taken once subscribed, so that no update falls between
+	// the two; clients skip updates the snapshot already reflects
+	for _, t := range added {
+		if err := s.sendSnapshot(ctx, t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *wsSession) unsubscribe(ctx context.Context, req wsRequest) error {
+	topics, werr := req.topics(s.user)
+	if werr != nil {
+		return s.reject(req, werr)
+	}
+	var keys []string
+	for _, t := range topics {
+		key := hub.Topic(t.Channel, t.Pair)
+		if _, ok := s.topics[key]; ok {
+			keys = append(keys, key)
+			delete(s.topics, key)
+		}
+	}
+	s.client.Unsubscribe(keys...)
+	return s.ack("unsubscribed", req, topics)
+}
+
+// challenge issues a fresh login nonce, replacing any outstanding one.
+func (s *wsSession) challenge(req wsRequest) error {
+	if _, err := rand.Read(s.nonce[:]); err != nil {
+		return s.reject(req, &wsError{Code: wsErrUnavailable, Message: "no challenge available, try again"})
+	}
+	return s.writeJSON(wsChallenge{Type: "challenge", ID: req.ID, Nonce: s.nonce.Hex()})
+}
+
+// login authenticates the connection with a signature of the outstanding
+// challenge. Each challenge can be tried once.
+func (s *wsSession) login(req wsRequest) error {
+	if s.user != "" {
+		return s.reject(req, &wsError{Code: wsErrBadRequest, Message: "already logged in"})
+	}
+	nonce := s.nonce
+	s.nonce = common.Hash{}
+	if nonce == (common.Hash{}) {
+		return s.reject(req, &wsError{Code: wsErrBadRequest, Message: "request a challenge first"})
+	}
+	if !common.IsHexAddress(req.Address) {
+		return s.reject(req, &wsError{Code: wsErrBadRequest, Message: fmt.Sprintf("invalid address %q", req.Address)})
+	}
+	account := common.HexToAddress(req.Address)
+	if err := s.h.svc.VerifyLogin(account, nonce, req.Signature); err != nil {
+		return s.reject(req, &wsError{Code: wsErrUnauthorized, Message: err.Error()})
+	}
+	s.user = repository.UserKey(account.Hex())
+	return s.writeJSON(wsLoggedIn{Type: "logged_in", ID: req.ID, Address: account.Hex()})
+}
+
+// resync sends new snapshots of the named subscriptions, or of every
+// subscription with a snapshot if the request names no channel.
+func (s *wsSession) resync(ctx context.Context, req wsRequest) error {
+	var topics []wsTopic
+	if req.Channel == "" {
+		for _, t := range s.topics {
+			if wsChannels[t.Channel] {
+				topics = append(topics, t)
+			}
+		}
+	} else {
+		var werr *wsError
+		if topics, werr = req.topics(s.user); werr != nil {
+			return s.reject(req, werr)
+		}
+		if !wsChannels[req.Channel] {
+			return s.reject(req, &wsError{Code: wsErrBadRequest, Message: fmt.Sprintf("channel %s has no snapshot", req.Channel)})
+		}
+		for _, t := range topics {
+			if _, ok := s.topics[hub.Topic(t.Channel, t.Pair)]; !ok {
+				return s.reject(req, &wsError{Code: wsErrNotSubscribed, Message: fmt.Sprintf("not subscribed to %s of %s", t.Channel, t.Pair)})
+			}
+		}
+	}
+	for _, t := range topics {
+		if err := s.sendSnapshot(ctx, t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sendSnapshot writes the current snapshot of a subscription. A snapshot
+// that cannot be loaded is skipped, leaving the client to resync.
+func (s *wsSession) sendSnapshot(ctx context.Context, t wsTopic) error {
+	var msg interface{}
+	switch t.Channel {
+	case repository.ChannelL3:
+		snapshot, err := s.h.svc.GetL3Snapshot(ctx, t.Pair)
+		if err != nil {
+			log.Printf("Failed to load l3 snapshot of %s: %v", t.Pair, err)
+			return nil
+		}
+		msg = map[string]interface{}{
+			"type":     "l3_snapshot",
+			"snapshot": snapshot,
+		}
+	case repository.ChannelTicker:
+		ticker, ok := s.h.svc.GetTicker(t.Pair)
+		if !ok {
+			return nil
+		}
+		msg = map[string]interface{}{
+			"type":   "ticker",
+			"ticker": ticker,
+		}
+	case repository.ChannelMarket:
+		msg = map[string]interface{}{
+			"type":   "market_status",
+			"status": s.h.svc.GetMarketStatus(t.Pair),
+		}
+	case repository.ChannelOrderbook:
+		snapshot, err := s.h.cache.GetSnapshot(ctx, t.Pair)
+		if err != nil {
+			log.Printf("Failed to load snapshot of %s: %v", t.Pair, err)
+			return nil
+		}
+		msg = map[string]interface{}{
+			"type": "snapshot",
+			"pair": t.Pair,
+			"seq":  snapshot.Seq,
+			"bids": snapshot.Bids,
+			"asks": snapshot.Asks,
+		}
+	default:
+		return nil
+	}
+	return s.writeJSON(msg)
+}
+
+func (s *wsSession) ack(kind string, req wsRequest, topics []wsTopic) error {
+	pairs := make([]string, len(topics))
+	for i, t := range topics {
+		pairs[i] = t.Pair
+	}
+	return s.writeJSON(wsAck{Type: kind, ID: req.ID, Channel: req.Channel, Pairs: pairs})
+}
+
+func (s *wsSession) reject(req wsRequest, werr *wsError) error {
+	werr.Type = "error"
+	werr.ID = req.ID
+	return s.writeJSON(werr)
+}
+
+func (s *wsSession) write(messageType int, data []byte) error {
+	s.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+	return s.conn.WriteMessage(messageType, data)
+}
+
+func (s *wsSession) writeJSON(v interface{}) error {
+	s.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+	return s.conn.WriteJSON(v)
+}
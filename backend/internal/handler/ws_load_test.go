@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"fmt"
+	"math/big"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+
+	"github.com/nexus-orderbook-dex/backend/internal/blockchain"
+	"github.com/nexus-orderbook-dex/backend/internal/hub"
+	"github.com/nexus-orderbook-dex/backend/internal/repository"
+	"github.com/nexus-orderbook-dex/backend/internal/repository/memory"
+	"github.com/nexus-orderbook-dex/backend/internal/service"
+)
+
+// TestWSFanOut_ThousandsOfConnections subscribes thousands of real
+// WebSocket connections to one pair and checks that every broadcast reaches
+// every connection, in order and without dropping anyone.
+func TestWSFanOut_ThousandsOfConnections(t *testing.T) {
+	if testing.Short() {
+		t.Skip("load test")
+	}
+	const (
+		clients  = 2000
+		messages = 100
+	)
+
+	gin.SetMode(gin.TestMode)
+	store, cache := memory.NewStore(), memory.NewCache()
+	relay := service.NewOutboxRelay(store, cache, make(chan blockchain.SettleJob))
+	svc := service.NewOrderService(store, cache, relay, big.NewInt(31337), common.Address{}, t.TempDir())
+	wsHub := hub.New(hub.DefaultBufferSize)
+	r := gin.New()
+	r.GET("/ws", NewWSHandler(cache, wsHub, svc).Handle)
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/ws?pair=TKA-TKB&channel=" + repository.ChannelTrades
+	conns := make([]*websocket.Conn, clients)
+	for i := range conns {
+		conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+		if err != nil {
+			t.Fatalf("dial %d: %v", i, err)
+		}
+		defer conn.Close()
+		// The ack is sent once the connection is subscribed
+		var ack wsAck
+		if err := conn.ReadJSON(&ack); err != nil || ack.Type != "subscribed" {
+			t.Fatalf("connection %d: expected an ack, got %+v (%v)", i, ack, err)
+		}
+		conns[i] = conn
+	}
+	if s := wsHub.Stats(); s.Subscriptions != clients {
+		t.Fatalf("expected %d subscriptions, got %+v", clients, s)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, clients)
+	for i, conn := range conns {
+		wg.Add(1)
+		go func(i int, conn *websocket.Conn) {
+			defer wg.Done()
+			conn.SetReadDeadline(time.Now().Add(time.Minute))
+			for n := 0; n < messages; n++ {
+				_, data, err := conn.ReadMessage()
+				if err != nil {
+					errs <- fmt.Errorf("connection %d: message %d: %v", i, n, err)
+					return
+				}
+				if want := fmt.Sprintf(`{"type":"trade","seq":%d}`, n); string(data) != want {
+					errs <- fmt.Errorf("connection %d: expected %s, got %s", i, want, data)
+					return
+				}
+			}
+		}(i, conn)
+	}
+
+	start := time.Now()
+	topic := hub.Topic(repository.ChannelTrades, "TKA-TKB")
+	for n := 0; n < messages; n++ {
+		wsHub.Broadcast(topic, []byte(fmt.Sprintf(`{"type":"trade","seq":%d}`, n)))
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+
+	if s := wsHub.Stats(); s.Dropped != 0 {
+		t.Fatalf("expected no slow consumers, got %+v", s)
+	}
+	t.Logf("delivered %d messages to %d connections in %v (%.0f messages/s)",
+		messages, clients, elapsed, float64(messages*clients)/elapsed.Seconds())
+}
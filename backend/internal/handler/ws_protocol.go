@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/nexus-orderbook-dex/backend/internal/repository"
+)
+
+// Limits of one WebSocket connection.
+const (
+	MaxWSSubscriptions = 50   // channel and pair combinations
+	maxWSMessageSize   = 4096 // bytes of one client frame
+	maxWSPairLength    = 32
+)
+
+// wsChannels are the channels a client can subscribe to, and whether their
+// subscriptions start with a snapshot.
+var wsChannels = map[string]bool{
+	repository.ChannelOrderbook: true,
+	repository.ChannelL3:        true,
+	repository.ChannelTicker:    true,
+	repository.ChannelMarket:    true,
+	repository.ChannelTrades:    false,
+	repository.ChannelCandles:   false,
+	repository.ChannelUser:      false,
+}
+
+// Error codes of WebSocket error frames.
+const (
+	wsErrBadRequest        = "bad_request"
+	wsErrUnknownType       = "unknown_type"
+	wsErrUnknownChannel    = "unknown_channel"
+	wsErrSubscriptionLimit = "subscription_limit"
+	wsErrNotSubscribed     = "not_subscribed"
+	wsErrUnauthorized      = "unauthorized"
+	wsErrUnavailable       = "unavailable"
+)
+
+// wsRequest is a client frame. Subscribe, unsubscribe and resync name one
+// channel and any number of pairs; the user channel takes no pair. Challenge
+// and login authenticate the connection for the user channel. ID is echoed
+// in the reply.
+type wsRequest struct {
+	Type    string          `json:"type"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Channel string          `json:"channel"`
+	Pair    string          `json:"pair,omitempty"`
+	Pairs   []string        `json:"pairs,omitempty"`
+
+	Address   string `json:"address,omitempty"`
+	Signature string `json:"signature,omitempty"`
+}
+
+// topics returns the channel and pair combinations the request names, for
+// the user channel the stream of the logged in user.
+func (r wsRequest) topics(user string) ([]wsTopic, *wsError) {
+	if _, ok := wsChannels[r.Channel]; !ok {
+		return nil, &wsError{Code: wsErrUnknownChannel, Message: fmt.Sprintf("unknown channel %q", r.Channel)}
+	}
+	if r.Channel == repository.ChannelUser {
+		if user == "" {
+			return nil, &wsError{Code: wsErrUnauthorized, Message: "the user channel requires login"}
+		}
+		return []wsTopic{{Channel: r.Channel, Pair: user}}, nil
+	}
+	pairs := r.Pairs
+	if r.Pair != "" {
+		pairs = append([]string{r.Pair}, pairs...)
+	}
+	if len(pairs) == 0 {
+		return nil, &wsError{Code: wsErrBadRequest, Message: "pair or pairs is required"}
+	}
+	topics := make([]wsTopic, 0, len(pairs))
+	for _, pair := range pairs {
+		if pair == "" || len(pair) > maxWSPairLength {
+			return nil, &wsError{Code: wsErrBadRequest, Message: fmt.Sprintf("invalid pair %q", pair)}
+		}
+		topics = append(topics, wsTopic{Channel: r.Channel, Pair: pair})
+	}
+	return topics, nil
+}
+
+// wsTopic is one subscription: a channel of a pair.
+type wsTopic struct {
+	Channel string
+	Pair    string
+}
+
+// wsAck confirms a subscribe or unsubscribe request once it has taken
+// effect.
+type wsAck struct {
+	Type    string          `json:"type"` // subscribed or unsubscribed
+	ID      json.RawMessage `json:"id,omitempty"`
+	Channel string          `json:"channel"`
+	Pairs   []string        `json:"pairs"`
+}
+
+// wsChallenge carries the nonce a client signs to log in.
+type wsChallenge struct {
+	Type  string          `json:"type"`
+	ID    json.RawMessage `json:"id,omitempty"`
+	Nonce string          `json:"nonce"`
+}
+
+// wsLoggedIn confirms a login; the user channel is then available.
+type wsLoggedIn struct {
+	Type    string          `json:"type"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Address string          `json:"address"`
+}
+
+// wsError rejects a client frame; the connection stays open.
+type wsError struct {
+	Type    string          `json:"type"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Code    string          `json:"code"`
+	Message string          `json:"message"`
+}
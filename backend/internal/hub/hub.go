@@ -0,0 +1,165 @@
+// Package hub fans real-time updates out to the WebSocket clients of one
+// server. Every client has a bounded send buffer; a client that falls a full
+// buffer behind is disconnected instead of slowing down the others.
+package hub
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultBufferSize is the number of messages a client may have queued.
+const DefaultBufferSize = 256
+
+// Topic names the updates of a channel for one pair, or one user on the
+// user channel.
+func Topic(channel, key string) string {
+	return channel + ":" + key
+}
+
+// Hub routes messages to the clients subscribed to their topic.
+type Hub struct {
+	bufferSize int
+
+	mu     sync.RWMutex
+	topics map[string]map[*Client]struct{}
+
+	dropped atomic.Int64 // clients disconnected as slow consumers
+}
+
+func New(bufferSize int) *Hub {
+	if bufferSize <= 0 {
+		bufferSize = DefaultBufferSize
+	}
+	return &Hub{bufferSize: bufferSize, topics: make(map[string]map[*Client]struct{})}
+}
+
+// Client is one connection's view of the hub. Messages of its topics arrive
+// on Messages until the client is closed, after which Done is closed.
+type Client struct {
+	hub    *Hub
+	send   chan []byte
+	done   chan struct{}
+	once   sync.Once
+	slow   atomic.Bool
+	topics map[string]struct{} // guarded by hub.mu
+}
+
+// NewClient registers a client without subscriptions.
+func (h *Hub) NewClient() *Client {
+	return &Client{
+		hub:    h,
+		send:   make(chan []byte, h.bufferSize),
+		done:   make(chan struct{}),
+		topics: make(map[string]struct{}),
+	}
+}
+
+// Subscribe adds topics to the client. Every message broadcast after it
+// returns reaches the client.
+func (c *Client) Subscribe(topics ...string) {
+	c.hub.mu.Lock()
+	defer c.hub.mu.Unlock()
+	select {
+	case <-c.done:
+		return
+	default:
+	}
+	for _, topic := range topics {
+		subs, ok := c.hub.topics[topic]
+		if !ok {
+			subs = make(map[*Client]struct{})
+			c.hub.topics[topic] = subs
+		}
+		subs[c] = struct{}{}
+		c.topics[topic] = struct{}{}
+	}
+}
+
+// Unsubscribe removes topics from the client. Messages already queued are
+// still delivered.
+func (c *Client) Unsubscribe(topics ...string) {
+	c.hub.mu.Lock()
+	defer c.hub.mu.Unlock()
+	for _, topic := range topics {
+		c.hub.remove(c, topic)
+	}
+}
+
+// remove drops a client from a topic; hub.mu must be held.
+func (h *Hub) remove(c *Client, topic string) {
+	delete(c.topics, topic)
+	if subs, ok := h.topics[topic]; ok {
+		delete(subs, c)
+		if len(subs) == 0 {
+			delete(h.topics, topic)
+		}
+	}
+}
+
+// Messages returns the client's queued messages.
+func (c *Client) Messages() <-chan []byte {
+	return c.send
+}
+
+// Done is closed once the client is closed, by its owner or for being slow.
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+// Slow reports whether the hub closed the client for falling behind.
+func (c *Client) Slow() bool {
+	return c.slow.Load()
+}
+
+// Close unsubscribes the client from every topic. It is safe to call more
+// than once.
+func (c *Client) Close() {
+	c.once.Do(func() {
+		c.hub.mu.Lock()
+		for topic := range c.topics {
+			c.hub.remove(c, topic)
+		}
+		c.hub.mu.Unlock()
+		close(c.done)
+	})
+}
+
+// Broadcast queues msg for every client subscribed to topic. It never
+// blocks: clients whose buffer is full are closed as slow consumers.
+func (h *Hub) Broadcast(topic string, msg []byte) {
+	var slow []*Client
+	h.mu.RLock()
+	for c := range h.topics[topic] {
+		select {
+		case c.send <- msg:
+		default:
+			slow = append(slow, c)
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, c := range slow {
+		if c.slow.CompareAndSwap(false, true) {
+			h.dropped.Add(1)
+		}
+		c.Close()
+	}
+}
+
+// Stats is a point-in-time view of the hub.
+type Stats struct {
+	Topics        int
+	Subscriptions int
+	Dropped       int64 // slow consumers disconnected so far
+}
+
+func (h *Hub) Stats() Stats {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	s := Stats{Topics: len(h.topics), Dropped: h.dropped.Load()}
+	for _, subs := range h.topics {
+		s.Subscriptions += len(subs)
+	}
+	return s
+}
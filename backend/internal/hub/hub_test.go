@@ -0,0 +1,72 @@
+package hub
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestBroadcast_ReachesOnlySubscribers(t *testing.T) {
+	h := New(4)
+	book, trades := h.NewClient(), h.NewClient()
+	book.Subscribe(Topic("orderbook", "TKA-TKB"))
+	trades.Subscribe(Topic("trades", "TKA-TKB"), Topic("trades", "TKC-TKD"))
+
+	h.Broadcast(Topic("trades", "TKC-TKD"), []byte("t1"))
+	h.Broadcast(Topic("orderbook", "TKA-TKB"), []byte("b1"))
+
+	if got := string(<-trades.Messages()); got != "t1" {
+		t.Fatalf("expected t1, got %s", got)
+	}
+	if got := string(<-book.Messages()); got != "b1" {
+		t.Fatalf("expected b1, got %s", got)
+	}
+	if len(book.Messages())+len(trades.Messages()) != 0 {
+		t.Fatal("expected no further messages")
+	}
+
+	trades.Unsubscribe(Topic("trades", "TKC-TKD"))
+	h.Broadcast(Topic("trades", "TKC-TKD"), []byte("t2"))
+	if len(trades.Messages()) != 0 {
+		t.Fatal("expected no message after unsubscribing")
+	}
+	if s := h.Stats(); s.Topics != 2 || s.Subscriptions != 2 {
+		t.Fatalf("expected 2 topics with 2 subscriptions, got %+v", s)
+	}
+}
+
+func TestBroadcast_DisconnectsSlowConsumers(t *testing.T) {
+	h := New(2)
+	topic := Topic("orderbook", "TKA-TKB")
+	slow, fast := h.NewClient(), h.NewClient()
+	slow.Subscribe(topic)
+	fast.Subscribe(topic)
+
+	for i := 0; i < 5; i++ {
+		h.Broadcast(topic, []byte(fmt.Sprint(i)))
+		// The fast client keeps up
+		<-fast.Messages()
+	}
+
+	select {
+	case <-slow.Done():
+	default:
+		t.Fatal("expected the slow client to be closed")
+	}
+	if !slow.Slow() || fast.Slow() {
+		t.Fatal("expected only the slow client to be marked slow")
+	}
+	select {
+	case <-fast.Done():
+		t.Fatal("expected the fast client to stay connected")
+	default:
+	}
+	if s := h.Stats(); s.Dropped != 1 || s.Subscriptions != 1 {
+		t.Fatalf("expected 1 drop and 1 subscription left, got %+v", s)
+	}
+
+	// A closed client cannot come back through Subscribe
+	slow.Subscribe(topic)
+	if s := h.Stats(); s.Subscriptions != 1 {
+		t.Fatalf("closed client was resubscribed: %+v", s)
+	}
+}
@@ -0,0 +1,258 @@
+// Package migrate applies versioned SQL migrations and records them in the
+// schema_migrations table.
+package migrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Migration is one schema version. Checksum is the SHA-256 of Up and is
+// recorded when the migration is applied, so that an edited file is caught.
+type Migration struct {
+	Version  int
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+func (m Migration) String() string {
+	return fmt.Sprintf("%03d_%s", m.Version, m.Name)
+}
+
+var fileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Load reads NNN_name.up.sql and NNN_name.down.sql files from the root of
+// fsys and returns the migrations ordered by version. Every version needs
+// an up file; a version without a down file cannot be rolled back.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		match := fileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("unrecognised migration file %s", entry.Name())
+		}
+		version, _ := strconv.Atoi(match[1])
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		m := byVersion[version]
+		if m == nil {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			m.Up = string(content)
+			sum := sha256.Sum256(content)
+			m.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %s has no up file", m)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// lockKey identifies the advisory lock that serialises migration runs
+// across processes sharing a database.
+const lockKey = 7_310_284_519_004
+
+const createTable = `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    BIGINT PRIMARY KEY,
+		name       TEXT NOT NULL,
+		checksum   TEXT NOT NULL,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	)`
+
+type appliedRow struct {
+	Version   int       `db:"version"`
+	Name      string    `db:"name"`
+	Checksum  string    `db:"checksum"`
+	AppliedAt time.Time `db:"applied_at"`
+}
+
+// Status is a migration and when it was applied, nil if it is pending.
+type Status struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+type Migrator struct {
+	db         *sqlx.DB
+	migrations []Migration
+}
+
+func New(db *sqlx.DB, migrations []Migration) *Migrator {
+	return &Migrator{db: db, migrations: migrations}
+}
+
+// Up applies every pending migration in version order, each in its own
+// transaction, and returns the ones it applied. Concurrent runs wait for
+// each other, so servers booting together apply each migration once.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var done []Migration
+	err := m.locked(ctx, func(conn *sqlx.Conn, applied map[int]appliedRow) error {
+		for _, mig := range m.migrations {
+			if _, ok := applied[mig.Version]; ok {
+				continue
+			}
+			err := inTx(ctx, conn, mig.Up, `
+				INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3)`,
+				mig.Version, mig.Name, mig.Checksum)
+			if err != nil {
+				return fmt.Errorf("apply %s: %w", mig, err)
+			}
+			done = append(done, mig)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down reverts the latest steps applied migrations, newest first, and
+// returns the ones it reverted.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var done []Migration
+	err := m.locked(ctx, func(conn *sqlx.Conn, applied map[int]appliedRow) error {
+		for i := len(m.migrations) - 1; i >= 0 && len(done) < steps; i-- {
+			mig := m.migrations[i]
+			if _, ok := applied[mig.Version]; !ok {
+				continue
+			}
+			if mig.Down == "" {
+				return fmt.Errorf("migration %s has no down file", mig)
+			}
+			err := inTx(ctx, conn, mig.Down, `DELETE FROM schema_migrations WHERE version = $1`, mig.Version)
+			if err != nil {
+				return fmt.Errorf("revert %s: %w", mig, err)
+			}
+			done = append(done, mig)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Status returns every known migration with the time it was applied.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var statuses []Status
+	err := m.locked(ctx, func(conn *sqlx.Conn, applied map[int]appliedRow) error {
+		for _, mig := range m.migrations {
+			st := Status{Migration: mig}
+			if row, ok := applied[mig.Version]; ok {
+				at := row.AppliedAt
+				st.AppliedAt = &at
+			}
+			statuses = append(statuses, st)
+		}
+		return nil
+	})
+	return statuses, err
+}
+
+// locked runs fn on a single connection holding the migration lock, after
+// checking that every applied migration still matches its file.
+func (m *Migrator) locked(ctx context.Context, fn func(conn *sqlx.Conn, applied map[int]appliedRow) error) error {
+	conn, err := m.db.Connx(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// Session-level lock: it is held by this connection until unlocked
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, lockKey); err != nil {
+		return fmt.Errorf("acquire migration lock: %w", err)
+	}
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, lockKey)
+
+	if _, err := conn.ExecContext(ctx, createTable); err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
+	var rows []appliedRow
+	if err := conn.SelectContext(ctx, &rows, `SELECT version, name, checksum, applied_at FROM schema_migrations`); err != nil {
+		return err
+	}
+
+	applied := make(map[int]appliedRow, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = row
+	}
+	if err := verify(m.migrations, applied); err != nil {
+		return err
+	}
+	return fn(conn, applied)
+}
+
+// verify fails if an applied migration has no file or its file changed.
+func verify(migrations []Migration, applied map[int]appliedRow) error {
+	known := make(map[int]Migration, len(migrations))
+	for _, mig := range migrations {
+		known[mig.Version] = mig
+	}
+	versions := make([]int, 0, len(applied))
+	for v := range applied {
+		versions = append(versions, v)
+	}
+	sort.Ints(versions)
+
+	for _, v := range versions {
+		row := applied[v]
+		mig, ok := known[v]
+		if !ok {
+			return fmt.Errorf("applied migration %03d_%s has no file", row.Version, row.Name)
+		}
+		if mig.Checksum != row.Checksum {
+			return fmt.Errorf("migration %s was changed after it was applied (checksum %s, applied %s)",
+				mig, mig.Checksum[:12], row.Checksum[:min(12, len(row.Checksum))])
+		}
+	}
+	return nil
+}
+
+// inTx runs a migration script and its bookkeeping statement atomically.
+func inTx(ctx context.Context, conn *sqlx.Conn, script, record string, args ...interface{}) error {
+	tx, err := conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,92 @@
+package migrate
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/nexus-orderbook-dex/backend/migrations"
+)
+
+func TestLoad_OrdersAndPairsFiles(t *testing.T) {
+	fsys := fstest.MapFS{
+		"010_later.up.sql":   {Data: []byte("CREATE TABLE b ();")},
+		"002_first.down.sql": {Data: []byte("DROP TABLE a;")},
+		"002_first.up.sql":   {Data: []byte("CREATE TABLE a ();")},
+	}
+
+	got, err := Load(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].Version != 2 || got[1].Version != 10 {
+		t.Fatalf("expected versions 2 and 10 in order, got %v", got)
+	}
+	if got[0].Name != "first" || got[0].Down != "DROP TABLE a;" || got[0].String() != "002_first" {
+		t.Fatalf("unexpected migration %+v", got[0])
+	}
+	if got[1].Down != "" {
+		t.Fatalf("expected no down script for %s", got[1])
+	}
+	if got[0].Checksum == "" || got[0].Checksum == got[1].Checksum {
+		t.Fatalf("expected distinct checksums, got %q and %q", got[0].Checksum, got[1].Checksum)
+	}
+}
+
+func TestLoad_RejectsInvalidSets(t *testing.T) {
+	tests := map[string]fstest.MapFS{
+		"unrecognised": {"001_init.sql": {Data: []byte("SELECT 1;")}},
+		"missing up":   {"001_init.down.sql": {Data: []byte("SELECT 1;")}},
+		"two names": {
+			"001_init.up.sql":  {Data: []byte("SELECT 1;")},
+			"001_other.up.sql": {Data: []byte("SELECT 2;")},
+		},
+	}
+	for name, fsys := range tests {
+		if _, err := Load(fsys); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	migs, err := Load(fstest.MapFS{
+		"001_init.up.sql": {Data: []byte("CREATE TABLE a ();")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ok := map[int]appliedRow{1: {Version: 1, Name: "init", Checksum: migs[0].Checksum}}
+	if err := verify(migs, ok); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	edited := map[int]appliedRow{1: {Version: 1, Name: "init", Checksum: strings.Repeat("0", 64)}}
+	if err := verify(migs, edited); err == nil || !strings.Contains(err.Error(), "changed") {
+		t.Fatalf("expected a checksum mismatch, got %v", err)
+	}
+
+	unknown := map[int]appliedRow{2: {Version: 2, Name: "gone", Checksum: "abc"}}
+	if err := verify(migs, unknown); err == nil || !strings.Contains(err.Error(), "no file") {
+		t.Fatalf("expected a missing file error, got %v", err)
+	}
+}
+
+func TestEmbeddedMigrationsCanBeReverted(t *testing.T) {
+	migs, err := Load(migrations.Files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migs) == 0 {
+		t.Fatal("no embedded migrations")
+	}
+	for i, mig := range migs {
+		if mig.Version != i+1 {
+			t.Errorf("expected version %d, got %s", i+1, mig)
+		}
+		if mig.Down == "" {
+			t.Errorf("%s has no down file", mig)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package orderbook
+
+import (
+	"container/heap"
+	"math"
+	"math/big"
+	"sort"
+
+	"github.com/nexus-orderbook-dex/backend/internal/domain"
+)
+
+// Indicative is what an auction would trade if the book uncrossed now.
+type Indicative struct {
+	Price       float64     `json:"price"`   // clearing price, 0 while nothing crosses
+	Volume      *big.Int    `json:"volume"`  // base amount that would trade at Price
+	Surplus     *big.Int    `json:"surplus"` // base left over at Price on SurplusSide
+	SurplusSide domain.Side `json:"surplusSide,omitempty"`
+}
+
+// Indicative returns the equilibrium price and volume of the resting
+// orders. The price is the limit price that trades the most base; among
+// those, the one that leaves the least over. If that still ties, the price
+// leans towards the side left over (the highest price when buyers are,
+// the lowest when sellers are), and otherwise is the one closest to the
+// last trade, or to the middle of the tied prices before the first trade.
+// Hidden iceberg size counts in full.
+func (ob *OrderBook) Indicative() Indicative {
+	ob.mu.RLock()
+	defer ob.mu.RUnlock()
+	return ob.indicative()
+}
+
+// auctionLevel is the remaining base of both sides at one limit price, and
+// what would trade there.
+type auctionLevel struct {
+	price          float64
+	buy, sell      *big.Int
+	demand, supply *big.Int // buys at or above price, sells at or below
+}
+
+func (l *auctionLevel) volume() *big.Int {
+	return minBigInt(l.demand, l.supply)
+}
+
+func (l *auctionLevel) surplus() *big.Int {
+	return new(big.Int).Abs(new(big.Int).Sub(l.demand, l.supply))
+}
+
+func (ob *OrderBook) indicative() Indicative {
+	byPrice := make(map[float64]*auctionLevel)
+	for _, entry := range ob.orderMap {
+		price := entry.Order.Price()
+		l, ok := byPrice[price]
+		if !ok {
+			l = &auctionLevel{price: price, buy: new(big.Int), sell: new(big.Int)}
+			byPrice[price] = l
+		}
+		if entry.Order.Side == domain.SideBuy {
+			l.buy.Add(l.buy, entry.Order.RemainingBase())
+		} else {
+			l.sell.Add(l.sell, entry.Order.RemainingBase())
+		}
+	}
+	levels := make([]*auctionLevel, 0, len(byPrice))
+	for _, l := range byPrice {
+		levels = append(levels, l)
+	}
+	sort.Slice(levels, func(i, j int) bool { return levels[i].price < levels[j].price })
+
+	supply, demand := new(big.Int), new(big.Int)
+	for _, l := range levels {
+		l.supply = new(big.Int).Add(supply, l.sell)
+		supply = l.supply
+	}
+	for i := len(levels) - 1; i >= 0; i-- {
+		levels[i].demand = new(big.Int).Add(demand, levels[i].buy)
+		demand = levels[i].demand
+	}
+
+	// Keep the levels that trade the most, then leave the least over
+	var tied []*auctionLevel
+	for _, l := range levels {
+		if l.volume().Sign() <= 0 {
+			continue
+		}
+		if len(tied) > 0 {
+			c := l.volume().Cmp(tied[0].volume())
+			if c == 0 {
+				c = tied[0].surplus().Cmp(l.surplus())
+			}
+			if c < 0 {
+				continue
+			}
+			if c > 0 {
+				tied = tied[:0]
+			}
+		}
+		tied = append(tied, l)
+	}
+	if len(tied) == 0 {
+		return Indicative{Volume: new(big.Int), Surplus: new(big.Int)}
+	}
+
+	best := tied[0]
+	if len(tied) > 1 {
+		best = ob.breakTie(tied)
+	}
+	ind := Indicative{Price: best.price, Volume: best.volume(), Surplus: best.surplus()}
+	switch best.demand.Cmp(best.supply) {
+	case 1:
+		ind.SurplusSide = domain.SideBuy
+	case -1:
+		ind.SurplusSide = domain.SideSell
+	}
+	return ind
+}
+
+// breakTie picks among levels, in price order, that trade and leave over
+// the same amounts.
+func (ob *OrderBook) breakTie(tied []*auctionLevel) *auctionLevel {
+	buyers, sellers := true, true
+	for _, l := range tied {
+		c := l.demand.Cmp(l.supply)
+		buyers = buyers && c > 0
+		sellers = sellers && c < 0
+	}
+	switch {
+	case buyers:
+		return tied[len(tied)-1]
+	case sellers:
+		return tied[0]
+	}
+
+	ref := ob.lastPrice
+	if ref == 0 {
+		ref = (tied[0].price + tied[len(tied)-1].price) / 2
+	}
+	best := tied[0]
+	for _, l := range tied[1:] {
+		if math.Abs(l.price-ref) < math.Abs(best.price-ref) {
+			best = l
+		}
+	}
+	return best
+}
+
+// uncross trades every order that crosses at the clearing price, in
+// price-time priority, and returns the fills. What is left does not cross,
+// or another price would have traded more. Fills have no taker side.
+//
+// settleMatch always pays the seller its own signed price and cannot clear
+// both sides at one uniform price, so each fill executes, and is reported,
+// at its sell order's price, which is at or below the clearing price and
+// within both orders' limits. Every fill also carries the clearing price,
+// which is what the auction marks the market at.
+func (ob *OrderBook) uncross() []MatchResult {
+	ind := ob.indicative()
+	if ind.Volume.Sign() <= 0 {
+		return nil
+	}
+
+	var matches []MatchResult
+	for ob.buys.Len() > 0 && ob.sells.Len() > 0 {
+		buy, sell := (*ob.buys)[0], (*ob.sells)[0]
+		if buy.Order.Price() < ind.Price || sell.Order.Price() > ind.Price {
+			break
+		}
+		fillAmount := minBigInt(buy.Order.RemainingBase(), sell.Order.RemainingBase())
+		if fillAmount.Sign() <= 0 {
+			break
+		}
+
+		quoteAmount := new(big.Int).Mul(fillAmount, sell.Order.AmountBuy)
+		quoteAmount.Div(quoteAmount, sell.Order.AmountSell)
+
+		matches = append(matches, MatchResult{
+			BuyOrder:      buy.Order,
+			SellOrder:     sell.Order,
+			FillAmount:    new(big.Int).Set(fillAmount),
+			QuoteAmount:   quoteAmount,
+			Price:         sell.Order.Price(),
+			ClearingPrice: ind.Price,
+		})
+		ob.emitMatch(matches[len(matches)-1])
+
+		buy.Order.FilledBase = new(big.Int).Add(buy.Order.FilledBase, fillAmount)
+		sell.Order.FilledBase = new(big.Int).Add(sell.Order.FilledBase, fillAmount)
+		updateOrderStatus(buy.Order)
+		updateOrderStatus(sell.Order)
+
+		// Auction fills keep the time priority of what is left
+		if buy.Order.Status == domain.OrderStatusFilled {
+			heap.Pop(ob.buys)
+			delete(ob.orderMap, buy.Order.ID)
+		} else if buy.Order.IsIceberg() {
+			buy.Visible = minBigInt(buy.Order.DisplayBase, buy.Order.RemainingBase())
+		}
+		if sell.Order.Status == domain.OrderStatusFilled {
+			heap.Pop(ob.sells)
+			delete(ob.orderMap, sell.Order.ID)
+		} else if sell.Order.IsIceberg() {
+			sell.Visible = minBigInt(sell.Order.DisplayBase, sell.Order.RemainingBase())
+		}
+
+		ob.onOrderChanged(buy.Order)
+		ob.onOrderChanged(sell.Order)
+	}
+	return matches
+}
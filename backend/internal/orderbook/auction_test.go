@@ -0,0 +1,105 @@
+package orderbook
+
+import (
+	"math"
+	"testing"
+
+	"github.com/nexus-orderbook-dex/backend/internal/domain"
+)
+
+func TestAuction_UncrossesAtTheVolumeMaximisingPrice(t *testing.T) {
+	ob := NewOrderBook("TKA-TKB")
+	ob.SetMarket(domain.MarketStatus{State: domain.MarketAuction})
+
+	ob.AddOrder(makeOrder("sell-1", domain.SideSell, 100, 100)) // 100 at 1
+	ob.AddOrder(makeOrder("sell-2", domain.SideSell, 100, 110)) // 100 at 1.1
+	ob.AddOrder(makeOrder("buy-1", domain.SideBuy, 180, 150))   // 150 at 1.2
+	ob.AddOrder(makeOrder("buy-2", domain.SideBuy, 105, 100))   // 100 at 1.05
+
+	// 1.1 and 1.2 both trade 150 and leave 50 of sells over: the lower
+	// price leans towards the sellers
+	ind := ob.Indicative()
+	if ind.Price != 1.1 || ind.Volume.Int64() != 150 || ind.Surplus.Int64() != 50 || ind.SurplusSide != domain.SideSell {
+		t.Fatalf("unexpected indicative %+v", ind)
+	}
+
+	matches := ob.SetMarket(domain.MarketStatus{State: domain.MarketOpen})
+	if len(matches) != 2 {
+		t.Fatalf("expected 2 fills, got %d", len(matches))
+	}
+	// Each fill executes at its seller's price, the one settleMatch pays
+	want := []struct {
+		buy, sell   string
+		fill, quote int64
+		price       float64
+	}{
+		{"buy-1", "sell-1", 100, 100, 1},
+		{"buy-1", "sell-2", 50, 55, 1.1},
+	}
+	for i, m := range matches {
+		w := want[i]
+		if m.BuyOrder.ID != w.buy || m.SellOrder.ID != w.sell || m.FillAmount.Int64() != w.fill ||
+			m.QuoteAmount.Int64() != w.quote || m.Price != w.price || m.TakerSide != "" {
+			t.Fatalf("fill %d: unexpected %s/%s %s (%s) at %g", i, m.BuyOrder.ID, m.SellOrder.ID, m.FillAmount, m.QuoteAmount, m.Price)
+		}
+		// The reported price is the one the quote amount was paid at
+		if float64(m.QuoteAmount.Int64()) != math.Round(float64(m.FillAmount.Int64())*m.Price) {
+			t.Fatalf("fill %d: quote %s is not %s at %g", i, m.QuoteAmount, m.FillAmount, m.Price)
+		}
+		// Each fill is within both signed limits and the clearing price,
+		// which it carries for the market to be marked at
+		if m.BuyOrder.Price() < m.Price || m.SellOrder.Price() > m.Price || m.Price > ind.Price {
+			t.Fatalf("fill %d at %g outside its limits", i, m.Price)
+		}
+		if m.ClearingPrice != ind.Price || m.MarketPrice() != ind.Price {
+			t.Fatalf("fill %d: expected clearing price %g, got %g", i, ind.Price, m.ClearingPrice)
+		}
+	}
+
+	bid, _ := ob.BestBid()
+	ask, _ := ob.BestAsk()
+	if bid != 1.05 || ask != 1.1 {
+		t.Fatalf("expected 1.05/1.1 left, got %g/%g", bid, ask)
+	}
+}
+
+func TestAuction_BalancedTieClearsNearestTheLastTrade(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		trade bool
+		want  float64
+	}{
+		{"no trade yet", false, 1}, // the middle is as far from both
+		{"after a trade at 2.5", true, 3},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ob := NewOrderBook("TKA-TKB")
+			if tc.trade {
+				ob.AddOrder(makeOrder("sell-0", domain.SideSell, 10, 25))
+				ob.AddOrder(makeOrder("buy-0", domain.SideBuy, 25, 10))
+			}
+			ob.SetMarket(domain.MarketStatus{State: domain.MarketAuction})
+			ob.AddOrder(makeOrder("sell-1", domain.SideSell, 100, 100))
+			ob.AddOrder(makeOrder("buy-1", domain.SideBuy, 300, 100))
+
+			// Both limit prices trade 100 with nothing over
+			if ind := ob.Indicative(); ind.Price != tc.want || ind.Volume.Int64() != 100 || ind.Surplus.Sign() != 0 {
+				t.Fatalf("unexpected indicative %+v", ind)
+			}
+		})
+	}
+}
+
+func TestAuction_NothingCrosses(t *testing.T) {
+	ob := NewOrderBook("TKA-TKB")
+	ob.SetMarket(domain.MarketStatus{State: domain.MarketAuction})
+	ob.AddOrder(makeOrder("sell-1", domain.SideSell, 100, 120))
+	ob.AddOrder(makeOrder("buy-1", domain.SideBuy, 100, 100))
+
+	if ind := ob.Indicative(); ind.Price != 0 || ind.Volume.Sign() != 0 {
+		t.Fatalf("expected no indicative price, got %+v", ind)
+	}
+	if matches := ob.SetMarket(domain.MarketStatus{State: domain.MarketOpen}); len(matches) != 0 {
+		t.Fatalf("expected no fills, got %d", len(matches))
+	}
+}
@@ -3,24 +3,50 @@ package orderbook
 import (
 	"container/heap"
 	"math/big"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/nexus-orderbook-dex/backend/internal/domain"
 )
 
 // MatchResult represents a single match between a buy and sell order.
 type MatchResult struct {
-	BuyOrder    *domain.Order
-	SellOrder   *domain.Order
-	FillAmount  *big.Int // base token amount
-	QuoteAmount *big.Int // quote token amount
-	Price       float64
+	BuyOrder      *domain.Order
+	SellOrder     *domain.Order
+	FillAmount    *big.Int // base token amount
+	QuoteAmount   *big.Int // quote token amount
+	Price         float64
+	TakerSide     domain.Side // side of the incoming order
+	ClearingPrice float64     // of the auction that made the fill, if any
+}
+
+// MarketPrice returns the price the fill marks the market at: its auction's
+// clearing price, or else its own.
+func (m MatchResult) MarketPrice() float64 {
+	if m.ClearingPrice > 0 {
+		return m.ClearingPrice
+	}
+	return m.Price
+}
+
+// Clone returns a copy of the match whose orders are detached from the book.
+func (m MatchResult) Clone() MatchResult {
+	return MatchResult{
+		BuyOrder:      m.BuyOrder.Clone(),
+		SellOrder:     m.SellOrder.Clone(),
+		FillAmount:    new(big.Int).Set(m.FillAmount),
+		QuoteAmount:   new(big.Int).Set(m.QuoteAmount),
+		Price:         m.Price,
+		TakerSide:     m.TakerSide,
+		ClearingPrice: m.ClearingPrice,
+	}
 }
 
 // PriceLevel represents an aggregated price level in the orderbook.
 type PriceLevel struct {
 	Price  float64  `json:"price"`
-	Amount *big.Int `json:"amount"` // total remaining base token
+	Amount *big.Int `json:"amount"` // total displayed remaining base token
 	Count  int      `json:"count"`
 }
 
@@ -37,6 +63,21 @@ type OrderBook struct {
 	buys     *BuyHeap
 	sells    *SellHeap
 	orderMap map[string]*OrderEntry // orderID -> entry
+	seq      uint64                 // last assigned entry sequence
+
+	groups      map[string]*orderGroup // groupID -> group
+	groupOf     map[string]*orderGroup // orderID -> group of that leg
+	groupEvents []GroupEvent
+
+	eventSeq uint64 // last assigned journal sequence
+	events   []Event
+
+	touched    []string // orders changed since TakeTouchedOrders, for the L3 feed
+	touchedSet map[string]bool
+
+	market    domain.MarketStatus // trading phase, see SetMarket
+	lastPrice float64             // of the last fill, the reference of auctions
+	band      *PriceBand          // limits continuous fills, see SetPriceBand
 }
 
 // NewOrderBook creates a new orderbook for the given pair.
@@ -50,25 +91,63 @@ func NewOrderBook(pair string) *OrderBook {
 		buys:     bh,
 		sells:    sh,
 		orderMap: make(map[string]*OrderEntry),
+		groups:   make(map[string]*orderGroup),
+		groupOf:  make(map[string]*orderGroup),
+		market:   domain.MarketStatus{Pair: pair, State: domain.MarketOpen},
 	}
 }
 
+// Pair returns the trading pair of the book.
+func (ob *OrderBook) Pair() string {
+	return ob.pair
+}
+
 // AddOrder adds a new order and attempts to match it. Returns match results.
 func (ob *OrderBook) AddOrder(order *domain.Order) []MatchResult {
 	ob.mu.Lock()
 	defer ob.mu.Unlock()
 
+	return ob.addOrder(order)
+}
+
+func (ob *OrderBook) addOrder(order *domain.Order) []MatchResult {
+	// A group leg may have been cancelled or exhausted by a sibling before placement
+	switch {
+	case isFinal(order):
+		ob.emit(Event{Type: EventRejected, OrderID: order.ID, Order: order.Clone(), Reason: ReasonFinal})
+		return nil
+	case order.RemainingBase().Sign() <= 0:
+		ob.emit(Event{Type: EventRejected, OrderID: order.ID, Order: order.Clone(), Reason: ReasonExhausted})
+		return nil
+	}
+	if _, resting := ob.orderMap[order.ID]; resting {
+		ob.emit(Event{Type: EventRejected, OrderID: order.ID, Order: order.Clone(), Reason: ReasonDuplicate})
+		return nil
+	}
+	if !ob.market.State.AcceptsOrders() {
+		ob.emit(Event{Type: EventRejected, OrderID: order.ID, Order: order.Clone(), Reason: ReasonMarketClosed})
+		return nil
+	}
+	ob.emit(Event{Type: EventAccepted, OrderID: order.ID, Order: order.Clone(), Band: ob.band.clone()})
+
 	var matches []MatchResult
 
-	if order.Side == domain.SideBuy {
+	// During an auction orders only rest
+	switch {
+	case ob.market.State == domain.MarketAuction:
+	case order.Side == domain.SideBuy:
 		matches = ob.matchBuy(order)
-	} else {
+	default:
 		matches = ob.matchSell(order)
 	}
 
 	// If order still has remaining quantity, add to book
-	if order.RemainingBase().Sign() > 0 && order.Status != domain.OrderStatusFilled {
-		entry := &OrderEntry{Order: order}
+	if order.RemainingBase().Sign() > 0 && !isFinal(order) {
+		ob.seq++
+		entry := &OrderEntry{Order: order, Seq: ob.seq}
+		if order.IsIceberg() {
+			entry.Visible = minBigInt(order.DisplayBase, order.RemainingBase())
+		}
 		ob.orderMap[order.ID] = entry
 		if order.Side == domain.SideBuy {
 			heap.Push(ob.buys, entry)
@@ -91,8 +170,11 @@ func (ob *OrderBook) matchBuy(buyOrder *domain.Order) []MatchResult {
 		if buyPrice < bestSell.Order.Price() {
 			break
 		}
+		if !ob.inBand(bestSell.Order.Price()) {
+			break
+		}
 
-		fillAmount := minBigInt(buyOrder.RemainingBase(), bestSell.Order.RemainingBase())
+		fillAmount := minBigInt(buyOrder.RemainingBase(), bestSell.Available())
 		if fillAmount.Sign() <= 0 {
 			break
 		}
@@ -108,7 +190,9 @@ func (ob *OrderBook) matchBuy(buyOrder *domain.Order) []MatchResult {
 			FillAmount:  new(big.Int).Set(fillAmount),
 			QuoteAmount: quoteAmount,
 			Price:       bestSell.Order.Price(),
+			TakerSide:   domain.SideBuy,
 		})
+		ob.emitMatch(matches[len(matches)-1])
 
 		// Update filled amounts
 		buyOrder.FilledBase = new(big.Int).Add(buyOrder.FilledBase, fillAmount)
@@ -122,7 +206,13 @@ func (ob *OrderBook) matchBuy(buyOrder *domain.Order) []MatchResult {
 		if bestSell.Order.Status == domain.OrderStatusFilled {
 			heap.Pop(ob.sells)
 			delete(ob.orderMap, bestSell.Order.ID)
+		} else if ob.consumeVisible(bestSell, fillAmount) {
+			heap.Fix(ob.sells, bestSell.Index)
 		}
+
+		// Resize or cancel sibling legs of grouped orders
+		ob.onOrderChanged(buyOrder)
+		ob.onOrderChanged(bestSell.Order)
 	}
 
 	return matches
@@ -139,8 +229,11 @@ func (ob *OrderBook) matchSell(sellOrder *domain.Order) []MatchResult {
 		if bestBuy.Order.Price() < sellPrice {
 			break
 		}
+		if !ob.inBand(bestBuy.Order.Price()) {
+			break
+		}
 
-		fillAmount := minBigInt(sellOrder.RemainingBase(), bestBuy.Order.RemainingBase())
+		fillAmount := minBigInt(sellOrder.RemainingBase(), bestBuy.Available())
 		if fillAmount.Sign() <= 0 {
 			break
 		}
@@ -156,7 +249,9 @@ func (ob *OrderBook) matchSell(sellOrder *domain.Order) []MatchResult {
 			FillAmount:  new(big.Int).Set(fillAmount),
 			QuoteAmount: quoteAmount,
 			Price:       bestBuy.Order.Price(),
+			TakerSide:   domain.SideSell,
 		})
+		ob.emitMatch(matches[len(matches)-1])
 
 		sellOrder.FilledBase = new(big.Int).Add(sellOrder.FilledBase, fillAmount)
 		bestBuy.Order.FilledBase = new(big.Int).Add(bestBuy.Order.FilledBase, fillAmount)
@@ -167,12 +262,79 @@ func (ob *OrderBook) matchSell(sellOrder *domain.Order) []MatchResult {
 		if bestBuy.Order.Status == domain.OrderStatusFilled {
 			heap.Pop(ob.buys)
 			delete(ob.orderMap, bestBuy.Order.ID)
+		} else if ob.consumeVisible(bestBuy, fillAmount) {
+			heap.Fix(ob.buys, bestBuy.Index)
 		}
+
+		ob.onOrderChanged(sellOrder)
+		ob.onOrderChanged(bestBuy.Order)
 	}
 
 	return matches
 }
 
+// consumeVisible takes a fill off an iceberg's displayed slice. When the slice
+// is used up it is refreshed from the hidden reserve and the entry moves to
+// the back of its price level; it returns true if the caller must re-heapify.
+func (ob *OrderBook) consumeVisible(entry *OrderEntry, fill *big.Int) bool {
+	if entry.Visible == nil {
+		return false
+	}
+	entry.Visible = new(big.Int).Sub(entry.Visible, fill)
+	if entry.Visible.Sign() > 0 {
+		return false
+	}
+	entry.Visible = minBigInt(entry.Order.DisplayBase, entry.Order.RemainingBase())
+	ob.seq++
+	entry.Seq = ob.seq
+	return true
+}
+
+// ReplaceOrder atomically swaps a resting order for a replacement order. A
+// size decrease at the same price and side keeps the original entry's queue
+// position; any other change removes the original and matches the
+// replacement as a new order. Group legs cannot be replaced. The replaced
+// order is returned cancelled, with the fills it had.
+func (ob *OrderBook) ReplaceOrder(orderID string, replacement *domain.Order) (replaced *domain.Order, matches []MatchResult, keptPriority bool, ok bool) {
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+
+	entry, ok := ob.orderMap[orderID]
+	if !ok {
+		return nil, nil, false, false
+	}
+	if _, grouped := ob.groupOf[orderID]; grouped {
+		return nil, nil, false, false
+	}
+	old := entry.Order
+	old.Status = domain.OrderStatusCancelled
+
+	keep := replacement.Side == old.Side &&
+		replacement.Price() == old.Price() &&
+		replacement.RemainingBase().Cmp(old.RemainingBase()) <= 0 &&
+		replacement.RemainingBase().Sign() > 0
+	ob.emit(Event{Type: EventReplaced, OrderID: orderID, Order: replacement.Clone(), KeptPriority: keep, Band: ob.band.clone()})
+
+	if keep {
+		delete(ob.orderMap, orderID)
+		ob.orderMap[replacement.ID] = entry
+		entry.Order = replacement
+		if replacement.IsIceberg() {
+			visible := minBigInt(replacement.DisplayBase, replacement.RemainingBase())
+			if entry.Visible != nil && entry.Visible.Cmp(visible) < 0 {
+				visible = new(big.Int).Set(entry.Visible)
+			}
+			entry.Visible = visible
+		} else {
+			entry.Visible = nil
+		}
+		return old, nil, true, true
+	}
+
+	ob.removeFromBook(old)
+	return old, ob.addOrder(replacement), false, true
+}
+
 // CancelOrder removes an order from the book.
 func (ob *OrderBook) CancelOrder(orderID string) (*domain.Order, bool) {
 	ob.mu.Lock()
@@ -180,11 +342,24 @@ func (ob *OrderBook) CancelOrder(orderID string) (*domain.Order, bool) {
 
 	entry, ok := ob.orderMap[orderID]
 	if !ok {
+		// Group legs waiting for a trigger or for their entry are not in the heaps
+		if g, grouped := ob.groupOf[orderID]; grouped {
+			for _, leg := range g.legs {
+				if leg.ID == orderID && !isFinal(leg) {
+					g.placed[leg.ID] = false
+					leg.Status = domain.OrderStatusCancelled
+					ob.emit(Event{Type: EventCancelled, OrderID: orderID})
+					ob.cancelGroupLeg(g, leg)
+					return leg, true
+				}
+			}
+		}
 		return nil, false
 	}
 
 	entry.Order.Status = domain.OrderStatusCancelled
 	delete(ob.orderMap, orderID)
+	ob.emit(Event{Type: EventCancelled, OrderID: orderID})
 
 	// Remove from heap by setting to worst priority and popping
 	if entry.Order.Side == domain.SideBuy {
@@ -193,86 +368,154 @@ func (ob *OrderBook) CancelOrder(orderID string) (*domain.Order, bool) {
 		heap.Remove(ob.sells, entry.Index)
 	}
 
+	if g, grouped := ob.groupOf[orderID]; grouped {
+		g.placed[orderID] = false
+		ob.cancelGroupLeg(g, entry.Order)
+	}
+
 	return entry.Order, true
 }
 
-// GetSnapshot returns the current orderbook state aggregated by price level.
-func (ob *OrderBook) GetSnapshot() Snapshot {
-	ob.mu.RLock()
-	defer ob.mu.RUnlock()
+// ExpireOrders removes every order whose signed expiry is at or before now,
+// including group legs waiting outside the book, and returns them with
+// status expired. Resting orders expire in queue order.
+func (ob *OrderBook) ExpireOrders(now time.Time) []*domain.Order {
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
 
-	bids := aggregateLevels(ob.buys)
-	asks := aggregateSellLevels(ob.sells)
+	cutoff := uint64(now.Unix())
+	expired := func(o *domain.Order) bool {
+		return o.Expiry != 0 && o.Expiry <= cutoff && !isFinal(o)
+	}
 
-	return Snapshot{Bids: bids, Asks: asks}
-}
+	var entries []*OrderEntry
+	for _, entry := range ob.orderMap {
+		if expired(entry.Order) {
+			entries = append(entries, entry)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Seq < entries[j].Seq })
 
-func aggregateLevels(h *BuyHeap) []PriceLevel {
-	levels := make(map[float64]*PriceLevel)
-	var prices []float64
-	for _, entry := range *h {
-		p := entry.Order.Price()
-		if lvl, ok := levels[p]; ok {
-			lvl.Amount = new(big.Int).Add(lvl.Amount, entry.Order.RemainingBase())
-			lvl.Count++
-		} else {
-			levels[p] = &PriceLevel{
-				Price:  p,
-				Amount: new(big.Int).Set(entry.Order.RemainingBase()),
-				Count:  1,
+	candidates := make([]*domain.Order, 0, len(entries))
+	for _, entry := range entries {
+		candidates = append(candidates, entry.Order)
+	}
+	var unplaced []*domain.Order
+	for id, g := range ob.groupOf {
+		if _, resting := ob.orderMap[id]; resting {
+			continue
+		}
+		for _, leg := range g.legs {
+			if leg.ID == id && expired(leg) {
+				unplaced = append(unplaced, leg)
 			}
-			prices = append(prices, p)
 		}
 	}
-	// Sort descending (best bid first)
-	sortDesc(prices)
-	result := make([]PriceLevel, 0, len(prices))
-	for _, p := range prices {
-		result = append(result, *levels[p])
+	sort.Slice(unplaced, func(i, j int) bool { return unplaced[i].ID < unplaced[j].ID })
+	candidates = append(candidates, unplaced...)
+
+	var out []*domain.Order
+	for _, order := range candidates {
+		// An earlier expiry may already have cancelled a sibling leg
+		if isFinal(order) {
+			continue
+		}
+		ob.expireOrder(order)
+		out = append(out, order)
 	}
-	return result
+	return out
 }
 
-func aggregateSellLevels(h *SellHeap) []PriceLevel {
-	levels := make(map[float64]*PriceLevel)
-	var prices []float64
-	for _, entry := range *h {
-		p := entry.Order.Price()
-		if lvl, ok := levels[p]; ok {
-			lvl.Amount = new(big.Int).Add(lvl.Amount, entry.Order.RemainingBase())
-			lvl.Count++
-		} else {
-			levels[p] = &PriceLevel{
-				Price:  p,
-				Amount: new(big.Int).Set(entry.Order.RemainingBase()),
-				Count:  1,
+// ExpireOrder expires a single order by ID regardless of its expiry time.
+// It is used to re-apply journalled expiries.
+func (ob *OrderBook) ExpireOrder(orderID string) (*domain.Order, bool) {
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+
+	order := ob.lookup(orderID)
+	if order == nil || isFinal(order) {
+		return nil, false
+	}
+	ob.expireOrder(order)
+	return order, true
+}
+
+func (ob *OrderBook) expireOrder(order *domain.Order) {
+	ob.removeFromBook(order)
+	order.Status = domain.OrderStatusExpired
+	ob.emit(Event{Type: EventExpired, OrderID: order.ID})
+	if g, grouped := ob.groupOf[order.ID]; grouped {
+		g.placed[order.ID] = false
+		ob.cancelGroupLeg(g, order)
+	}
+}
+
+// lookup finds a resting order or a live group leg by ID.
+func (ob *OrderBook) lookup(orderID string) *domain.Order {
+	if entry, ok := ob.orderMap[orderID]; ok {
+		return entry.Order
+	}
+	if g, grouped := ob.groupOf[orderID]; grouped {
+		for _, leg := range g.legs {
+			if leg.ID == orderID {
+				return leg
 			}
-			prices = append(prices, p)
 		}
 	}
-	// Sort ascending (best ask first)
-	sortAsc(prices)
-	result := make([]PriceLevel, 0, len(prices))
-	for _, p := range prices {
-		result = append(result, *levels[p])
+	return nil
+}
+
+// BestBid returns the highest bid price, false if there are no bids.
+func (ob *OrderBook) BestBid() (float64, bool) {
+	ob.mu.RLock()
+	defer ob.mu.RUnlock()
+
+	if ob.buys.Len() == 0 {
+		return 0, false
 	}
-	return result
+	return (*ob.buys)[0].Order.Price(), true
 }
 
-func sortDesc(s []float64) {
-	for i := 1; i < len(s); i++ {
-		for j := i; j > 0 && s[j] > s[j-1]; j-- {
-			s[j], s[j-1] = s[j-1], s[j]
-		}
+// BestAsk returns the lowest ask price, false if there are no asks.
+func (ob *OrderBook) BestAsk() (float64, bool) {
+	ob.mu.RLock()
+	defer ob.mu.RUnlock()
+
+	if ob.sells.Len() == 0 {
+		return 0, false
 	}
+	return (*ob.sells)[0].Order.Price(), true
+}
+
+// GetSnapshot returns the current orderbook state aggregated by price level.
+func (ob *OrderBook) GetSnapshot() Snapshot {
+	ob.mu.RLock()
+	defer ob.mu.RUnlock()
+
+	// Best first: highest bid, lowest ask
+	bids := aggregateLevels(*ob.buys, func(a, b float64) bool { return a > b })
+	asks := aggregateLevels(*ob.sells, func(a, b float64) bool { return a < b })
+
+	return Snapshot{Bids: bids, Asks: asks}
 }
 
-func sortAsc(s []float64) {
-	for i := 1; i < len(s); i++ {
-		for j := i; j > 0 && s[j] < s[j-1]; j-- {
-			s[j], s[j-1] = s[j-1], s[j]
+// aggregateLevels sums the entries of one side by price in a single pass
+// and orders the levels best first.
+func aggregateLevels(entries []*OrderEntry, better func(a, b float64) bool) []PriceLevel {
+	index := make(map[float64]int)
+	levels := make([]PriceLevel, 0)
+	for _, entry := range entries {
+		p := entry.Order.Price()
+		if i, ok := index[p]; ok {
+			levels[i].Amount.Add(levels[i].Amount, entry.Available())
+			levels[i].Count++
+			continue
 		}
+		index[p] = len(levels)
+		levels = append(levels, PriceLevel{Price: p, Amount: new(big.Int).Set(entry.Available()), Count: 1})
 	}
+	sort.Slice(levels, func(i, j int) bool { return better(levels[i].Price, levels[j].Price) })
+	return levels
 }
 
 func updateOrderStatus(o *domain.Order) {
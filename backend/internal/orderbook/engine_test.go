@@ -1,6 +1,7 @@
 package orderbook
 
 import (
+	"fmt"
 	"math/big"
 	"testing"
 	"time"
@@ -239,3 +240,149 @@ func TestCancelOrder(t *testing.T) {
 		t.Fatalf("expected 0 bids after cancel, got %d", len(snap.Bids))
 	}
 }
+
+func TestReplaceOrder_SizeDecreaseKeepsPriority(t *testing.T) {
+	ob := NewOrderBook("TKA-TKB")
+
+	ob.AddOrder(makeOrder("sell-1", domain.SideSell, 100, 200))
+	ob.AddOrder(makeOrder("sell-2", domain.SideSell, 100, 200))
+
+	// Shrink sell-1 to 50 at the same price of 2
+	replaced, matches, kept, ok := ob.ReplaceOrder("sell-1", makeOrder("sell-1b", domain.SideSell, 50, 100))
+	if !ok || !kept || len(matches) != 0 {
+		t.Fatalf("expected in-place replace, got ok=%v kept=%v matches=%d", ok, kept, len(matches))
+	}
+	if replaced.ID != "sell-1" || replaced.Status != domain.OrderStatusCancelled {
+		t.Fatalf("expected sell-1 returned cancelled, got %s %s", replaced.ID, replaced.Status)
+	}
+
+	fill := ob.AddOrder(makeOrder("buy-1", domain.SideBuy, 100, 50))
+	if len(fill) != 1 || fill[0].SellOrder.ID != "sell-1b" {
+		t.Fatalf("expected replacement to keep the front of the queue, got %v", fill)
+	}
+}
+
+func TestReplaceOrder_SizeIncreaseRequeues(t *testing.T) {
+	ob := NewOrderBook("TKA-TKB")
+
+	ob.AddOrder(makeOrder("sell-1", domain.SideSell, 100, 200))
+	ob.AddOrder(makeOrder("sell-2", domain.SideSell, 100, 200))
+
+	_, _, kept, ok := ob.ReplaceOrder("sell-1", makeOrder("sell-1b", domain.SideSell, 150, 300))
+	if !ok || kept {
+		t.Fatalf("expected requeue, got ok=%v kept=%v", ok, kept)
+	}
+
+	fill := ob.AddOrder(makeOrder("buy-1", domain.SideBuy, 200, 100))
+	if len(fill) != 1 || fill[0].SellOrder.ID != "sell-2" {
+		t.Fatalf("expected sell-2 to move ahead, got %v", fill)
+	}
+}
+
+func TestReplaceOrder_PriceChangeMatches(t *testing.T) {
+	ob := NewOrderBook("TKA-TKB")
+
+	ob.AddOrder(makeOrder("buy-1", domain.SideBuy, 200, 100))   // bid at 2
+	ob.AddOrder(makeOrder("sell-1", domain.SideSell, 100, 300)) // ask at 3
+
+	// Re-price the ask down to 2: it crosses the bid in the same operation
+	_, matches, kept, ok := ob.ReplaceOrder("sell-1", makeOrder("sell-1b", domain.SideSell, 100, 200))
+	if !ok || kept {
+		t.Fatalf("expected requeue, got ok=%v kept=%v", ok, kept)
+	}
+	if len(matches) != 1 || matches[0].FillAmount.Int64() != 100 {
+		t.Fatalf("expected replacement to fill 100, got %v", matches)
+	}
+
+	snap := ob.GetSnapshot()
+	if len(snap.Bids) != 0 || len(snap.Asks) != 0 {
+		t.Fatalf("expected empty book, got %d bids, %d asks", len(snap.Bids), len(snap.Asks))
+	}
+}
+
+func TestReplaceOrder_NotInBook(t *testing.T) {
+	ob := NewOrderBook("TKA-TKB")
+
+	if _, _, _, ok := ob.ReplaceOrder("missing", makeOrder("sell-1", domain.SideSell, 100, 200)); ok {
+		t.Fatal("replace of unknown order should fail")
+	}
+}
+
+func TestBestBidAndAsk(t *testing.T) {
+	ob := NewOrderBook("TKA-TKB")
+	if _, ok := ob.BestBid(); ok {
+		t.Fatal("expected no best bid in an empty book")
+	}
+
+	ob.AddOrder(makeOrder("buy-1", domain.SideBuy, 200, 100))   // price 2
+	ob.AddOrder(makeOrder("buy-2", domain.SideBuy, 250, 100))   // price 2.5
+	ob.AddOrder(makeOrder("sell-1", domain.SideSell, 100, 400)) // price 4
+	ob.AddOrder(makeOrder("sell-2", domain.SideSell, 100, 300)) // price 3
+
+	if bid, ok := ob.BestBid(); !ok || bid != 2.5 {
+		t.Fatalf("expected best bid 2.5, got %v (%v)", bid, ok)
+	}
+	if ask, ok := ob.BestAsk(); !ok || ask != 3 {
+		t.Fatalf("expected best ask 3, got %v (%v)", ask, ok)
+	}
+}
+
+func TestSnapshotView_GroupsAndLimitsDepth(t *testing.T) {
+	ob := NewOrderBook("TKA-TKB")
+	// Bids at 2.5, 2.4, 2.3, 1.9 and asks at 3.1, 3.2, 4.6 (amount 100 each)
+	for i, sell := range []int64{250, 240, 230, 190} {
+		ob.AddOrder(makeOrder(fmt.Sprintf("buy-%d", i), domain.SideBuy, sell, 100))
+	}
+	for i, buy := range []int64{310, 320, 460} {
+		ob.AddOrder(makeOrder(fmt.Sprintf("sell-%d", i), domain.SideSell, 100, buy))
+	}
+	snap := ob.GetSnapshot()
+
+	top := snap.View(BookView{Depth: 2})
+	if len(top.Bids) != 2 || top.Bids[0].Price != 2.5 || top.Bids[1].Price != 2.4 || len(top.Asks) != 2 {
+		t.Fatalf("unexpected depth 2 view %+v", top)
+	}
+
+	grouped := snap.View(BookView{Group: 1})
+	if len(grouped.Bids) != 2 || grouped.Bids[0].Price != 2 || grouped.Bids[0].Amount.Int64() != 300 || grouped.Bids[0].Count != 3 {
+		t.Fatalf("expected bids grouped down into 2 and 1, got %+v", grouped.Bids)
+	}
+	if len(grouped.Asks) != 2 || grouped.Asks[0].Price != 4 || grouped.Asks[0].Amount.Int64() != 200 || grouped.Asks[1].Price != 5 {
+		t.Fatalf("expected asks grouped up into 4 and 5, got %+v", grouped.Asks)
+	}
+
+	fine := snap.View(BookView{Depth: 1, Group: 0.1})
+	if len(fine.Bids) != 1 || fine.Bids[0].Price != 2.5 || fine.Asks[0].Price != 3.1 {
+		t.Fatalf("unexpected 0.1 grouping %+v", fine)
+	}
+
+	// Views never share levels with the snapshot
+	grouped.Bids[0].Amount.SetInt64(0)
+	if snap.Bids[0].Amount.Int64() != 100 {
+		t.Fatal("view modified the snapshot")
+	}
+}
+
+func TestL3Snapshot_OrdersByPriceThenQueue(t *testing.T) {
+	ob := NewOrderBook("TKA-TKB")
+	ob.AddOrder(makeOrder("buy-1", domain.SideBuy, 200, 100)) // price 2
+	ob.AddOrder(makeOrder("buy-2", domain.SideBuy, 250, 100)) // price 2.5
+	ob.AddOrder(makeOrder("buy-3", domain.SideBuy, 100, 50))  // price 2, behind buy-1
+
+	snap := ob.L3Snapshot()
+	if len(snap.Bids) != 3 || snap.Seq != ob.EventSeq() {
+		t.Fatalf("unexpected snapshot %+v", snap)
+	}
+	want := []struct {
+		id       string
+		position int
+	}{{"buy-2", 0}, {"buy-1", 0}, {"buy-3", 1}}
+	for i, w := range want {
+		if got := snap.Bids[i]; got.ID != w.id || got.Position != w.position {
+			t.Fatalf("bid %d: expected %s at position %d, got %s at %d", i, w.id, w.position, got.ID, got.Position)
+		}
+	}
+	if snap.Bids[2].Size.Int64() != 50 {
+		t.Fatalf("expected size 50, got %s", snap.Bids[2].Size)
+	}
+}
@@ -0,0 +1,277 @@
+package orderbook
+
+import (
+	"container/heap"
+	"math/big"
+
+	"github.com/nexus-orderbook-dex/backend/internal/domain"
+)
+
+type GroupEventType string
+
+const (
+	GroupEventActivated GroupEventType = "activated" // leg released for placement (bracket exit)
+	GroupEventResized   GroupEventType = "resized"   // leg BaseCap or status changed
+	GroupEventSuspended GroupEventType = "suspended" // bracket exit exhausted while entry still works
+	GroupEventCancelled GroupEventType = "cancelled" // leg cancelled by a sibling
+	GroupEventCompleted GroupEventType = "completed" // group reached a final status
+)
+
+// GroupEvent reports a change the engine applied to a group leg as a result
+// of a fill or cancel on another leg. Order is nil for GroupEventCompleted.
+type GroupEvent struct {
+	Type  GroupEventType
+	Group *domain.OrderGroup
+	Order *domain.Order
+}
+
+type orderGroup struct {
+	group  *domain.OrderGroup
+	legs   []*domain.Order // bracket: legs[0] is the entry
+	placed map[string]bool // legs currently resting in the book or waiting for a trigger
+}
+
+// AddGroup registers an order group with the book. Legs must carry their
+// persisted IDs and the caps they should start with; the caller places the
+// active legs (AddOrder or a TriggerStore) afterwards. Bracket exits start
+// unplaced and are released through GroupEventActivated as the entry fills.
+func (ob *OrderBook) AddGroup(group *domain.OrderGroup, legs []*domain.Order) {
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+
+	recorded := *group
+	clones := make([]*domain.Order, len(legs))
+	for i, leg := range legs {
+		clones[i] = leg.Clone()
+	}
+	ob.emit(Event{Type: EventGroupAdded, Group: &recorded, Legs: clones, Band: ob.band.clone()})
+
+	g := &orderGroup{group: group, legs: legs, placed: make(map[string]bool)}
+	ob.groups[group.ID] = g
+	for i, leg := range legs {
+		ob.groupOf[leg.ID] = g
+		if group.Type == domain.GroupTypeOCO || i == 0 || leg.RemainingBase().Sign() > 0 {
+			g.placed[leg.ID] = true
+		}
+	}
+}
+
+// CancelGroup cancels every live leg of a group.
+func (ob *OrderBook) CancelGroup(groupID string) bool {
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+
+	g, ok := ob.groups[groupID]
+	if !ok {
+		return false
+	}
+	recorded := *g.group
+	ob.emit(Event{Type: EventGroupCancelled, Group: &recorded})
+	ob.cancelLegs(g, g.legs)
+	ob.settleGroup(g)
+	return true
+}
+
+// GroupLeg returns the group leg with the given ID if its group is still
+// active, whether or not the leg rests in the book.
+func (ob *OrderBook) GroupLeg(orderID string) *domain.Order {
+	ob.mu.RLock()
+	defer ob.mu.RUnlock()
+
+	if _, grouped := ob.groupOf[orderID]; !grouped {
+		return nil
+	}
+	return ob.lookup(orderID)
+}
+
+// TakeGroupEvents returns and clears the group events produced since the last call.
+func (ob *OrderBook) TakeGroupEvents() []GroupEvent {
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+
+	events := ob.groupEvents
+	ob.groupEvents = nil
+	return events
+}
+
+// cancelGroupLeg applies an explicit cancel of one leg to its siblings.
+// Cancelling an OCO leg cancels the whole group; cancelling a bracket exit
+// cancels all exits; cancelling a bracket entry caps the exits at what the
+// entry already filled.
+func (ob *OrderBook) cancelGroupLeg(g *orderGroup, order *domain.Order) {
+	switch {
+	case g.group.Type == domain.GroupTypeOCO:
+		ob.cancelLegs(g, g.legs)
+	case order.GroupRole == domain.GroupRoleExit:
+		ob.cancelLegs(g, g.legs[1:])
+	}
+	ob.rebalanceGroup(g)
+}
+
+// onOrderChanged rebalances the group of an order after a fill or cancel.
+func (ob *OrderBook) onOrderChanged(order *domain.Order) {
+	if g, ok := ob.groupOf[order.ID]; ok {
+		ob.rebalanceGroup(g)
+	}
+}
+
+func (ob *OrderBook) rebalanceGroup(g *orderGroup) {
+	if g.group.Status != domain.GroupStatusActive {
+		return
+	}
+
+	switch g.group.Type {
+	case domain.GroupTypeOCO:
+		// Every leg shrinks by what all legs have filled together
+		filled := sumFilled(g.legs)
+		for _, leg := range g.legs {
+			if isFinal(leg) {
+				continue
+			}
+			left := new(big.Int).Sub(leg.BaseAmount(), filled)
+			ob.setLegCap(g, leg, capAt(leg, left))
+			if leg.RemainingBase().Sign() <= 0 {
+				ob.cancelLegs(g, []*domain.Order{leg})
+			}
+		}
+
+	case domain.GroupTypeBracket:
+		entry, exits := g.legs[0], g.legs[1:]
+		entryDone := isFinal(entry)
+		avail := new(big.Int).Sub(entry.FilledBase, sumFilled(exits))
+		for _, exit := range exits {
+			if exit.Status == domain.OrderStatusCancelled || exit.Status == domain.OrderStatusExpired || exit.FilledBase.Cmp(exit.BaseAmount()) >= 0 {
+				continue
+			}
+			ob.setLegCap(g, exit, capAt(exit, avail))
+			if exit.RemainingBase().Sign() > 0 {
+				if !g.placed[exit.ID] {
+					g.placed[exit.ID] = true
+					exit.Status = activeStatus(exit)
+					ob.emitGroupEvent(GroupEventActivated, g, exit)
+				}
+				continue
+			}
+			switch {
+			case entryDone && exit.FilledBase.Sign() > 0:
+				ob.removeFromBook(exit)
+				g.placed[exit.ID] = false
+				exit.Status = domain.OrderStatusFilled
+				ob.emitGroupEvent(GroupEventResized, g, exit)
+			case entryDone:
+				ob.cancelLegs(g, []*domain.Order{exit})
+			case g.placed[exit.ID]:
+				ob.removeFromBook(exit)
+				g.placed[exit.ID] = false
+				exit.Status = domain.OrderStatusPending
+				ob.emitGroupEvent(GroupEventSuspended, g, exit)
+			}
+		}
+	}
+
+	ob.settleGroup(g)
+}
+
+// setLegCap updates a leg's BaseCap, reporting the change as a resize.
+func (ob *OrderBook) setLegCap(g *orderGroup, leg *domain.Order, limit *big.Int) {
+	if leg.BaseCap != nil && leg.BaseCap.Cmp(limit) == 0 {
+		return
+	}
+	leg.BaseCap = limit
+	if leg.RemainingBase().Sign() > 0 && g.placed[leg.ID] {
+		ob.emitGroupEvent(GroupEventResized, g, leg)
+	}
+}
+
+func (ob *OrderBook) cancelLegs(g *orderGroup, legs []*domain.Order) {
+	for _, leg := range legs {
+		if isFinal(leg) {
+			continue
+		}
+		ob.removeFromBook(leg)
+		g.placed[leg.ID] = false
+		leg.Status = domain.OrderStatusCancelled
+		ob.emit(Event{Type: EventCancelled, OrderID: leg.ID, Reason: ReasonGroup})
+		ob.emitGroupEvent(GroupEventCancelled, g, leg)
+	}
+}
+
+// settleGroup marks the group completed once no leg can trade any more.
+func (ob *OrderBook) settleGroup(g *orderGroup) {
+	if g.group.Status != domain.GroupStatusActive {
+		return
+	}
+	for _, leg := range g.legs {
+		if !isFinal(leg) {
+			return
+		}
+	}
+
+	g.group.Status = domain.GroupStatusCancelled
+	if sumFilled(g.legs).Sign() > 0 {
+		g.group.Status = domain.GroupStatusCompleted
+	}
+	delete(ob.groups, g.group.ID)
+	for _, leg := range g.legs {
+		delete(ob.groupOf, leg.ID)
+	}
+	ob.emitGroupEvent(GroupEventCompleted, g, nil)
+}
+
+func (ob *OrderBook) removeFromBook(order *domain.Order) {
+	entry, ok := ob.orderMap[order.ID]
+	if !ok {
+		return
+	}
+	delete(ob.orderMap, order.ID)
+	if order.Side == domain.SideBuy {
+		heap.Remove(ob.buys, entry.Index)
+	} else {
+		heap.Remove(ob.sells, entry.Index)
+	}
+}
+
+func (ob *OrderBook) emitGroupEvent(t GroupEventType, g *orderGroup, order *domain.Order) {
+	ob.groupEvents = append(ob.groupEvents, GroupEvent{Type: t, Group: g.group, Order: order})
+	if order != nil {
+		ob.touch(order.ID)
+	}
+}
+
+func isFinal(o *domain.Order) bool {
+	return o.Status == domain.OrderStatusFilled ||
+		o.Status == domain.OrderStatusCancelled ||
+		o.Status == domain.OrderStatusExpired
+}
+
+// activeStatus is the status of a leg released to trade.
+func activeStatus(o *domain.Order) domain.OrderStatus {
+	switch {
+	case o.IsConditional() && o.TriggeredAt == nil:
+		return domain.OrderStatusPending
+	case o.FilledBase.Sign() > 0:
+		return domain.OrderStatusPartiallyFilled
+	}
+	return domain.OrderStatusOpen
+}
+
+// capAt returns the BaseCap leaving a leg `left` more base to fill, bounded
+// by its signed amount.
+func capAt(o *domain.Order, left *big.Int) *big.Int {
+	c := new(big.Int).Set(o.FilledBase)
+	if left.Sign() > 0 {
+		c.Add(c, left)
+	}
+	if c.Cmp(o.BaseAmount()) > 0 {
+		c.Set(o.BaseAmount())
+	}
+	return c
+}
+
+func sumFilled(orders []*domain.Order) *big.Int {
+	total := new(big.Int)
+	for _, o := range orders {
+		total.Add(total, o.FilledBase)
+	}
+	return total
+}
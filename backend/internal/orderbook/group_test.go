@@ -0,0 +1,169 @@
+package orderbook
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/nexus-orderbook-dex/backend/internal/domain"
+)
+
+func makeGroup(id string, groupType domain.GroupType, legs ...*domain.Order) *domain.OrderGroup {
+	group := &domain.OrderGroup{ID: id, Type: groupType, Pair: "TKA-TKB", Status: domain.GroupStatusActive}
+	for i, leg := range legs {
+		leg.GroupID = id
+		switch {
+		case groupType == domain.GroupTypeOCO:
+			leg.GroupRole = domain.GroupRoleLeg
+		case i == 0:
+			leg.GroupRole = domain.GroupRoleEntry
+		default:
+			leg.GroupRole = domain.GroupRoleExit
+			leg.Status = domain.OrderStatusPending
+			leg.BaseCap = big.NewInt(0)
+		}
+	}
+	return group
+}
+
+func eventsOf(events []GroupEvent, t GroupEventType) []GroupEvent {
+	var out []GroupEvent
+	for _, ev := range events {
+		if ev.Type == t {
+			out = append(out, ev)
+		}
+	}
+	return out
+}
+
+func TestOCO_PartialFillResizesSibling(t *testing.T) {
+	ob := NewOrderBook("TKA-TKB")
+
+	// Sell 100 TKA at 3 or at 4, whichever fills first
+	legA := makeOrder("oco-a", domain.SideSell, 100, 300)
+	legB := makeOrder("oco-b", domain.SideSell, 100, 400)
+	group := makeGroup("g-1", domain.GroupTypeOCO, legA, legB)
+	ob.AddGroup(group, []*domain.Order{legA, legB})
+	ob.AddOrder(legA)
+	ob.AddOrder(legB)
+
+	// Buy 40 at 3 partially fills leg A
+	ob.AddOrder(makeOrder("buy-1", domain.SideBuy, 120, 40))
+
+	if legB.RemainingBase().Int64() != 60 {
+		t.Fatalf("expected leg B resized to 60, got %s", legB.RemainingBase())
+	}
+	if len(eventsOf(ob.TakeGroupEvents(), GroupEventResized)) == 0 {
+		t.Fatal("expected a resize event")
+	}
+
+	// Buy the remaining 60 of leg A: leg B must be cancelled and the group completed
+	ob.AddOrder(makeOrder("buy-2", domain.SideBuy, 180, 60))
+
+	if legB.Status != domain.OrderStatusCancelled {
+		t.Fatalf("expected leg B cancelled, got %s", legB.Status)
+	}
+	if group.Status != domain.GroupStatusCompleted {
+		t.Fatalf("expected group completed, got %s", group.Status)
+	}
+	snap := ob.GetSnapshot()
+	if len(snap.Asks) != 0 {
+		t.Fatalf("expected no resting asks, got %d", len(snap.Asks))
+	}
+}
+
+func TestOCO_CancelLegCancelsSiblings(t *testing.T) {
+	ob := NewOrderBook("TKA-TKB")
+
+	legA := makeOrder("oco-a", domain.SideSell, 100, 300)
+	legB := makeOrder("oco-b", domain.SideBuy, 100, 100)
+	group := makeGroup("g-1", domain.GroupTypeOCO, legA, legB)
+	ob.AddGroup(group, []*domain.Order{legA, legB})
+	ob.AddOrder(legA)
+	ob.AddOrder(legB)
+
+	if _, ok := ob.CancelOrder("oco-a"); !ok {
+		t.Fatal("cancel should succeed")
+	}
+	if legB.Status != domain.OrderStatusCancelled {
+		t.Fatalf("expected leg B cancelled, got %s", legB.Status)
+	}
+	if group.Status != domain.GroupStatusCancelled {
+		t.Fatalf("expected group cancelled, got %s", group.Status)
+	}
+	snap := ob.GetSnapshot()
+	if len(snap.Bids) != 0 || len(snap.Asks) != 0 {
+		t.Fatalf("expected empty book, got %d bids, %d asks", len(snap.Bids), len(snap.Asks))
+	}
+}
+
+func TestBracket_ExitsFollowEntryFills(t *testing.T) {
+	ob := NewOrderBook("TKA-TKB")
+
+	// Entry: buy 100 at 2; exit: sell 100 at 3
+	entry := makeOrder("entry", domain.SideBuy, 200, 100)
+	exit := makeOrder("tp", domain.SideSell, 100, 300)
+	group := makeGroup("g-1", domain.GroupTypeBracket, entry, exit)
+	ob.AddGroup(group, []*domain.Order{entry, exit})
+	ob.AddOrder(entry)
+
+	// Seller fills 40 of the entry: exit is activated for 40
+	ob.AddOrder(makeOrder("sell-1", domain.SideSell, 40, 80))
+	activated := eventsOf(ob.TakeGroupEvents(), GroupEventActivated)
+	if len(activated) != 1 || activated[0].Order.ID != "tp" {
+		t.Fatalf("expected exit activated, got %v", activated)
+	}
+	if exit.RemainingBase().Int64() != 40 {
+		t.Fatalf("expected exit sized 40, got %s", exit.RemainingBase())
+	}
+	ob.AddOrder(exit)
+
+	// Buyer lifts the exit: it is suspended until the entry fills again
+	ob.AddOrder(makeOrder("buy-1", domain.SideBuy, 120, 40))
+	if len(eventsOf(ob.TakeGroupEvents(), GroupEventSuspended)) != 1 {
+		t.Fatal("expected exit suspended")
+	}
+	if exit.Status != domain.OrderStatusPending {
+		t.Fatalf("expected suspended exit pending, got %s", exit.Status)
+	}
+
+	// Entry fills the rest: exit is re-activated for the new 60
+	ob.AddOrder(makeOrder("sell-2", domain.SideSell, 60, 120))
+	if len(eventsOf(ob.TakeGroupEvents(), GroupEventActivated)) != 1 {
+		t.Fatal("expected exit re-activated")
+	}
+	if exit.RemainingBase().Int64() != 60 {
+		t.Fatalf("expected exit sized 60, got %s", exit.RemainingBase())
+	}
+	ob.AddOrder(exit)
+
+	ob.AddOrder(makeOrder("buy-2", domain.SideBuy, 180, 60))
+	if exit.Status != domain.OrderStatusFilled {
+		t.Fatalf("expected exit filled, got %s", exit.Status)
+	}
+	if group.Status != domain.GroupStatusCompleted {
+		t.Fatalf("expected group completed, got %s", group.Status)
+	}
+}
+
+func TestBracket_CancelUnfilledEntryCancelsExits(t *testing.T) {
+	ob := NewOrderBook("TKA-TKB")
+
+	entry := makeOrder("entry", domain.SideBuy, 200, 100)
+	tp := makeOrder("tp", domain.SideSell, 100, 300)
+	sl := makeOrder("sl", domain.SideSell, 100, 150)
+	sl.Type = domain.OrderTypeStopLoss
+	sl.TriggerPrice = 1.5
+	group := makeGroup("g-1", domain.GroupTypeBracket, entry, tp, sl)
+	ob.AddGroup(group, []*domain.Order{entry, tp, sl})
+	ob.AddOrder(entry)
+
+	if _, ok := ob.CancelOrder("entry"); !ok {
+		t.Fatal("cancel should succeed")
+	}
+	if tp.Status != domain.OrderStatusCancelled || sl.Status != domain.OrderStatusCancelled {
+		t.Fatalf("expected exits cancelled, got %s / %s", tp.Status, sl.Status)
+	}
+	if group.Status != domain.GroupStatusCancelled {
+		t.Fatalf("expected group cancelled, got %s", group.Status)
+	}
+}
@@ -2,14 +2,27 @@ package orderbook
 
 import (
 	"container/heap"
+	"math/big"
 
 	"github.com/nexus-orderbook-dex/backend/internal/domain"
 )
 
 // OrderEntry is a wrapper for an order in the heap.
 type OrderEntry struct {
-	Order *domain.Order
-	Index int
+	Order   *domain.Order
+	Index   int
+	Seq     uint64   // arrival sequence in the book, used for time priority
+	Visible *big.Int // displayed slice of an iceberg order, nil otherwise
+}
+
+// Available returns how much of the entry can trade right now: the displayed
+// slice for icebergs, the full remaining size otherwise.
+func (e *OrderEntry) Available() *big.Int {
+	remaining := e.Order.RemainingBase()
+	if e.Visible != nil && e.Visible.Cmp(remaining) < 0 {
+		return new(big.Int).Set(e.Visible)
+	}
+	return remaining
 }
 
 // BuyHeap is a max-heap by price (highest price first), then by time (earliest first).
@@ -22,7 +35,7 @@ func (h BuyHeap) Less(i, j int) bool {
 	if pi != pj {
 		return pi > pj // max-heap: higher price = better buy
 	}
-	return h[i].Order.CreatedAt.Before(h[j].Order.CreatedAt) // time priority
+	return h[i].Seq < h[j].Seq // time priority
 }
 func (h BuyHeap) Swap(i, j int) {
 	h[i], h[j] = h[j], h[i]
@@ -54,7 +67,7 @@ func (h SellHeap) Less(i, j int) bool {
 	if pi != pj {
 		return pi < pj // min-heap: lower price = better sell
 	}
-	return h[i].Order.CreatedAt.Before(h[j].Order.CreatedAt)
+	return h[i].Seq < h[j].Seq
 }
 func (h SellHeap) Swap(i, j int) {
 	h[i], h[j] = h[j], h[i]
@@ -0,0 +1,91 @@
+package orderbook
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/nexus-orderbook-dex/backend/internal/domain"
+)
+
+func makeIceberg(id string, side domain.Side, amountSell, amountBuy, display int64) *domain.Order {
+	o := makeOrder(id, side, amountSell, amountBuy)
+	o.DisplayBase = big.NewInt(display)
+	return o
+}
+
+func TestIceberg_SnapshotShowsDisplayOnly(t *testing.T) {
+	ob := NewOrderBook("TKA-TKB")
+
+	// Sell 100 TKA at 2, showing 10 at a time
+	ob.AddOrder(makeIceberg("ice-1", domain.SideSell, 100, 200, 10))
+
+	snap := ob.GetSnapshot()
+	if len(snap.Asks) != 1 {
+		t.Fatalf("expected 1 ask level, got %d", len(snap.Asks))
+	}
+	if snap.Asks[0].Amount.Int64() != 10 {
+		t.Fatalf("expected displayed amount 10, got %s", snap.Asks[0].Amount)
+	}
+}
+
+func TestIceberg_TakerSweepsHiddenReserve(t *testing.T) {
+	ob := NewOrderBook("TKA-TKB")
+
+	ice := makeIceberg("ice-1", domain.SideSell, 100, 200, 10)
+	ob.AddOrder(ice)
+
+	// A taker for 25 fills the slice, then the refreshed slices
+	matches := ob.AddOrder(makeOrder("buy-1", domain.SideBuy, 50, 25))
+
+	total := new(big.Int)
+	for _, m := range matches {
+		total.Add(total, m.FillAmount)
+		if m.FillAmount.Int64() > 10 {
+			t.Fatalf("fill %s exceeds display size", m.FillAmount)
+		}
+	}
+	if total.Int64() != 25 {
+		t.Fatalf("expected total fill 25, got %s", total)
+	}
+	if ice.FilledBase.Int64() != 25 || ice.Status != domain.OrderStatusPartiallyFilled {
+		t.Fatalf("expected iceberg partially filled 25, got %s (%s)", ice.FilledBase, ice.Status)
+	}
+
+	// 5 left in the current slice
+	snap := ob.GetSnapshot()
+	if snap.Asks[0].Amount.Int64() != 5 {
+		t.Fatalf("expected 5 displayed, got %s", snap.Asks[0].Amount)
+	}
+}
+
+func TestIceberg_RefreshLosesPriority(t *testing.T) {
+	ob := NewOrderBook("TKA-TKB")
+
+	ice := makeIceberg("ice-1", domain.SideSell, 100, 200, 10)
+	ob.AddOrder(ice)
+	ob.AddOrder(makeOrder("sell-2", domain.SideSell, 10, 20))
+
+	// Exhaust the displayed slice: the iceberg refreshes behind sell-2
+	ob.AddOrder(makeOrder("buy-1", domain.SideBuy, 20, 10))
+
+	matches := ob.AddOrder(makeOrder("buy-2", domain.SideBuy, 20, 10))
+	if len(matches) != 1 || matches[0].SellOrder.ID != "sell-2" {
+		t.Fatalf("expected sell-2 to fill before the refreshed iceberg, got %v", matches)
+	}
+}
+
+func TestIceberg_QuoteFollowsSignedRatio(t *testing.T) {
+	ob := NewOrderBook("TKA-TKB")
+
+	ob.AddOrder(makeIceberg("ice-1", domain.SideSell, 100, 300, 30))
+	matches := ob.AddOrder(makeOrder("buy-1", domain.SideBuy, 300, 100))
+
+	base, quote := new(big.Int), new(big.Int)
+	for _, m := range matches {
+		base.Add(base, m.FillAmount)
+		quote.Add(quote, m.QuoteAmount)
+	}
+	if base.Int64() != 100 || quote.Int64() != 300 {
+		t.Fatalf("expected 100 base for 300 quote, got %s / %s", base, quote)
+	}
+}
@@ -0,0 +1,123 @@
+package orderbook
+
+import (
+	"math/big"
+	"time"
+
+	"github.com/nexus-orderbook-dex/backend/internal/domain"
+)
+
+type EventType string
+
+const (
+	EventAccepted       EventType = "accepted"        // order entered the matcher
+	EventMatched        EventType = "matched"         // one fill between two orders
+	EventCancelled      EventType = "cancelled"       // order removed by its maker, or by its group (Reason "group")
+	EventExpired        EventType = "expired"         // order removed after its signed expiry
+	EventRejected       EventType = "rejected"        // order refused by the matcher
+	EventReplaced       EventType = "replaced"        // order swapped for a replacement
+	EventGroupAdded     EventType = "group_added"     // order group registered
+	EventGroupCancelled EventType = "group_cancelled" // order group cancelled as a whole
+	EventMarketChanged  EventType = "market_changed"  // trading phase of the pair changed
+)
+
+// Cancel and reject reasons recorded on events.
+const (
+	ReasonGroup        = "group"         // cancelled as a consequence of a sibling leg
+	ReasonFinal        = "final"         // order was already filled, cancelled or expired
+	ReasonExhausted    = "exhausted"     // order had nothing left to fill
+	ReasonDuplicate    = "duplicate"     // an order with the same ID is resting
+	ReasonMarketClosed = "market_closed" // the pair is halted or cancel-only
+)
+
+// MatchEvent is the serialisable form of a MatchResult.
+type MatchEvent struct {
+	BuyOrderID    string   `json:"buyOrderId"`
+	SellOrderID   string   `json:"sellOrderId"`
+	FillAmount    *big.Int `json:"fillAmount"`
+	QuoteAmount   *big.Int `json:"quoteAmount"`
+	Price         float64  `json:"price"`
+	ClearingPrice float64  `json:"clearingPrice,omitempty"` // of the auction that made the fill, if any
+}
+
+// Event is one entry of a book's lifecycle journal. Events are numbered per
+// book without gaps. Accepted, replaced and group_added events carry a copy
+// of the orders as the matcher received them, and market_changed events the
+// new market status. Commands that match also carry the price band they
+// matched within, so that the journal alone is enough to rebuild the book
+// (see Replay).
+type Event struct {
+	Seq          uint64               `json:"seq"`
+	Type         EventType            `json:"type"`
+	Pair         string               `json:"pair"`
+	Time         time.Time            `json:"time"`
+	OrderID      string               `json:"orderId,omitempty"`
+	Order        *domain.Order        `json:"order,omitempty"`
+	Match        *MatchEvent          `json:"match,omitempty"`
+	Group        *domain.OrderGroup   `json:"group,omitempty"`
+	Legs         []*domain.Order      `json:"legs,omitempty"`
+	Reason       string               `json:"reason,omitempty"`
+	KeptPriority bool                 `json:"keptPriority,omitempty"`
+	Market       *domain.MarketStatus `json:"market,omitempty"`
+	Band         *PriceBand           `json:"band,omitempty"`
+}
+
+// TakeEvents returns and clears the journal events produced since the last call.
+func (ob *OrderBook) TakeEvents() []Event {
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+
+	events := ob.events
+	ob.events = nil
+	return events
+}
+
+// EventSeq returns the sequence number of the last emitted event.
+func (ob *OrderBook) EventSeq() uint64 {
+	ob.mu.RLock()
+	defer ob.mu.RUnlock()
+	return ob.eventSeq
+}
+
+// SetEventSeq continues event numbering after seq, e.g. after the book was
+// rebuilt on startup and the journal already holds events up to seq.
+func (ob *OrderBook) SetEventSeq(seq uint64) {
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+	ob.eventSeq = seq
+}
+
+func (ob *OrderBook) emit(ev Event) {
+	ob.eventSeq++
+	ev.Seq = ob.eventSeq
+	ev.Pair = ob.pair
+	ev.Time = time.Now().UTC()
+	ob.events = append(ob.events, ev)
+
+	ob.touch(ev.OrderID)
+	if ev.Order != nil {
+		ob.touch(ev.Order.ID)
+	}
+	if ev.Match != nil {
+		ob.touch(ev.Match.BuyOrderID)
+		ob.touch(ev.Match.SellOrderID)
+	}
+	for _, leg := range ev.Legs {
+		ob.touch(leg.ID)
+	}
+}
+
+func (ob *OrderBook) emitMatch(m MatchResult) {
+	ob.lastPrice = m.MarketPrice()
+	ob.emit(Event{
+		Type: EventMatched,
+		Match: &MatchEvent{
+			BuyOrderID:    m.BuyOrder.ID,
+			SellOrderID:   m.SellOrder.ID,
+			FillAmount:    new(big.Int).Set(m.FillAmount),
+			QuoteAmount:   new(big.Int).Set(m.QuoteAmount),
+			Price:         m.Price,
+			ClearingPrice: m.ClearingPrice,
+		},
+	})
+}
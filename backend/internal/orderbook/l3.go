@@ -0,0 +1,171 @@
+package orderbook
+
+import (
+	"math/big"
+	"sort"
+
+	"github.com/nexus-orderbook-dex/backend/internal/domain"
+)
+
+// L3Order is a resting order as shown in the order-by-order feed. Orders at
+// the same price fill in Seq order; Size is the displayed remaining base,
+// only the visible slice for icebergs.
+type L3Order struct {
+	ID       string      `json:"id"`
+	Side     domain.Side `json:"side"`
+	Price    float64     `json:"price"`
+	Size     *big.Int    `json:"size"`
+	Seq      uint64      `json:"seq"`
+	Position int         `json:"position"` // place in the queue of its price, 0 first; snapshots only
+}
+
+// L3Snapshot lists every resting order of a book as of journal event Seq.
+// Each side is ordered best price first, then by queue position.
+type L3Snapshot struct {
+	Pair string    `json:"pair"`
+	Seq  uint64    `json:"seq"`
+	Bids []L3Order `json:"bids"`
+	Asks []L3Order `json:"asks"`
+}
+
+type L3ChangeType string
+
+const (
+	L3Add    L3ChangeType = "add"    // order started resting; it joins the back of its price
+	L3Modify L3ChangeType = "modify" // displayed size changed, queue position kept
+	L3Delete L3ChangeType = "delete" // order left the book
+)
+
+type L3Change struct {
+	Type  L3ChangeType `json:"type"`
+	Order L3Order      `json:"order"`
+}
+
+// L3Snapshot returns every resting order of the book.
+func (ob *OrderBook) L3Snapshot() L3Snapshot {
+	ob.mu.RLock()
+	defer ob.mu.RUnlock()
+
+	return L3Snapshot{
+		Pair: ob.pair,
+		Seq:  ob.eventSeq,
+		Bids: l3Side(*ob.buys, func(a, b float64) bool { return a > b }),
+		Asks: l3Side(*ob.sells, func(a, b float64) bool { return a < b }),
+	}
+}
+
+func l3Side(entries []*OrderEntry, better func(a, b float64) bool) []L3Order {
+	orders := make([]L3Order, len(entries))
+	for i, entry := range entries {
+		orders[i] = l3Order(entry)
+	}
+	sort.Slice(orders, func(i, j int) bool {
+		if orders[i].Price != orders[j].Price {
+			return better(orders[i].Price, orders[j].Price)
+		}
+		return orders[i].Seq < orders[j].Seq
+	})
+	for i := range orders {
+		if i > 0 && orders[i].Price == orders[i-1].Price {
+			orders[i].Position = orders[i-1].Position + 1
+		}
+	}
+	return orders
+}
+
+func l3Order(entry *OrderEntry) L3Order {
+	return L3Order{
+		ID:    entry.Order.ID,
+		Side:  entry.Order.Side,
+		Price: entry.Order.Price(),
+		Size:  entry.Available(),
+		Seq:   entry.Seq,
+	}
+}
+
+// touch records that an order may have changed its place in the book.
+func (ob *OrderBook) touch(orderID string) {
+	if orderID == "" {
+		return
+	}
+	if ob.touchedSet == nil {
+		ob.touchedSet = make(map[string]bool)
+	}
+	if !ob.touchedSet[orderID] {
+		ob.touchedSet[orderID] = true
+		ob.touched = append(ob.touched, orderID)
+	}
+}
+
+// TakeTouchedOrders returns and clears the IDs of the orders touched since
+// the last call, in the order they were first touched. Every change to a
+// resting order touches it.
+func (ob *OrderBook) TakeTouchedOrders() []string {
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+
+	touched := ob.touched
+	ob.touched, ob.touchedSet = nil, nil
+	return touched
+}
+
+// L3Tracker turns the orders touched by commands into order-by-order
+// changes against the state it last reported.
+type L3Tracker struct {
+	last map[string]L3Order
+}
+
+// NewL3Tracker starts tracking from the current state of book, discarding
+// the orders touched while it was built.
+func NewL3Tracker(book *OrderBook) *L3Tracker {
+	book.TakeTouchedOrders()
+	snapshot := book.L3Snapshot()
+	t := &L3Tracker{last: make(map[string]L3Order, len(snapshot.Bids)+len(snapshot.Asks))}
+	for _, side := range [][]L3Order{snapshot.Bids, snapshot.Asks} {
+		for _, o := range side {
+			o.Position = 0
+			t.last[o.ID] = o
+		}
+	}
+	return t
+}
+
+// Changes returns the changes of the touched orders since the tracked
+// state, without recording them; see Apply.
+func (t *L3Tracker) Changes(book *OrderBook, touched []string) []L3Change {
+	book.mu.RLock()
+	defer book.mu.RUnlock()
+
+	var changes []L3Change
+	for _, id := range touched {
+		prev, was := t.last[id]
+		entry, is := book.orderMap[id]
+		switch {
+		case !was && !is:
+			// Filled or rejected on arrival, never visible
+		case !is:
+			changes = append(changes, L3Change{Type: L3Delete, Order: prev})
+		case !was:
+			changes = append(changes, L3Change{Type: L3Add, Order: l3Order(entry)})
+		default:
+			cur := l3Order(entry)
+			if cur.Price != prev.Price || cur.Seq != prev.Seq {
+				changes = append(changes, L3Change{Type: L3Delete, Order: prev}, L3Change{Type: L3Add, Order: cur})
+			} else if cur.Size.Cmp(prev.Size) != 0 {
+				changes = append(changes, L3Change{Type: L3Modify, Order: cur})
+			}
+		}
+	}
+	return changes
+}
+
+// Apply records changes returned by Changes once they are published.
+func (t *L3Tracker) Apply(changes []L3Change) {
+	for _, c := range changes {
+		if c.Type == L3Delete {
+			delete(t.last, c.Order.ID)
+		} else {
+			t.last[c.Order.ID] = c.Order
+		}
+	}
+}
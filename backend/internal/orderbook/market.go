@@ -0,0 +1,84 @@
+package orderbook
+
+import (
+	"fmt"
+
+	"github.com/nexus-orderbook-dex/backend/internal/domain"
+)
+
+// Market returns the trading phase of the book.
+func (ob *OrderBook) Market() domain.MarketStatus {
+	ob.mu.RLock()
+	defer ob.mu.RUnlock()
+	return cloneMarket(ob.market)
+}
+
+// SetMarket moves the book to a new market status and journals it. While
+// halted or cancel-only the book rejects orders; during an auction they
+// rest without matching. Opening the book uncrosses the orders left
+// crossed at a single price (see Indicative) and returns the fills.
+func (ob *OrderBook) SetMarket(status domain.MarketStatus) []MatchResult {
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+
+	status.Pair = ob.pair
+	ob.market = cloneMarket(status)
+	journalled := cloneMarket(status)
+	ob.emit(Event{Type: EventMarketChanged, Market: &journalled})
+
+	if status.State != domain.MarketOpen {
+		return nil
+	}
+	return ob.uncross()
+}
+
+// PriceBand bounds the prices continuous matching fills at, e.g. for a
+// circuit breaker. A fill outside [Low, High] is not made: the book moves to
+// Halt instead, and what is left of the incoming order rests.
+type PriceBand struct {
+	Low  float64             `json:"low"`
+	High float64             `json:"high"`
+	Halt domain.MarketStatus `json:"halt"`
+}
+
+func (b *PriceBand) clone() *PriceBand {
+	if b == nil {
+		return nil
+	}
+	c := *b
+	c.Halt = cloneMarket(b.Halt)
+	return &c
+}
+
+// SetPriceBand sets the band the following commands match within, nil for
+// none. Matching commands journal it. Auctions uncross without it.
+func (ob *OrderBook) SetPriceBand(band *PriceBand) {
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+	ob.band = band.clone()
+}
+
+// inBand reports whether a continuous fill may be made at price. If not it
+// halts the book with the band's halt status.
+func (ob *OrderBook) inBand(price float64) bool {
+	b := ob.band
+	if b == nil || price >= b.Low && price <= b.High {
+		return true
+	}
+	halt := cloneMarket(b.Halt)
+	halt.Pair = ob.pair
+	halt.State = domain.MarketHalted
+	halt.Reason = fmt.Sprintf("price would move to %g, outside %g to %g", price, b.Low, b.High)
+	ob.market = halt
+	journalled := cloneMarket(halt)
+	ob.emit(Event{Type: EventMarketChanged, Market: &journalled})
+	return false
+}
+
+func cloneMarket(status domain.MarketStatus) domain.MarketStatus {
+	if status.ResumeAt != nil {
+		t := *status.ResumeAt
+		status.ResumeAt = &t
+	}
+	return status
+}
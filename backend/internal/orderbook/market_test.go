@@ -0,0 +1,103 @@
+package orderbook
+
+import (
+	"testing"
+
+	"github.com/nexus-orderbook-dex/backend/internal/domain"
+)
+
+func TestMarket_HaltRejectsOrders(t *testing.T) {
+	ob := NewOrderBook("TKA-TKB")
+	ob.AddOrder(makeOrder("sell-1", domain.SideSell, 100, 100))
+	ob.SetMarket(domain.MarketStatus{State: domain.MarketHalted})
+	ob.TakeEvents()
+
+	if matches := ob.AddOrder(makeOrder("buy-1", domain.SideBuy, 100, 100)); len(matches) != 0 {
+		t.Fatalf("expected no fills while halted, got %d", len(matches))
+	}
+	events := ob.TakeEvents()
+	if len(events) != 1 || events[0].Type != EventRejected || events[0].Reason != ReasonMarketClosed {
+		t.Fatalf("expected a market_closed rejection, got %+v", events)
+	}
+	// Orders can still leave a halted book, e.g. on expiry
+	if _, ok := ob.CancelOrder("sell-1"); !ok {
+		t.Fatal("expected the resting order to be cancellable by the engine")
+	}
+}
+
+func TestMarket_AuctionRestsThenOpenUncrosses(t *testing.T) {
+	ob := NewOrderBook("TKA-TKB")
+	ob.SetMarket(domain.MarketStatus{State: domain.MarketAuction})
+
+	ob.AddOrder(makeOrder("sell-1", domain.SideSell, 100, 100)) // 1 TKB per TKA
+	ob.AddOrder(makeOrder("buy-1", domain.SideBuy, 120, 100))   // crosses at 1.2
+	ob.AddOrder(makeOrder("buy-2", domain.SideBuy, 150, 100))   // better price, later
+	bid, _ := ob.BestBid()
+	ask, _ := ob.BestAsk()
+	if bid < ask {
+		t.Fatalf("expected a crossed book during the auction, got bid %g ask %g", bid, ask)
+	}
+
+	matches := ob.SetMarket(domain.MarketStatus{State: domain.MarketOpen})
+	if len(matches) != 1 {
+		t.Fatalf("expected 1 fill on open, got %d", len(matches))
+	}
+	// Clearing at 1.5 trades as much as 1 or 1.2 and leaves nothing over;
+	// the fill executes at the seller's price
+	if m := matches[0]; m.SellOrder.ID != "sell-1" || m.BuyOrder.ID != "buy-2" || m.Price != 1 {
+		t.Fatalf("unexpected fill %s/%s at %g", m.BuyOrder.ID, m.SellOrder.ID, m.Price)
+	}
+	if ob.Market().State != domain.MarketOpen {
+		t.Fatalf("expected the book open, got %s", ob.Market().State)
+	}
+}
+
+func TestMarket_ReplayRestoresState(t *testing.T) {
+	ob := NewOrderBook("TKA-TKB")
+	ob.SetMarket(domain.MarketStatus{State: domain.MarketAuction, Source: domain.MarketSourceAdmin})
+	ob.AddOrder(makeOrder("sell-1", domain.SideSell, 100, 100))
+	ob.AddOrder(makeOrder("buy-1", domain.SideBuy, 120, 100))
+	ob.SetMarket(domain.MarketStatus{State: domain.MarketOpen})
+	ob.SetMarket(domain.MarketStatus{State: domain.MarketCancelOnly, Reason: "maintenance"})
+	ob.AddOrder(makeOrder("buy-2", domain.SideBuy, 100, 100))
+
+	replayed, err := Replay("TKA-TKB", roundTrip(t, ob.TakeEvents()))
+	if err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	if got := replayed.Market(); got.State != domain.MarketCancelOnly || got.Reason != "maintenance" {
+		t.Fatalf("expected the replayed book cancel-only, got %+v", got)
+	}
+	if snap := replayed.GetSnapshot(); len(snap.Bids) != 0 || len(snap.Asks) != 0 {
+		t.Fatalf("expected the replayed book empty, got %+v", snap)
+	}
+}
+
+func TestMarket_PriceBandHaltsASweep(t *testing.T) {
+	ob := NewOrderBook("TKA-TKB")
+	ob.AddOrder(makeOrder("sell-1", domain.SideSell, 100, 100)) // 1
+	ob.AddOrder(makeOrder("sell-2", domain.SideSell, 100, 105)) // 1.05
+	ob.AddOrder(makeOrder("sell-3", domain.SideSell, 100, 120)) // 1.2
+	ob.SetPriceBand(&PriceBand{Low: 0.9, High: 1.1, Halt: domain.MarketStatus{Source: domain.MarketSourceCircuitBreaker}})
+
+	buy := makeOrder("buy-1", domain.SideBuy, 390, 300) // up to 1.3
+	matches := ob.AddOrder(buy)
+	if len(matches) != 2 || matches[1].SellOrder.ID != "sell-2" {
+		t.Fatalf("expected the fills within the band only, got %d", len(matches))
+	}
+	if got := ob.Market(); got.State != domain.MarketHalted || got.Source != domain.MarketSourceCircuitBreaker {
+		t.Fatalf("expected the book halted by the band, got %+v", got)
+	}
+	if bid, ok := ob.BestBid(); buy.Status != domain.OrderStatusPartiallyFilled || !ok || bid != 1.3 {
+		t.Fatalf("expected the rest of the sweep to rest, got %s", buy.Status)
+	}
+
+	// The band is journalled with the order, so replay halts at the same fill
+	replayed, err := Replay("TKA-TKB", roundTrip(t, ob.TakeEvents()))
+	if err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	if got := replayed.Market(); got.State != domain.MarketHalted || got.Reason != ob.Market().Reason {
+		t.Fatalf("expected the replayed book halted, got %+v", got)
+	}
+}
@@ -0,0 +1,149 @@
+package orderbook
+
+import (
+	"fmt"
+
+	"github.com/nexus-orderbook-dex/backend/internal/domain"
+)
+
+// Replay rebuilds a book from its complete journal. See Apply.
+func Replay(pair string, events []Event) (*OrderBook, error) {
+	book := NewOrderBook(pair)
+	if len(events) > 0 {
+		book.SetEventSeq(events[0].Seq - 1)
+	}
+	if err := book.Apply(events); err != nil {
+		return nil, err
+	}
+	return book, nil
+}
+
+// Apply re-applies journalled events that follow the book's current event
+// sequence, e.g. the tail of the journal after a snapshot. Every command
+// event (accepted, rejected, an explicit cancel, expired, replaced,
+// group_added, group_cancelled and market_changed) is executed again, and
+// the events the book emits in response, within the price band journalled
+// with the command, must match the journal one for one: same sequence, type, order and reason, for fills the same orders,
+// amounts and price, and for market changes the same state. The first
+// divergence is returned as an error; the book is left in the state
+// reached up to that point. Events the book emitted before Apply and
+// nobody took are discarded.
+func (ob *OrderBook) Apply(events []Event) error {
+	ob.TakeEvents()
+
+	for i := 0; i < len(events); {
+		ev := events[i]
+		ob.SetPriceBand(ev.Band)
+		switch ev.Type {
+		case EventAccepted, EventRejected:
+			if ev.Order == nil {
+				return fmt.Errorf("event %d: %s event without an order", ev.Seq, ev.Type)
+			}
+			order := ev.Order.Clone()
+			if leg := ob.GroupLeg(order.ID); leg != nil {
+				// Group legs are shared between the group and the book, and
+				// trigger release happens outside the engine
+				leg.Status = order.Status
+				leg.TriggeredAt = order.TriggeredAt
+				order = leg
+			}
+			ob.AddOrder(order)
+
+		case EventCancelled:
+			if ev.Reason != "" {
+				return fmt.Errorf("event %d: unexpected %s cancel of order %s", ev.Seq, ev.Reason, ev.OrderID)
+			}
+			ob.CancelOrder(ev.OrderID)
+
+		case EventExpired:
+			ob.ExpireOrder(ev.OrderID)
+
+		case EventReplaced:
+			if ev.Order == nil {
+				return fmt.Errorf("event %d: replaced event without a replacement", ev.Seq)
+			}
+			ob.ReplaceOrder(ev.OrderID, ev.Order.Clone())
+
+		case EventGroupAdded:
+			if ev.Group == nil {
+				return fmt.Errorf("event %d: group_added event without a group", ev.Seq)
+			}
+			group := *ev.Group
+			legs := make([]*domain.Order, len(ev.Legs))
+			for j, leg := range ev.Legs {
+				legs[j] = leg.Clone()
+			}
+			ob.AddGroup(&group, legs)
+
+		case EventGroupCancelled:
+			if ev.Group == nil {
+				return fmt.Errorf("event %d: group_cancelled event without a group", ev.Seq)
+			}
+			ob.CancelGroup(ev.Group.ID)
+
+		case EventMarketChanged:
+			if ev.Market == nil {
+				return fmt.Errorf("event %d: market_changed event without a status", ev.Seq)
+			}
+			ob.SetMarket(*ev.Market)
+
+		default:
+			return fmt.Errorf("event %d: %s event without a preceding command", ev.Seq, ev.Type)
+		}
+
+		produced := ob.TakeEvents()
+		if len(produced) == 0 {
+			return fmt.Errorf("event %d: %s of order %s had no effect", ev.Seq, ev.Type, ev.OrderID)
+		}
+		for j, got := range produced {
+			if i+j >= len(events) {
+				return fmt.Errorf("event %d: replay produced an extra %s event", got.Seq, got.Type)
+			}
+			if err := compareEvents(events[i+j], got); err != nil {
+				return fmt.Errorf("event %d: %w", events[i+j].Seq, err)
+			}
+		}
+		i += len(produced)
+	}
+	ob.SetPriceBand(nil)
+	return nil
+}
+
+// compareEvents reports how a replayed event differs from the recorded one.
+// Timestamps are not compared.
+func compareEvents(want, got Event) error {
+	switch {
+	case want.Seq != got.Seq:
+		return fmt.Errorf("sequence %d replayed as %d", want.Seq, got.Seq)
+	case want.Type != got.Type:
+		return fmt.Errorf("recorded %s, replayed %s", want.Type, got.Type)
+	case want.OrderID != got.OrderID:
+		return fmt.Errorf("%s of order %s replayed for order %s", want.Type, want.OrderID, got.OrderID)
+	case want.Reason != got.Reason:
+		return fmt.Errorf("%s reason %q replayed as %q", want.Type, want.Reason, got.Reason)
+	case want.KeptPriority != got.KeptPriority:
+		return fmt.Errorf("replace of order %s kept priority %t, replayed %t", want.OrderID, want.KeptPriority, got.KeptPriority)
+	case (want.Market == nil) != (got.Market == nil) || want.Market != nil && want.Market.State != got.Market.State:
+		return fmt.Errorf("%s event replayed with another market state", want.Type)
+	}
+
+	if (want.Match == nil) != (got.Match == nil) {
+		return fmt.Errorf("match details differ")
+	}
+	if want.Match != nil {
+		w, g := want.Match, got.Match
+		switch {
+		case w.BuyOrderID != g.BuyOrderID || w.SellOrderID != g.SellOrderID:
+			return fmt.Errorf("match %s/%s replayed as %s/%s", w.BuyOrderID, w.SellOrderID, g.BuyOrderID, g.SellOrderID)
+		case w.FillAmount.Cmp(g.FillAmount) != 0:
+			return fmt.Errorf("fill amount %s replayed as %s", w.FillAmount, g.FillAmount)
+		case w.QuoteAmount.Cmp(g.QuoteAmount) != 0:
+			return fmt.Errorf("quote amount %s replayed as %s", w.QuoteAmount, g.QuoteAmount)
+		case w.Price != g.Price:
+			return fmt.Errorf("price %v replayed as %v", w.Price, g.Price)
+		case w.ClearingPrice != g.ClearingPrice:
+			return fmt.Errorf("clearing price %v replayed as %v", w.ClearingPrice, g.ClearingPrice)
+		}
+	}
+	return nil
+}
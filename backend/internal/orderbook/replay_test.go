@@ -0,0 +1,109 @@
+package orderbook
+
+import (
+	"encoding/json"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nexus-orderbook-dex/backend/internal/domain"
+)
+
+// roundTrip simulates persisting the journal.
+func roundTrip(t *testing.T, events []Event) []Event {
+	t.Helper()
+	data, err := json.Marshal(events)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	var out []Event
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	return out
+}
+
+func recordSession(t *testing.T) (*OrderBook, []Event) {
+	t.Helper()
+	ob := NewOrderBook("TKA-TKB")
+
+	ob.AddOrder(makeOrder("sell-1", domain.SideSell, 100, 300))
+	ob.AddOrder(makeOrder("sell-2", domain.SideSell, 50, 200))
+	iceberg := makeOrder("sell-3", domain.SideSell, 100, 300)
+	iceberg.DisplayBase = big.NewInt(20)
+	ob.AddOrder(iceberg)
+	ob.AddOrder(makeOrder("buy-1", domain.SideBuy, 450, 150))
+	ob.ReplaceOrder("sell-3", makeOrder("sell-3b", domain.SideSell, 40, 120))
+	ob.AddOrder(makeOrder("buy-2", domain.SideBuy, 100, 50))
+	ob.CancelOrder("buy-2")
+
+	legA := makeOrder("oco-a", domain.SideBuy, 200, 100)
+	legB := makeOrder("oco-b", domain.SideBuy, 150, 100)
+	ob.AddGroup(makeGroup("g-1", domain.GroupTypeOCO, legA, legB), []*domain.Order{legA, legB})
+	ob.AddOrder(legA)
+	ob.AddOrder(legB)
+	ob.AddOrder(makeOrder("sell-4", domain.SideSell, 30, 60))
+
+	expiring := makeOrder("buy-3", domain.SideBuy, 50, 50)
+	expiring.Expiry = 1000
+	ob.AddOrder(expiring)
+	ob.ExpireOrders(time.Unix(2000, 0))
+
+	ob.AddOrder(makeOrder("sell-5", domain.SideSell, 200, 300))
+
+	return ob, roundTrip(t, ob.TakeEvents())
+}
+
+func TestReplay_ReproducesBook(t *testing.T) {
+	original, events := recordSession(t)
+
+	types := make(map[EventType]int)
+	for _, ev := range events {
+		types[ev.Type]++
+	}
+	for _, want := range []EventType{EventAccepted, EventMatched, EventCancelled, EventExpired, EventReplaced, EventGroupAdded} {
+		if types[want] == 0 {
+			t.Fatalf("expected at least one %s event, got %v", want, types)
+		}
+	}
+
+	replayed, err := Replay("TKA-TKB", events)
+	if err != nil {
+		t.Fatalf("replay failed: %v", err)
+	}
+	if !reflect.DeepEqual(replayed.GetSnapshot(), original.GetSnapshot()) {
+		t.Fatalf("replayed book differs:\n got %+v\nwant %+v", replayed.GetSnapshot(), original.GetSnapshot())
+	}
+	if replayed.EventSeq() != original.EventSeq() {
+		t.Fatalf("expected event seq %d, got %d", original.EventSeq(), replayed.EventSeq())
+	}
+}
+
+func TestReplay_DetectsAlteredMatch(t *testing.T) {
+	_, events := recordSession(t)
+
+	for i := range events {
+		if events[i].Type == EventMatched {
+			events[i].Match.FillAmount = new(big.Int).Add(events[i].Match.FillAmount, big.NewInt(1))
+			break
+		}
+	}
+
+	if _, err := Replay("TKA-TKB", events); err == nil || !strings.Contains(err.Error(), "fill amount") {
+		t.Fatalf("expected fill amount mismatch, got %v", err)
+	}
+}
+
+func TestRejectedOrderIsJournalled(t *testing.T) {
+	ob := NewOrderBook("TKA-TKB")
+	ob.AddOrder(makeOrder("sell-1", domain.SideSell, 100, 300))
+	ob.AddOrder(makeOrder("sell-1", domain.SideSell, 100, 300))
+
+	events := ob.TakeEvents()
+	last := events[len(events)-1]
+	if last.Type != EventRejected || last.Reason != ReasonDuplicate {
+		t.Fatalf("expected duplicate rejection, got %s %q", last.Type, last.Reason)
+	}
+}
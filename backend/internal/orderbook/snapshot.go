@@ -0,0 +1,241 @@
+package orderbook
+
+import (
+	"container/heap"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"math/big"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/nexus-orderbook-dex/backend/internal/domain"
+)
+
+const (
+	snapshotVersion = 1
+	snapshotExt     = ".snap"
+)
+
+// bookSnapshot is the on-disk form of an OrderBook. Orders are stored once
+// and referenced by ID so that group legs resting in the book are restored
+// as the same object in both places.
+type bookSnapshot struct {
+	Version   int
+	Pair      string
+	EventSeq  uint64 // last journal event reflected in the snapshot
+	EntrySeq  uint64
+	Orders    []*domain.Order
+	Entries   []snapshotEntry
+	Groups    []snapshotGroup
+	Market    domain.MarketStatus // zero in snapshots taken before market states
+	LastPrice float64
+}
+
+type snapshotEntry struct {
+	OrderID string
+	Seq     uint64
+	Visible *big.Int
+}
+
+type snapshotGroup struct {
+	Group  domain.OrderGroup
+	LegIDs []string
+	Placed []string
+}
+
+// WriteSnapshot encodes the book, including time priority, iceberg slices,
+// active groups and the journal sequence it reflects. Events the book has
+// emitted but nobody has taken yet are not part of the snapshot.
+func (ob *OrderBook) WriteSnapshot(w io.Writer) error {
+	ob.mu.RLock()
+	defer ob.mu.RUnlock()
+
+	snap := bookSnapshot{
+		Version:   snapshotVersion,
+		Pair:      ob.pair,
+		EventSeq:  ob.eventSeq,
+		EntrySeq:  ob.seq,
+		Market:    cloneMarket(ob.market),
+		LastPrice: ob.lastPrice,
+	}
+	saved := make(map[string]bool)
+	save := func(o *domain.Order) {
+		if !saved[o.ID] {
+			saved[o.ID] = true
+			snap.Orders = append(snap.Orders, o)
+		}
+	}
+
+	entries := make([]*OrderEntry, 0, len(ob.orderMap))
+	for _, entry := range ob.orderMap {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Seq < entries[j].Seq })
+	for _, entry := range entries {
+		save(entry.Order)
+		snap.Entries = append(snap.Entries, snapshotEntry{OrderID: entry.Order.ID, Seq: entry.Seq, Visible: entry.Visible})
+	}
+
+	groupIDs := make([]string, 0, len(ob.groups))
+	for id := range ob.groups {
+		groupIDs = append(groupIDs, id)
+	}
+	sort.Strings(groupIDs)
+	for _, id := range groupIDs {
+		g := ob.groups[id]
+		sg := snapshotGroup{Group: *g.group}
+		for _, leg := range g.legs {
+			save(leg)
+			sg.LegIDs = append(sg.LegIDs, leg.ID)
+			if g.placed[leg.ID] {
+				sg.Placed = append(sg.Placed, leg.ID)
+			}
+		}
+		snap.Groups = append(snap.Groups, sg)
+	}
+
+	return gob.NewEncoder(w).Encode(&snap)
+}
+
+// ReadSnapshot restores a book written by WriteSnapshot. No matching takes
+// place: entries are put back with their original sequence numbers.
+func ReadSnapshot(r io.Reader) (*OrderBook, error) {
+	var snap bookSnapshot
+	if err := gob.NewDecoder(r).Decode(&snap); err != nil {
+		return nil, fmt.Errorf("decode snapshot: %w", err)
+	}
+	if snap.Version != snapshotVersion {
+		return nil, fmt.Errorf("unsupported snapshot version %d", snap.Version)
+	}
+
+	ob := NewOrderBook(snap.Pair)
+	ob.eventSeq = snap.EventSeq
+	ob.seq = snap.EntrySeq
+	ob.lastPrice = snap.LastPrice
+	if snap.Market.State != "" {
+		ob.market = snap.Market
+	}
+
+	orders := make(map[string]*domain.Order, len(snap.Orders))
+	for _, o := range snap.Orders {
+		if o.FilledBase == nil {
+			o.FilledBase = new(big.Int)
+		}
+		orders[o.ID] = o
+	}
+
+	for _, se := range snap.Entries {
+		order, ok := orders[se.OrderID]
+		if !ok {
+			return nil, fmt.Errorf("snapshot entry for unknown order %s", se.OrderID)
+		}
+		entry := &OrderEntry{Order: order, Seq: se.Seq, Visible: se.Visible}
+		ob.orderMap[order.ID] = entry
+		if order.Side == domain.SideBuy {
+			heap.Push(ob.buys, entry)
+		} else {
+			heap.Push(ob.sells, entry)
+		}
+	}
+
+	for _, sg := range snap.Groups {
+		group := sg.Group
+		g := &orderGroup{group: &group, placed: make(map[string]bool)}
+		for _, id := range sg.LegIDs {
+			leg, ok := orders[id]
+			if !ok {
+				return nil, fmt.Errorf("snapshot group %s has unknown leg %s", group.ID, id)
+			}
+			g.legs = append(g.legs, leg)
+			ob.groupOf[id] = g
+		}
+		for _, id := range sg.Placed {
+			g.placed[id] = true
+		}
+		ob.groups[group.ID] = g
+	}
+
+	return ob, nil
+}
+
+// SaveSnapshotFile atomically writes the book's snapshot to dir.
+func SaveSnapshotFile(dir string, ob *OrderBook) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, ".snapshot-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := ob.WriteSnapshot(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), snapshotPath(dir, ob.pair))
+}
+
+// LoadSnapshotFile reads the snapshot of pair from dir. It returns nil and no
+// error if the pair has no snapshot.
+func LoadSnapshotFile(dir, pair string) (*OrderBook, error) {
+	f, err := os.Open(snapshotPath(dir, pair))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	ob, err := ReadSnapshot(f)
+	if err != nil {
+		return nil, err
+	}
+	if ob.pair != pair {
+		return nil, fmt.Errorf("snapshot %s holds pair %s", f.Name(), ob.pair)
+	}
+	return ob, nil
+}
+
+// SnapshotPairs lists the pairs that have a snapshot in dir.
+func SnapshotPairs(dir string) ([]string, error) {
+	files, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var pairs []string
+	for _, f := range files {
+		name := f.Name()
+		if f.IsDir() || !strings.HasSuffix(name, snapshotExt) {
+			continue
+		}
+		pair, err := url.PathUnescape(strings.TrimSuffix(name, snapshotExt))
+		if err != nil {
+			continue
+		}
+		pairs = append(pairs, pair)
+	}
+	return pairs, nil
+}
+
+// snapshotPath escapes the pair so that it is always a single file name.
+func snapshotPath(dir, pair string) string {
+	return filepath.Join(dir, url.PathEscape(pair)+snapshotExt)
+}
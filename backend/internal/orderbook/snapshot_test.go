@@ -0,0 +1,97 @@
+package orderbook
+
+import (
+	"bytes"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/nexus-orderbook-dex/backend/internal/domain"
+)
+
+func TestSnapshot_RestoresPriorityAndGroups(t *testing.T) {
+	ob := NewOrderBook("TKA-TKB")
+
+	// Two asks at the same price: sell-1 has time priority
+	ob.AddOrder(makeOrder("sell-1", domain.SideSell, 100, 300))
+	ob.AddOrder(makeOrder("sell-2", domain.SideSell, 100, 300))
+	iceberg := makeOrder("sell-3", domain.SideSell, 100, 400)
+	iceberg.DisplayBase = big.NewInt(10)
+	ob.AddOrder(iceberg)
+
+	// A bracket whose exit is still dormant (BaseCap 0)
+	entry := makeOrder("entry", domain.SideBuy, 200, 100)
+	exit := makeOrder("tp", domain.SideSell, 100, 500)
+	ob.AddGroup(makeGroup("g-1", domain.GroupTypeBracket, entry, exit), []*domain.Order{entry, exit})
+	ob.AddOrder(entry)
+	ob.TakeEvents()
+
+	var buf bytes.Buffer
+	if err := ob.WriteSnapshot(&buf); err != nil {
+		t.Fatalf("write snapshot: %v", err)
+	}
+
+	restored, err := ReadSnapshot(&buf)
+	if err != nil {
+		t.Fatalf("read snapshot: %v", err)
+	}
+	if restored.EventSeq() != ob.EventSeq() {
+		t.Fatalf("expected event seq %d, got %d", ob.EventSeq(), restored.EventSeq())
+	}
+	if len(restored.TakeEvents()) != 0 {
+		t.Fatal("restoring a snapshot must not emit events")
+	}
+	if !reflect.DeepEqual(restored.GetSnapshot(), ob.GetSnapshot()) {
+		t.Fatalf("restored book differs:\n got %+v\nwant %+v", restored.GetSnapshot(), ob.GetSnapshot())
+	}
+
+	// Time priority survives: the first fill at 3 goes to sell-1
+	matches := restored.AddOrder(makeOrder("buy-1", domain.SideBuy, 150, 50))
+	if len(matches) != 1 || matches[0].SellOrder.ID != "sell-1" {
+		t.Fatalf("expected sell-1 to fill first, got %+v", matches)
+	}
+
+	// The dormant exit is still capped and activates with the entry
+	restored.TakeGroupEvents()
+	restored.AddOrder(makeOrder("sell-4", domain.SideSell, 30, 60))
+	activated := eventsOf(restored.TakeGroupEvents(), GroupEventActivated)
+	if len(activated) != 1 || activated[0].Order.RemainingBase().Int64() != 30 {
+		t.Fatalf("expected exit activated for 30, got %+v", activated)
+	}
+}
+
+func TestSnapshot_ApplyJournalTail(t *testing.T) {
+	ob := NewOrderBook("TKA-TKB")
+	ob.AddOrder(makeOrder("sell-1", domain.SideSell, 100, 300))
+	ob.AddOrder(makeOrder("buy-1", domain.SideBuy, 100, 50))
+	ob.TakeEvents()
+
+	dir := t.TempDir()
+	if err := SaveSnapshotFile(dir, ob); err != nil {
+		t.Fatalf("save snapshot: %v", err)
+	}
+
+	ob.AddOrder(makeOrder("buy-2", domain.SideBuy, 120, 40))
+	ob.CancelOrder("buy-1")
+	ob.AddOrder(makeOrder("sell-2", domain.SideSell, 50, 100))
+	tail := roundTrip(t, ob.TakeEvents())
+
+	restored, err := LoadSnapshotFile(dir, "TKA-TKB")
+	if err != nil || restored == nil {
+		t.Fatalf("load snapshot: %v", err)
+	}
+	if err := restored.Apply(tail); err != nil {
+		t.Fatalf("apply tail: %v", err)
+	}
+	if !reflect.DeepEqual(restored.GetSnapshot(), ob.GetSnapshot()) {
+		t.Fatalf("restored book differs:\n got %+v\nwant %+v", restored.GetSnapshot(), ob.GetSnapshot())
+	}
+
+	pairs, err := SnapshotPairs(dir)
+	if err != nil || len(pairs) != 1 || pairs[0] != "TKA-TKB" {
+		t.Fatalf("expected one snapshot for TKA-TKB, got %v (%v)", pairs, err)
+	}
+	if missing, err := LoadSnapshotFile(dir, "TKC-TKD"); missing != nil || err != nil {
+		t.Fatalf("expected no snapshot for TKC-TKD, got %v (%v)", missing, err)
+	}
+}
@@ -0,0 +1,166 @@
+package orderbook
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/nexus-orderbook-dex/backend/internal/domain"
+)
+
+// TriggerStore holds conditional (stop-loss / take-profit) orders for a single
+// trading pair until the last trade price crosses their trigger. Released
+// orders are returned to the caller, which injects them into OrderBook.AddOrder.
+type TriggerStore struct {
+	mu        sync.Mutex
+	pair      string
+	lastPrice float64
+	orders    map[string]*domain.Order // orderID -> pending order
+}
+
+// NewTriggerStore creates an empty trigger store for the given pair.
+func NewTriggerStore(pair string) *TriggerStore {
+	return &TriggerStore{
+		pair:   pair,
+		orders: make(map[string]*domain.Order),
+	}
+}
+
+// Add registers a pending conditional order. If a trade has already crossed
+// the trigger, the order is not stored and Add returns true so the caller can
+// release it immediately.
+func (ts *TriggerStore) Add(order *domain.Order) bool {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if ts.lastPrice > 0 && order.Triggered(ts.lastPrice) {
+		return true
+	}
+	ts.orders[order.ID] = order
+	return false
+}
+
+// Cancel removes a pending order from the store.
+func (ts *TriggerStore) Cancel(orderID string) (*domain.Order, bool) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	order, ok := ts.orders[orderID]
+	if !ok {
+		return nil, false
+	}
+	order.Status = domain.OrderStatusCancelled
+	delete(ts.orders, orderID)
+	return order, true
+}
+
+// Remove drops an order from the store without changing its status, e.g.
+// when its group suspended or cancelled it.
+func (ts *TriggerStore) Remove(orderID string) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	delete(ts.orders, orderID)
+}
+
+// Expire removes and returns every order whose signed expiry is at or before
+// now, with status expired. Group legs are left to the book, which expires
+// them together with their siblings.
+func (ts *TriggerStore) Expire(now time.Time) []*domain.Order {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	cutoff := uint64(now.Unix())
+	var expired []*domain.Order
+	for id, order := range ts.orders {
+		if order.GroupID != "" || order.Expiry == 0 || order.Expiry > cutoff {
+			continue
+		}
+		order.Status = domain.OrderStatusExpired
+		delete(ts.orders, id)
+		expired = append(expired, order)
+	}
+	sort.Slice(expired, func(i, j int) bool { return expired[i].ID < expired[j].ID })
+	return expired
+}
+
+// SetLastPrice seeds the last trade price, e.g. from the trades table on startup.
+func (ts *TriggerStore) SetLastPrice(price float64) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	ts.lastPrice = price
+}
+
+// LastPrice returns the last observed trade price (0 if none yet).
+func (ts *TriggerStore) LastPrice() float64 {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	return ts.lastPrice
+}
+
+// Pending returns the number of orders waiting for a trigger.
+func (ts *TriggerStore) Pending() int {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	return len(ts.orders)
+}
+
+// OnMatches advances the last trade price through the given matches and
+// returns every pending order whose trigger was crossed, removed from the
+// store. Released orders are ordered by submission time so that their
+// priority in the book is deterministic.
+func (ts *TriggerStore) OnMatches(matches []MatchResult) []*domain.Order {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	var released []*domain.Order
+	for _, m := range matches {
+		ts.lastPrice = m.MarketPrice()
+		for id, order := range ts.orders {
+			if order.Triggered(ts.lastPrice) {
+				released = append(released, order)
+				delete(ts.orders, id)
+			}
+		}
+	}
+
+	sortReleased(released)
+	return released
+}
+
+// Hold puts back a released order that cannot enter its book yet, e.g.
+// because a fill halted the pair. Unlike Add it does not release the order
+// again; Crossed does once the book takes orders.
+func (ts *TriggerStore) Hold(order *domain.Order) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	ts.orders[order.ID] = order
+}
+
+// Crossed removes and returns every pending order whose trigger the last
+// trade price has crossed, in the order OnMatches releases them.
+func (ts *TriggerStore) Crossed() []*domain.Order {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	var released []*domain.Order
+	if ts.lastPrice <= 0 {
+		return nil
+	}
+	for id, order := range ts.orders {
+		if order.Triggered(ts.lastPrice) {
+			released = append(released, order)
+			delete(ts.orders, id)
+		}
+	}
+	sortReleased(released)
+	return released
+}
+
+func sortReleased(released []*domain.Order) {
+	sort.SliceStable(released, func(i, j int) bool {
+		if !released[i].CreatedAt.Equal(released[j].CreatedAt) {
+			return released[i].CreatedAt.Before(released[j].CreatedAt)
+		}
+		return released[i].ID < released[j].ID
+	})
+}
@@ -0,0 +1,149 @@
+package orderbook
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/nexus-orderbook-dex/backend/internal/domain"
+)
+
+func makeTriggerOrder(id string, side domain.Side, orderType domain.OrderType, trigger float64) *domain.Order {
+	o := makeOrder(id, side, 100, 100)
+	o.Type = orderType
+	o.TriggerPrice = trigger
+	o.Status = domain.OrderStatusPending
+	return o
+}
+
+func matchAt(price float64) []MatchResult {
+	return []MatchResult{{FillAmount: big.NewInt(1), QuoteAmount: big.NewInt(1), Price: price}}
+}
+
+func TestTriggerStore_StopLossSell(t *testing.T) {
+	ts := NewTriggerStore("TKA-TKB")
+	ts.SetLastPrice(2.0)
+
+	// Sell stop at 1.5: fires when price falls to or below 1.5
+	if ts.Add(makeTriggerOrder("stop-1", domain.SideSell, domain.OrderTypeStopLoss, 1.5)) {
+		t.Fatal("stop should not fire at 2.0")
+	}
+
+	if released := ts.OnMatches(matchAt(1.8)); len(released) != 0 {
+		t.Fatalf("expected no release at 1.8, got %d", len(released))
+	}
+	released := ts.OnMatches(matchAt(1.5))
+	if len(released) != 1 || released[0].ID != "stop-1" {
+		t.Fatalf("expected stop-1 released at 1.5, got %v", released)
+	}
+	if ts.Pending() != 0 {
+		t.Fatalf("expected empty store, got %d pending", ts.Pending())
+	}
+}
+
+func TestTriggerStore_TakeProfitSell(t *testing.T) {
+	ts := NewTriggerStore("TKA-TKB")
+	ts.SetLastPrice(2.0)
+
+	ts.Add(makeTriggerOrder("tp-1", domain.SideSell, domain.OrderTypeTakeProfit, 3.0))
+	ts.Add(makeTriggerOrder("sl-1", domain.SideSell, domain.OrderTypeStopLoss, 1.0))
+
+	released := ts.OnMatches(matchAt(3.2))
+	if len(released) != 1 || released[0].ID != "tp-1" {
+		t.Fatalf("expected only tp-1 released, got %v", released)
+	}
+	if ts.Pending() != 1 {
+		t.Fatalf("expected sl-1 still pending, got %d", ts.Pending())
+	}
+}
+
+func TestTriggerStore_AlreadyCrossed(t *testing.T) {
+	ts := NewTriggerStore("TKA-TKB")
+	ts.SetLastPrice(2.0)
+
+	// Buy stop at 1.9 with last price already above it fires immediately
+	if !ts.Add(makeTriggerOrder("stop-1", domain.SideBuy, domain.OrderTypeStopLoss, 1.9)) {
+		t.Fatal("expected immediate trigger")
+	}
+	if ts.Pending() != 0 {
+		t.Fatalf("immediately triggered order should not be stored, got %d", ts.Pending())
+	}
+}
+
+func TestTriggerStore_Cancel(t *testing.T) {
+	ts := NewTriggerStore("TKA-TKB")
+	ts.Add(makeTriggerOrder("stop-1", domain.SideSell, domain.OrderTypeStopLoss, 1.5))
+
+	cancelled, ok := ts.Cancel("stop-1")
+	if !ok {
+		t.Fatal("cancel should succeed")
+	}
+	if cancelled.Status != domain.OrderStatusCancelled {
+		t.Fatalf("expected cancelled, got %s", cancelled.Status)
+	}
+	if released := ts.OnMatches(matchAt(1.0)); len(released) != 0 {
+		t.Fatalf("cancelled order must not be released, got %d", len(released))
+	}
+}
+
+func TestTriggeredOrderQueuesBehindRestingOrders(t *testing.T) {
+	ob := NewOrderBook("TKA-TKB")
+
+	// A triggered order created earlier than a resting order at the same
+	// price still enters the book behind it.
+	triggered := makeOrder("stop-1", domain.SideSell, 100, 200)
+	resting := makeOrder("sell-1", domain.SideSell, 100, 200)
+	triggered.CreatedAt = resting.CreatedAt.Add(-1)
+
+	ob.AddOrder(resting)
+	ob.AddOrder(triggered)
+
+	buy := makeOrder("buy-1", domain.SideBuy, 200, 100)
+	matches := ob.AddOrder(buy)
+	if len(matches) != 1 {
+		t.Fatalf("expected 1 match, got %d", len(matches))
+	}
+	if matches[0].SellOrder.ID != "sell-1" {
+		t.Fatalf("expected resting order to fill first, got %s", matches[0].SellOrder.ID)
+	}
+}
+
+func TestTriggerStore_Expire(t *testing.T) {
+	ts := NewTriggerStore("TKA-TKB")
+	stale := makeTriggerOrder("sl-1", domain.SideSell, domain.OrderTypeStopLoss, 2.5)
+	stale.Expiry = 1000
+	fresh := makeTriggerOrder("sl-2", domain.SideSell, domain.OrderTypeStopLoss, 2.5)
+	fresh.Expiry = 3000
+	ts.Add(stale)
+	ts.Add(fresh)
+
+	expired := ts.Expire(time.Unix(2000, 0))
+	if len(expired) != 1 || expired[0].ID != "sl-1" || expired[0].Status != domain.OrderStatusExpired {
+		t.Fatalf("expected sl-1 expired, got %v", expired)
+	}
+	if ts.Pending() != 1 {
+		t.Fatalf("expected 1 pending order, got %d", ts.Pending())
+	}
+}
+
+func TestTriggerStore_HoldUntilCrossed(t *testing.T) {
+	ts := NewTriggerStore("TKA-TKB")
+	ts.SetLastPrice(2.0)
+	ts.Add(makeTriggerOrder("stop-1", domain.SideSell, domain.OrderTypeStopLoss, 1.5))
+
+	// Released into a halted book, the order waits for it to reopen
+	released := ts.OnMatches(matchAt(1.4))
+	if len(released) != 1 {
+		t.Fatalf("expected stop-1 released, got %d", len(released))
+	}
+	ts.Hold(released[0])
+	if ts.Pending() != 1 {
+		t.Fatalf("expected the held order pending, got %d", ts.Pending())
+	}
+	if crossed := ts.Crossed(); len(crossed) != 1 || crossed[0].ID != "stop-1" {
+		t.Fatalf("expected stop-1 released on reopening, got %v", crossed)
+	}
+	if ts.Pending() != 0 {
+		t.Fatalf("expected empty store, got %d pending", ts.Pending())
+	}
+}
@@ -0,0 +1,99 @@
+package orderbook
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+)
+
+// BookView selects part of an aggregated book: levels merged into price
+// buckets of Group (0 keeps every price) and the best Depth levels of each
+// side (0 keeps all).
+type BookView struct {
+	Depth int
+	Group float64
+}
+
+func (v BookView) Validate() error {
+	if v.Depth < 0 {
+		return fmt.Errorf("invalid depth %d", v.Depth)
+	}
+	if v.Group < 0 || math.IsNaN(v.Group) || math.IsInf(v.Group, 0) {
+		return fmt.Errorf("invalid group %v", v.Group)
+	}
+	return nil
+}
+
+// View returns the part of s selected by v. Bids are grouped down and asks
+// up, so a grouped book never crosses. The levels of s are not shared.
+func (s Snapshot) View(v BookView) Snapshot {
+	return Snapshot{
+		Bids: viewLevels(s.Bids, v, math.Floor),
+		Asks: viewLevels(s.Asks, v, math.Ceil),
+	}
+}
+
+// viewLevels groups and truncates levels that are ordered best first. Any
+// rounding keeps that order, so levels of the same bucket are adjacent and
+// the walk stops once Depth buckets are complete.
+func viewLevels(levels []PriceLevel, v BookView, round func(float64) float64) []PriceLevel {
+	size := len(levels)
+	if v.Depth > 0 && v.Depth < size {
+		size = v.Depth
+	}
+	out := make([]PriceLevel, 0, size)
+	for _, lvl := range levels {
+		price := lvl.Price
+		if v.Group > 0 {
+			price = groupPrice(price, v.Group, round)
+		}
+		if n := len(out); n > 0 && out[n-1].Price == price {
+			out[n-1].Amount.Add(out[n-1].Amount, lvl.Amount)
+			out[n-1].Count += lvl.Count
+			continue
+		}
+		if v.Depth > 0 && len(out) == v.Depth {
+			break
+		}
+		out = append(out, PriceLevel{Price: price, Amount: new(big.Int).Set(lvl.Amount), Count: lvl.Count})
+	}
+	return out
+}
+
+// priceEpsilon absorbs float error in price / group, so that 0.3 in buckets
+// of 0.1 lands in bucket 3 and not 2.
+const priceEpsilon = 1e-9
+
+func groupPrice(price, group float64, round func(float64) float64) float64 {
+	q := price / group
+	if r := math.Round(q); math.Abs(q-r) < priceEpsilon {
+		q = r
+	}
+	bucket := round(q) * group
+	// Drop the float noise of the multiplication: 3 * 0.1 is 0.30000000000000004
+	return math.Round(bucket/priceEpsilon) * priceEpsilon
+}
+
+// DiffLevels returns the levels of next that differ from prev, followed by
+// the prices of prev missing from next with a zero amount and count. Applying
+// the result to prev by price yields next.
+func DiffLevels(prev, next []PriceLevel) []PriceLevel {
+	before := make(map[float64]PriceLevel, len(prev))
+	for _, lvl := range prev {
+		before[lvl.Price] = lvl
+	}
+	var diff []PriceLevel
+	for _, lvl := range next {
+		old, ok := before[lvl.Price]
+		if !ok || old.Count != lvl.Count || old.Amount.Cmp(lvl.Amount) != 0 {
+			diff = append(diff, lvl)
+		}
+		delete(before, lvl.Price)
+	}
+	for _, lvl := range prev {
+		if _, gone := before[lvl.Price]; gone {
+			diff = append(diff, PriceLevel{Price: lvl.Price, Amount: new(big.Int)})
+		}
+	}
+	return diff
+}
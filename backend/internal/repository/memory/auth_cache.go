@@ -0,0 +1,87 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/nexus-orderbook-dex/backend/internal/domain"
+)
+
+// AuthCache implements repository.AuthCache. Expired entries are dropped
+// when they are read.
+type AuthCache struct {
+	mu       sync.Mutex
+	nonces   map[string]time.Time // nonce -> expiry
+	sessions map[string]authSession
+	requests map[string]time.Time // key:nonce -> expiry
+}
+
+type authSession struct {
+	session domain.Session
+	expiry  time.Time
+}
+
+func NewAuthCache() *AuthCache {
+	return &AuthCache{
+		nonces:   make(map[string]time.Time),
+		sessions: make(map[string]authSession),
+		requests: make(map[string]time.Time),
+	}
+}
+
+func (c *AuthCache) AddNonce(ctx context.Context, nonce string, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.nonces[nonce] = time.Now().Add(ttl)
+	return nil
+}
+
+func (c *AuthCache) TakeNonce(ctx context.Context, nonce string) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	expiry, ok := c.nonces[nonce]
+	delete(c.nonces, nonce)
+	return ok && time.Now().Before(expiry), nil
+}
+
+func (c *AuthCache) SetSession(ctx context.Context, id string, session domain.Session, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sessions[id] = authSession{session: session, expiry: time.Now().Add(ttl)}
+	return nil
+}
+
+func (c *AuthCache) GetSession(ctx context.Context, id string) (*domain.Session, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s, ok := c.sessions[id]
+	if !ok {
+		return nil, nil
+	}
+	if !time.Now().Before(s.expiry) {
+		delete(c.sessions, id)
+		return nil, nil
+	}
+	session := s.session
+	return &session, nil
+}
+
+func (c *AuthCache) DeleteSession(ctx context.Context, id string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.sessions, id)
+	return nil
+}
+
+func (c *AuthCache) ClaimRequestNonce(ctx context.Context, keyID, nonce string, ttl time.Duration) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	k := keyID + ":" + nonce
+	if expiry, ok := c.requests[k]; ok && now.Before(expiry) {
+		return false, nil
+	}
+	c.requests[k] = now.Add(ttl)
+	return true, nil
+}
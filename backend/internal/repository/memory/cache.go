@@ -0,0 +1,60 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/nexus-orderbook-dex/backend/internal/repository"
+)
+
+// Cache implements repository.SnapshotCache and repository.Publisher. It
+// keeps every published update so that tests can inspect them.
+type Cache struct {
+	mu        sync.Mutex
+	snapshots map[string]repository.BookSnapshot
+	published map[string][][]byte // channel:pair -> updates
+}
+
+func NewCache() *Cache {
+	return &Cache{
+		snapshots: make(map[string]repository.BookSnapshot),
+		published: make(map[string][][]byte),
+	}
+}
+
+func (c *Cache) SetSnapshot(ctx context.Context, pair string, snapshot repository.BookSnapshot) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	snapshot.Bids = append([]repository.PriceLevelData(nil), snapshot.Bids...)
+	snapshot.Asks = append([]repository.PriceLevelData(nil), snapshot.Asks...)
+	c.snapshots[pair] = snapshot
+	return nil
+}
+
+func (c *Cache) GetSnapshot(ctx context.Context, pair string) (repository.BookSnapshot, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.snapshots[pair], nil
+}
+
+// PublishUpdate records data JSON-encoded, as it would be sent to subscribers.
+func (c *Cache) PublishUpdate(ctx context.Context, channel, pair string, data interface{}) error {
+	msg, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key := channel + ":" + pair
+	c.published[key] = append(c.published[key], msg)
+	return nil
+}
+
+// Published returns the updates published on a pair's channel so far,
+// oldest first.
+func (c *Cache) Published(channel, pair string) [][]byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([][]byte(nil), c.published[channel+":"+pair]...)
+}
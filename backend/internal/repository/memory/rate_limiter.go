@@ -0,0 +1,75 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/nexus-orderbook-dex/backend/internal/repository"
+)
+
+// RateLimiter implements repository.RateLimiter in process.
+type RateLimiter struct {
+	mu      sync.Mutex
+	now     func() time.Time
+	buckets map[string]bucket
+}
+
+type bucket struct {
+	tokens float64
+	at     time.Time
+}
+
+func NewRateLimiter() *RateLimiter {
+	return &RateLimiter{now: time.Now, buckets: make(map[string]bucket)}
+}
+
+// SetClock replaces the limiter's clock, so that tests can refill buckets
+// without sleeping.
+func (l *RateLimiter) SetClock(now func() time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.now = now
+}
+
+func (l *RateLimiter) Take(ctx context.Context, buckets []repository.RateBucket, cost float64) (repository.RateDecision, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+
+	// Refill every bucket, then take only if none of them is short
+	d := repository.RateDecision{Allowed: true, Remaining: math.Inf(1)}
+	refilled := make([]bucket, len(buckets))
+	for i, rb := range buckets {
+		limit := rb.Limit
+		if !(limit.Rate > 0 && limit.Burst > 0) {
+			return repository.RateDecision{}, fmt.Errorf("rate limit %s: rate and burst must be positive", rb.Key)
+		}
+		b, ok := l.buckets[rb.Key]
+		if !ok {
+			b = bucket{tokens: limit.Burst, at: now}
+		}
+		b.tokens = math.Min(limit.Burst, b.tokens+now.Sub(b.at).Seconds()*limit.Rate)
+		b.at = now
+		refilled[i] = b
+		if b.tokens < cost && d.Allowed {
+			d = repository.RateDecision{
+				Bucket:     i,
+				Remaining:  b.tokens,
+				RetryAfter: time.Duration(math.Ceil((cost - b.tokens) / limit.Rate * float64(time.Second))),
+			}
+		}
+	}
+	for i, b := range refilled {
+		if d.Allowed {
+			b.tokens -= cost
+			if b.tokens < d.Remaining {
+				d.Bucket, d.Remaining = i, b.tokens
+			}
+		}
+		l.buckets[buckets[i].Key] = b
+	}
+	return d, nil
+}
@@ -0,0 +1,703 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nexus-orderbook-dex/backend/internal/domain"
+	ob "github.com/nexus-orderbook-dex/backend/internal/orderbook"
+	"github.com/nexus-orderbook-dex/backend/internal/repository"
+)
+
+// The repositories mirror the queries of their Postgres counterparts,
+// including their ordering and sql.ErrNoRows for a missing row.
+
+type orderRepo struct{ with access }
+
+func (r *orderRepo) Create(ctx context.Context, order *domain.Order) error {
+	if order.ID == "" {
+		order.ID = uuid.New().String()
+	}
+	now := time.Now()
+	order.CreatedAt = now
+	order.UpdatedAt = now
+	if order.Type == "" {
+		order.Type = domain.OrderTypeLimit
+	}
+
+	return r.with(func(d *data) error {
+		if _, ok := d.orders[order.ID]; ok {
+			return fmt.Errorf("duplicate order id %s", order.ID)
+		}
+		d.orders[order.ID] = order.Clone()
+		d.orderIDs = append(d.orderIDs, order.ID)
+		return nil
+	})
+}
+
+func (r *orderRepo) GetByID(ctx context.Context, id string) (*domain.Order, error) {
+	var order *domain.Order
+	err := r.with(func(d *data) error {
+		o, ok := d.orders[id]
+		if !ok {
+			return sql.ErrNoRows
+		}
+		order = o.Clone()
+		return nil
+	})
+	return order, err
+}
+
+// find returns copies of the orders matching keep in insertion order.
+func (r *orderRepo) find(keep func(o *domain.Order) bool) ([]*domain.Order, error) {
+	var orders []*domain.Order
+	err := r.with(func(d *data) error {
+		for _, id := range d.orderIDs {
+			if o := d.orders[id]; keep(o) {
+				orders = append(orders, o.Clone())
+			}
+		}
+		return nil
+	})
+	return orders, err
+}
+
+func (r *orderRepo) GetByMaker(ctx context.Context, maker string) ([]*domain.Order, error) {
+	orders, err := r.find(func(o *domain.Order) bool { return strings.EqualFold(o.Maker, maker) })
+	// Newest first
+	for i, j := 0, len(orders)-1; i < j; i, j = i+1, j-1 {
+		orders[i], orders[j] = orders[j], orders[i]
+	}
+	return orders, err
+}
+
+func (r *orderRepo) List(ctx context.Context, q domain.OrderQuery) ([]*domain.Order, error) {
+	orders, err := r.find(func(o *domain.Order) bool {
+		return strings.EqualFold(o.Maker, q.Maker) &&
+			(q.Pair == "" || o.Pair == q.Pair) &&
+			(q.Side == "" || o.Side == q.Side) &&
+			(q.Status == "" || o.Status == q.Status) &&
+			inPage(o.CreatedAt, o.ID, q.From, q.To, q.After)
+	})
+	sort.Slice(orders, func(i, j int) bool {
+		return newerThan(orders[i].CreatedAt, orders[i].ID, orders[j].CreatedAt, orders[j].ID)
+	})
+	if len(orders) > q.Limit {
+		orders = orders[:q.Limit]
+	}
+	return orders, err
+}
+
+// inPage reports whether an item is within [from, to) and after the cursor.
+func inPage(createdAt time.Time, id string, from, to time.Time, after *domain.Cursor) bool {
+	if !from.IsZero() && createdAt.Before(from) {
+		return false
+	}
+	if !to.IsZero() && !createdAt.Before(to) {
+		return false
+	}
+	return after == nil || after.Before(createdAt, id)
+}
+
+// newerThan orders history newest first by (created_at, id).
+func newerThan(at1 time.Time, id1 string, at2 time.Time, id2 string) bool {
+	if !at1.Equal(at2) {
+		return at1.After(at2)
+	}
+	return id1 > id2
+}
+
+func (r *orderRepo) GetOpenByPair(ctx context.Context, pair string) ([]*domain.Order, error) {
+	return r.find(func(o *domain.Order) bool {
+		return o.Pair == pair && (o.Status == domain.OrderStatusOpen || o.Status == domain.OrderStatusPartiallyFilled)
+	})
+}
+
+func (r *orderRepo) GetActivePairs(ctx context.Context) ([]string, error) {
+	orders, err := r.find(func(o *domain.Order) bool {
+		switch o.Status {
+		case domain.OrderStatusOpen, domain.OrderStatusPartiallyFilled, domain.OrderStatusPending:
+			return true
+		}
+		return false
+	})
+	seen := make(map[string]bool)
+	var pairs []string
+	for _, o := range orders {
+		if !seen[o.Pair] {
+			seen[o.Pair] = true
+			pairs = append(pairs, o.Pair)
+		}
+	}
+	sort.Strings(pairs)
+	return pairs, err
+}
+
+func (r *orderRepo) GetPendingByPair(ctx context.Context, pair string) ([]*domain.Order, error) {
+	return r.find(func(o *domain.Order) bool {
+		return o.Pair == pair && o.Status == domain.OrderStatusPending
+	})
+}
+
+func (r *orderRepo) GetLiveByMaker(ctx context.Context, maker string) ([]*domain.Order, error) {
+	return r.find(func(o *domain.Order) bool {
+		switch o.Status {
+		case domain.OrderStatusOpen, domain.OrderStatusPartiallyFilled, domain.OrderStatusPending:
+			return strings.EqualFold(o.Maker, maker)
+		}
+		return false
+	})
+}
+
+func (r *orderRepo) MarkTriggered(ctx context.Context, id string, triggeredAt time.Time) error {
+	return r.update(id, func(o *domain.Order) {
+		if o.Status == domain.OrderStatusPending {
+			o.Status = domain.OrderStatusOpen
+			o.TriggeredAt = &triggeredAt
+		}
+	})
+}
+
+func (r *orderRepo) GetByGroup(ctx context.Context, groupID string) ([]*domain.Order, error) {
+	orders, err := r.find(func(o *domain.Order) bool { return o.GroupID == groupID })
+	sort.SliceStable(orders, func(i, j int) bool {
+		ei, ej := orders[i].GroupRole == domain.GroupRoleEntry, orders[j].GroupRole == domain.GroupRoleEntry
+		if ei != ej {
+			return ei
+		}
+		return orders[i].CreatedAt.Before(orders[j].CreatedAt)
+	})
+	return orders, err
+}
+
+func (r *orderRepo) UpdateGroupLeg(ctx context.Context, id string, status domain.OrderStatus, filledBase string, baseCap *big.Int) error {
+	filled, ok := new(big.Int).SetString(filledBase, 10)
+	if !ok {
+		return fmt.Errorf("invalid filled base %q", filledBase)
+	}
+	return r.update(id, func(o *domain.Order) {
+		o.Status = status
+		o.FilledBase = filled
+		o.BaseCap = nil
+		if baseCap != nil {
+			o.BaseCap = new(big.Int).Set(baseCap)
+		}
+	})
+}
+
+func (r *orderRepo) UpdateStatus(ctx context.Context, id string, status domain.OrderStatus, filledBase string) error {
+	filled, ok := new(big.Int).SetString(filledBase, 10)
+	if !ok {
+		return fmt.Errorf("invalid filled base %q", filledBase)
+	}
+	return r.update(id, func(o *domain.Order) {
+		o.Status = status
+		o.FilledBase = filled
+	})
+}
+
+// update applies fn to a stored order; like an UPDATE, a missing order is
+// not an error.
+func (r *orderRepo) update(id string, fn func(o *domain.Order)) error {
+	return r.with(func(d *data) error {
+		if o, ok := d.orders[id]; ok {
+			fn(o)
+			o.UpdatedAt = time.Now()
+		}
+		return nil
+	})
+}
+
+type tradeRepo struct{ with access }
+
+func cloneTrade(t *domain.Trade) *domain.Trade {
+	c := *t
+	c.BaseAmount = new(big.Int).Set(t.BaseAmount)
+	c.QuoteAmount = new(big.Int).Set(t.QuoteAmount)
+	return &c
+}
+
+func (r *tradeRepo) Create(ctx context.Context, trade *domain.Trade) error {
+	if trade.ID == "" {
+		trade.ID = uuid.New().String()
+	}
+	trade.CreatedAt = time.Now()
+
+	return r.with(func(d *data) error {
+		d.trades = append(d.trades, cloneTrade(trade))
+		return nil
+	})
+}
+
+func (r *tradeRepo) GetByPair(ctx context.Context, pair string, limit int) ([]*domain.Trade, error) {
+	var trades []*domain.Trade
+	err := r.with(func(d *data) error {
+		for i := len(d.trades) - 1; i >= 0 && len(trades) < limit; i-- {
+			if d.trades[i].Pair == pair {
+				trades = append(trades, cloneTrade(d.trades[i]))
+			}
+		}
+		return nil
+	})
+	return trades, err
+}
+
+func (r *tradeRepo) List(ctx context.Context, q domain.TradeQuery) ([]*domain.Trade, error) {
+	var trades []*domain.Trade
+	err := r.with(func(d *data) error {
+		for _, t := range d.trades {
+			if q.Pair != "" && t.Pair != q.Pair {
+				continue
+			}
+			if q.Address != "" {
+				buyer, seller := strings.EqualFold(t.Buyer, q.Address), strings.EqualFold(t.Seller, q.Address)
+				switch q.Side {
+				case domain.SideBuy:
+					seller = false
+				case domain.SideSell:
+					buyer = false
+				}
+				if !buyer && !seller {
+					continue
+				}
+			}
+			if inPage(t.CreatedAt, t.ID, q.From, q.To, q.After) {
+				trades = append(trades, cloneTrade(t))
+			}
+		}
+		return nil
+	})
+	sort.Slice(trades, func(i, j int) bool {
+		return newerThan(trades[i].CreatedAt, trades[i].ID, trades[j].CreatedAt, trades[j].ID)
+	})
+	if len(trades) > q.Limit {
+		trades = trades[:q.Limit]
+	}
+	return trades, err
+}
+
+func (r *tradeRepo) MarkSettled(ctx context.Context, id string, txHash string) error {
+	return r.with(func(d *data) error {
+		for _, t := range d.trades {
+			if t.ID == id {
+				t.SettledOnChain = true
+				t.TxHash = txHash
+			}
+		}
+		return nil
+	})
+}
+
+func (r *tradeRepo) GetUnsettled(ctx context.Context) ([]*domain.Trade, error) {
+	var trades []*domain.Trade
+	err := r.with(func(d *data) error {
+		for _, t := range d.trades {
+			if !t.SettledOnChain {
+				trades = append(trades, cloneTrade(t))
+			}
+		}
+		return nil
+	})
+	return trades, err
+}
+
+type groupRepo struct{ with access }
+
+func (r *groupRepo) Create(ctx context.Context, group *domain.OrderGroup) error {
+	if group.ID == "" {
+		group.ID = uuid.New().String()
+	}
+	now := time.Now()
+	group.CreatedAt = now
+	group.UpdatedAt = now
+
+	return r.with(func(d *data) error {
+		if _, ok := d.groups[group.ID]; ok {
+			return fmt.Errorf("duplicate group id %s", group.ID)
+		}
+		g := *group
+		d.groups[group.ID] = &g
+		d.groupIDs = append(d.groupIDs, group.ID)
+		return nil
+	})
+}
+
+func (r *groupRepo) GetByID(ctx context.Context, id string) (*domain.OrderGroup, error) {
+	var group *domain.OrderGroup
+	err := r.with(func(d *data) error {
+		g, ok := d.groups[id]
+		if !ok {
+			return sql.ErrNoRows
+		}
+		c := *g
+		group = &c
+		return nil
+	})
+	return group, err
+}
+
+func (r *groupRepo) GetActiveByPair(ctx context.Context, pair string) ([]*domain.OrderGroup, error) {
+	var groups []*domain.OrderGroup
+	err := r.with(func(d *data) error {
+		for _, id := range d.groupIDs {
+			if g := d.groups[id]; g.Pair == pair && g.Status == domain.GroupStatusActive {
+				c := *g
+				groups = append(groups, &c)
+			}
+		}
+		return nil
+	})
+	return groups, err
+}
+
+func (r *groupRepo) UpdateStatus(ctx context.Context, id string, status domain.GroupStatus) error {
+	return r.with(func(d *data) error {
+		if g, ok := d.groups[id]; ok {
+			g.Status = status
+			g.UpdatedAt = time.Now()
+		}
+		return nil
+	})
+}
+
+// eventRepo stores events JSON-encoded like the order_events table, so that
+// replays see exactly what they would read back from Postgres.
+type eventRepo struct{ with access }
+
+func (r *eventRepo) Append(ctx context.Context, events []ob.Event) error {
+	return r.with(func(d *data) error {
+		for _, ev := range events {
+			stored := d.events[ev.Pair]
+			if n := len(stored); n > 0 && ev.Seq <= stored[n-1].seq {
+				return fmt.Errorf("append event %s/%d: journal is already at %d", ev.Pair, ev.Seq, stored[n-1].seq)
+			}
+			payload, err := json.Marshal(ev)
+			if err != nil {
+				return fmt.Errorf("encode event %d: %w", ev.Seq, err)
+			}
+			d.events[ev.Pair] = append(stored, storedEvent{ev.Seq, payload})
+		}
+		return nil
+	})
+}
+
+func (r *eventRepo) ListByPair(ctx context.Context, pair string, afterSeq uint64) ([]ob.Event, error) {
+	var events []ob.Event
+	err := r.with(func(d *data) error {
+		for _, stored := range d.events[pair] {
+			if stored.seq <= afterSeq {
+				continue
+			}
+			var ev ob.Event
+			if err := json.Unmarshal(stored.payload, &ev); err != nil {
+				return fmt.Errorf("decode event: %w", err)
+			}
+			events = append(events, ev)
+		}
+		return nil
+	})
+	return events, err
+}
+
+func (r *eventRepo) LastSeq(ctx context.Context, pair string) (uint64, error) {
+	var seq uint64
+	err := r.with(func(d *data) error {
+		if stored := d.events[pair]; len(stored) > 0 {
+			seq = stored[len(stored)-1].seq
+		}
+		return nil
+	})
+	return seq, err
+}
+
+func (r *eventRepo) Pairs(ctx context.Context) ([]string, error) {
+	var pairs []string
+	err := r.with(func(d *data) error {
+		for pair := range d.events {
+			pairs = append(pairs, pair)
+		}
+		return nil
+	})
+	sort.Strings(pairs)
+	return pairs, err
+}
+
+type outboxRepo struct{ with access }
+
+func (r *outboxRepo) Enqueue(ctx context.Context, topic string, payload interface{}) error {
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("encode %s message: %w", topic, err)
+	}
+	return r.with(func(d *data) error {
+		d.nextOutbox++
+		d.outbox = append(d.outbox, &outboxRow{msg: repository.OutboxMessage{
+			ID:        d.nextOutbox,
+			Topic:     topic,
+			Payload:   encoded,
+			CreatedAt: time.Now(),
+		}})
+		return nil
+	})
+}
+
+func (r *outboxRepo) Pending(ctx context.Context, topic string, limit int) ([]*repository.OutboxMessage, error) {
+	var msgs []*repository.OutboxMessage
+	err := r.with(func(d *data) error {
+		for _, row := range d.outbox {
+			if len(msgs) == limit {
+				break
+			}
+			if row.msg.Topic == topic && !row.processed && !row.dead {
+				msg := row.msg
+				msgs = append(msgs, &msg)
+			}
+		}
+		return nil
+	})
+	return msgs, err
+}
+
+func (r *outboxRepo) MarkProcessed(ctx context.Context, ids []int64) error {
+	return r.with(func(d *data) error {
+		for _, id := range ids {
+			if row := d.outboxRow(id); row != nil {
+				row.processed = true
+			}
+		}
+		return nil
+	})
+}
+
+func (r *outboxRepo) MarkFailed(ctx context.Context, id int64, cause error, dead bool) error {
+	return r.with(func(d *data) error {
+		if row := d.outboxRow(id); row != nil {
+			row.msg.Attempts++
+			row.lastError = cause.Error()
+			row.dead = dead
+		}
+		return nil
+	})
+}
+
+func (r *outboxRepo) Lease(ctx context.Context, holder string, ttl time.Duration) (bool, error) {
+	held := false
+	err := r.with(func(d *data) error {
+		now := time.Now()
+		if d.leaseHolder != holder && now.Before(d.leaseUntil) {
+			return nil
+		}
+		d.leaseHolder, d.leaseUntil = holder, now.Add(ttl)
+		held = true
+		return nil
+	})
+	return held, err
+}
+
+func (d *data) outboxRow(id int64) *outboxRow {
+	for _, row := range d.outbox {
+		if row.msg.ID == id {
+			return row
+		}
+	}
+	return nil
+}
+
+type candleRepo struct{ with access }
+
+func keyOf(c *domain.Candle) candleKey {
+	return candleKey{pair: c.Pair, interval: c.Interval, openTime: c.OpenTime.Unix()}
+}
+
+func (r *candleRepo) Merge(ctx context.Context, c *domain.Candle) (*domain.Candle, error) {
+	var merged *domain.Candle
+	err := r.with(func(d *data) error {
+		stored, ok := d.candles[keyOf(c)]
+		if !ok {
+			stored = c.Clone()
+			stored.OpenTime = stored.OpenTime.UTC()
+			d.candles[keyOf(c)] = stored
+		} else {
+			stored.Merge(c)
+		}
+		merged = stored.Clone()
+		return nil
+	})
+	return merged, err
+}
+
+func (r *candleRepo) List(ctx context.Context, pair string, interval domain.CandleInterval, from, to time.Time, limit int) ([]*domain.Candle, error) {
+	var candles []*domain.Candle
+	err := r.with(func(d *data) error {
+		for _, c := range d.candles {
+			if c.Pair == pair && c.Interval == interval && !c.OpenTime.Before(from) && c.OpenTime.Before(to) {
+				candles = append(candles, c.Clone())
+			}
+		}
+		return nil
+	})
+	sort.Slice(candles, func(i, j int) bool { return candles[i].OpenTime.Before(candles[j].OpenTime) })
+	if len(candles) > limit {
+		candles = candles[len(candles)-limit:]
+	}
+	return candles, err
+}
+
+func (r *candleRepo) LatestOpenTime(ctx context.Context, interval domain.CandleInterval) (time.Time, error) {
+	var latest time.Time
+	err := r.with(func(d *data) error {
+		for _, c := range d.candles {
+			if c.Interval == interval && c.OpenTime.After(latest) {
+				latest = c.OpenTime
+			}
+		}
+		return nil
+	})
+	return latest, err
+}
+
+func (r *candleRepo) Rebuild(ctx context.Context, interval domain.CandleInterval, since time.Time) error {
+	return r.with(func(d *data) error {
+		trades := make([]*domain.Trade, 0, len(d.trades))
+		for _, t := range d.trades {
+			if !t.CreatedAt.Before(since) {
+				trades = append(trades, t)
+			}
+		}
+		sort.SliceStable(trades, func(i, j int) bool { return trades[i].CreatedAt.Before(trades[j].CreatedAt) })
+
+		rebuilt := make(map[candleKey]*domain.Candle)
+		for _, t := range trades {
+			c := domain.NewCandle(interval, t)
+			if stored, ok := rebuilt[keyOf(c)]; ok {
+				stored.Merge(c)
+			} else {
+				rebuilt[keyOf(c)] = c
+			}
+		}
+		for key, c := range rebuilt {
+			d.candles[key] = c
+		}
+		return nil
+	})
+}
+
+type apiKeyRepo struct{ with access }
+
+func cloneAPIKey(k *domain.APIKey) *domain.APIKey {
+	c := *k
+	c.Scopes = append([]domain.Scope(nil), k.Scopes...)
+	if k.RevokedAt != nil {
+		revokedAt := *k.RevokedAt
+		c.RevokedAt = &revokedAt
+	}
+	return &c
+}
+
+func (r *apiKeyRepo) Create(ctx context.Context, key *domain.APIKey) error {
+	key.CreatedAt = time.Now()
+	return r.with(func(d *data) error {
+		if _, ok := d.apiKeys[key.ID]; ok {
+			return fmt.Errorf("duplicate api key id %s", key.ID)
+		}
+		d.apiKeys[key.ID] = cloneAPIKey(key)
+		return nil
+	})
+}
+
+func (r *apiKeyRepo) GetByID(ctx context.Context, id string) (*domain.APIKey, error) {
+	var key *domain.APIKey
+	err := r.with(func(d *data) error {
+		k, ok := d.apiKeys[id]
+		if !ok {
+			return sql.ErrNoRows
+		}
+		key = cloneAPIKey(k)
+		return nil
+	})
+	return key, err
+}
+
+func (r *apiKeyRepo) ListByAddress(ctx context.Context, address string) ([]*domain.APIKey, error) {
+	var keys []*domain.APIKey
+	err := r.with(func(d *data) error {
+		for _, k := range d.apiKeys {
+			if k.Address == address {
+				keys = append(keys, cloneAPIKey(k))
+			}
+		}
+		return nil
+	})
+	sort.Slice(keys, func(i, j int) bool {
+		if !keys[i].CreatedAt.Equal(keys[j].CreatedAt) {
+			return keys[i].CreatedAt.After(keys[j].CreatedAt)
+		}
+		return keys[i].ID > keys[j].ID
+	})
+	return keys, err
+}
+
+func (r *apiKeyRepo) Revoke(ctx context.Context, id, address string) (bool, error) {
+	var revoked bool
+	err := r.with(func(d *data) error {
+		if k, ok := d.apiKeys[id]; ok && k.Address == address && k.RevokedAt == nil {
+			now := time.Now()
+			k.RevokedAt = &now
+			revoked = true
+		}
+		return nil
+	})
+	return revoked, err
+}
+
+type riskRepo struct{ with access }
+
+func (r *riskRepo) GetOverride(ctx context.Context, address string) (*domain.RiskOverride, error) {
+	var override *domain.RiskOverride
+	err := r.with(func(d *data) error {
+		o, ok := d.riskOverrides[address]
+		if !ok {
+			return sql.ErrNoRows
+		}
+		override = o.Clone()
+		return nil
+	})
+	return override, err
+}
+
+func (r *riskRepo) ListOverrides(ctx context.Context) ([]*domain.RiskOverride, error) {
+	var overrides []*domain.RiskOverride
+	err := r.with(func(d *data) error {
+		for _, o := range d.riskOverrides {
+			overrides = append(overrides, o.Clone())
+		}
+		return nil
+	})
+	sort.Slice(overrides, func(i, j int) bool { return overrides[i].Address < overrides[j].Address })
+	return overrides, err
+}
+
+func (r *riskRepo) PutOverride(ctx context.Context, o *domain.RiskOverride) error {
+	o.UpdatedAt = time.Now()
+	return r.with(func(d *data) error {
+		d.riskOverrides[o.Address] = o.Clone()
+		return nil
+	})
+}
+
+func (r *riskRepo) DeleteOverride(ctx context.Context, address string) (bool, error) {
+	var deleted bool
+	err := r.with(func(d *data) error {
+		_, deleted = d.riskOverrides[address]
+		delete(d.riskOverrides, address)
+		return nil
+	})
+	return deleted, err
+}
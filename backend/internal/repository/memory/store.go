@@ -0,0 +1,169 @@
+// Package memory implements the repository interfaces in process memory, so
+// that the service layer can be tested without Postgres or Redis.
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/nexus-orderbook-dex/backend/internal/domain"
+	"github.com/nexus-orderbook-dex/backend/internal/repository"
+)
+
+// Store implements repository.Store. Transactions run one at a time on a
+// copy of the data that replaces it on commit; calls outside a transaction
+// apply immediately.
+type Store struct {
+	mu   sync.Mutex
+	data *data
+}
+
+func NewStore() *Store {
+	return &Store{data: newData()}
+}
+
+// data is everything the store holds. Stored orders and trades are never
+// shared with callers.
+type data struct {
+	orders     map[string]*domain.Order
+	orderIDs   []string // insertion order
+	trades     []*domain.Trade
+	groups     map[string]*domain.OrderGroup
+	groupIDs   []string
+	events     map[string][]storedEvent // pair -> events in seq order
+	outbox     []*outboxRow
+	nextOutbox int64
+	candles    map[candleKey]*domain.Candle
+	apiKeys    map[string]*domain.APIKey
+	// address -> override of the global risk limits
+	riskOverrides map[string]*domain.RiskOverride
+	// relay lease, see OutboxRepository.Lease
+	leaseHolder string
+	leaseUntil  time.Time
+}
+
+type storedEvent struct {
+	seq     uint64
+	payload []byte // JSON, as in the order_events table
+}
+
+type candleKey struct {
+	pair     string
+	interval domain.CandleInterval
+	openTime int64 // unix seconds
+}
+
+type outboxRow struct {
+	msg       repository.OutboxMessage
+	lastError string
+	processed bool
+	dead      bool
+}
+
+func newData() *data {
+	return &data{
+		orders:  make(map[string]*domain.Order),
+		groups:  make(map[string]*domain.OrderGroup),
+		events:  make(map[string][]storedEvent),
+		candles: make(map[candleKey]*domain.Candle),
+		apiKeys: make(map[string]*domain.APIKey),
+
+		riskOverrides: make(map[string]*domain.RiskOverride),
+	}
+}
+
+func (d *data) clone() *data {
+	c := &data{
+		orders:     make(map[string]*domain.Order, len(d.orders)),
+		orderIDs:   append([]string(nil), d.orderIDs...),
+		trades:     make([]*domain.Trade, len(d.trades)),
+		groups:     make(map[string]*domain.OrderGroup, len(d.groups)),
+		groupIDs:   append([]string(nil), d.groupIDs...),
+		events:     make(map[string][]storedEvent, len(d.events)),
+		outbox:     make([]*outboxRow, len(d.outbox)),
+		nextOutbox: d.nextOutbox,
+		candles:    make(map[candleKey]*domain.Candle, len(d.candles)),
+		apiKeys:    make(map[string]*domain.APIKey, len(d.apiKeys)),
+
+		riskOverrides: make(map[string]*domain.RiskOverride, len(d.riskOverrides)),
+
+		leaseHolder: d.leaseHolder,
+		leaseUntil:  d.leaseUntil,
+	}
+	for id, o := range d.orders {
+		c.orders[id] = o.Clone()
+	}
+	for i, t := range d.trades {
+		c.trades[i] = cloneTrade(t)
+	}
+	for id, g := range d.groups {
+		group := *g
+		c.groups[id] = &group
+	}
+	for pair, events := range d.events {
+		c.events[pair] = append([]storedEvent(nil), events...)
+	}
+	for i, row := range d.outbox {
+		r := *row
+		c.outbox[i] = &r
+	}
+	for key, candle := range d.candles {
+		c.candles[key] = candle.Clone()
+	}
+	for id, k := range d.apiKeys {
+		c.apiKeys[id] = cloneAPIKey(k)
+	}
+	for address, o := range d.riskOverrides {
+		c.riskOverrides[address] = o.Clone()
+	}
+	return c
+}
+
+// access runs fn on the data a repository works on.
+type access func(fn func(d *data) error) error
+
+func (s *Store) direct(fn func(d *data) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return fn(s.data)
+}
+
+func (s *Store) Orders() repository.OrderRepository   { return &orderRepo{s.direct} }
+func (s *Store) Trades() repository.TradeRepository   { return &tradeRepo{s.direct} }
+func (s *Store) Groups() repository.GroupRepository   { return &groupRepo{s.direct} }
+func (s *Store) Events() repository.EventRepository   { return &eventRepo{s.direct} }
+func (s *Store) Outbox() repository.OutboxRepository  { return &outboxRepo{s.direct} }
+func (s *Store) Candles() repository.CandleRepository { return &candleRepo{s.direct} }
+func (s *Store) APIKeys() repository.APIKeyRepository { return &apiKeyRepo{s.direct} }
+func (s *Store) Risk() repository.RiskRepository      { return &riskRepo{s.direct} }
+
+// InTx runs fn on a copy of the data and keeps the copy if fn returns nil.
+// Calling the store's own repositories from inside fn deadlocks, as it
+// would block on a row lock in Postgres.
+func (s *Store) InTx(ctx context.Context, fn func(tx repository.Repositories) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	work := s.data.clone()
+	if err := fn(&txRepos{work: work}); err != nil {
+		return err
+	}
+	s.data = work
+	return nil
+}
+
+type txRepos struct {
+	work *data
+}
+
+func (t *txRepos) use(fn func(d *data) error) error { return fn(t.work) }
+
+func (t *txRepos) Orders() repository.OrderRepository   { return &orderRepo{t.use} }
+func (t *txRepos) Trades() repository.TradeRepository   { return &tradeRepo{t.use} }
+func (t *txRepos) Groups() repository.GroupRepository   { return &groupRepo{t.use} }
+func (t *txRepos) Events() repository.EventRepository   { return &eventRepo{t.use} }
+func (t *txRepos) Outbox() repository.OutboxRepository  { return &outboxRepo{t.use} }
+func (t *txRepos) Candles() repository.CandleRepository { return &candleRepo{t.use} }
+func (t *txRepos) APIKeys() repository.APIKeyRepository { return &apiKeyRepo{t.use} }
+func (t *txRepos) Risk() repository.RiskRepository      { return &riskRepo{t.use} }
@@ -0,0 +1,77 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/nexus-orderbook-dex/backend/internal/domain"
+)
+
+type APIKeyRepo struct {
+	db DBTX
+}
+
+func NewAPIKeyRepo(db DBTX) *APIKeyRepo {
+	return &APIKeyRepo{db: db}
+}
+
+type apiKeyRow struct {
+	domain.APIKey
+	Scopes pq.StringArray `db:"scopes"`
+}
+
+func (row apiKeyRow) key() *domain.APIKey {
+	key := row.APIKey
+	key.Scopes = make([]domain.Scope, len(row.Scopes))
+	for i, s := range row.Scopes {
+		key.Scopes[i] = domain.Scope(s)
+	}
+	return &key
+}
+
+func (r *APIKeyRepo) Create(ctx context.Context, key *domain.APIKey) error {
+	key.CreatedAt = time.Now()
+	scopes := make(pq.StringArray, len(key.Scopes))
+	for i, s := range key.Scopes {
+		scopes[i] = string(s)
+	}
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO api_keys (id, address, secret, label, scopes, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		key.ID, key.Address, key.Secret, key.Label, scopes, key.CreatedAt,
+	)
+	return err
+}
+
+func (r *APIKeyRepo) GetByID(ctx context.Context, id string) (*domain.APIKey, error) {
+	var row apiKeyRow
+	if err := r.db.GetContext(ctx, &row, `SELECT * FROM api_keys WHERE id = $1`, id); err != nil {
+		return nil, err
+	}
+	return row.key(), nil
+}
+
+func (r *APIKeyRepo) ListByAddress(ctx context.Context, address string) ([]*domain.APIKey, error) {
+	var rows []apiKeyRow
+	err := r.db.SelectContext(ctx, &rows,
+		`SELECT * FROM api_keys WHERE address = $1 ORDER BY created_at DESC, id DESC`, address)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]*domain.APIKey, len(rows))
+	for i, row := range rows {
+		keys[i] = row.key()
+	}
+	return keys, nil
+}
+
+func (r *APIKeyRepo) Revoke(ctx context.Context, id, address string) (bool, error) {
+	res, err := r.db.ExecContext(ctx,
+		`UPDATE api_keys SET revoked_at = NOW() WHERE id = $1 AND address = $2 AND revoked_at IS NULL`, id, address)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
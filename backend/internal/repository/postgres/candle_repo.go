@@ -0,0 +1,134 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/nexus-orderbook-dex/backend/internal/domain"
+)
+
+type CandleRepo struct {
+	db DBTX
+}
+
+func NewCandleRepo(db DBTX) *CandleRepo {
+	return &CandleRepo{db: db}
+}
+
+type candleRow struct {
+	Pair        string    `db:"pair"`
+	Resolution  string    `db:"resolution"`
+	OpenTime    time.Time `db:"open_time"`
+	Open        float64   `db:"open"`
+	High        float64   `db:"high"`
+	Low         float64   `db:"low"`
+	Close       float64   `db:"close"`
+	BaseVolume  string    `db:"base_volume"`
+	QuoteVolume string    `db:"quote_volume"`
+	TradeCount  int       `db:"trade_count"`
+}
+
+func (r *CandleRepo) Merge(ctx context.Context, c *domain.Candle) (*domain.Candle, error) {
+	var row candleRow
+	err := r.db.GetContext(ctx, &row, `
+		INSERT INTO candles (pair, resolution, open_time, open, high, low, close, base_volume, quote_volume, trade_count)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (pair, resolution, open_time) DO UPDATE SET
+			high = GREATEST(candles.high, EXCLUDED.high),
+			low = LEAST(candles.low, EXCLUDED.low),
+			close = EXCLUDED.close,
+			base_volume = candles.base_volume + EXCLUDED.base_volume,
+			quote_volume = candles.quote_volume + EXCLUDED.quote_volume,
+			trade_count = candles.trade_count + EXCLUDED.trade_count
+		RETURNING *`,
+		c.Pair, string(c.Interval), c.OpenTime, c.Open, c.High, c.Low, c.Close,
+		c.BaseVolume.String(), c.QuoteVolume.String(), c.TradeCount,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return rowToCandle(row)
+}
+
+func (r *CandleRepo) List(ctx context.Context, pair string, interval domain.CandleInterval, from, to time.Time, limit int) ([]*domain.Candle, error) {
+	var rows []candleRow
+	err := r.db.SelectContext(ctx, &rows, `
+		SELECT * FROM (
+			SELECT * FROM candles
+			WHERE pair = $1 AND resolution = $2 AND open_time >= $3 AND open_time < $4
+			ORDER BY open_time DESC LIMIT $5
+		) newest ORDER BY open_time ASC`,
+		pair, string(interval), from, to, limit)
+	if err != nil {
+		return nil, err
+	}
+	candles := make([]*domain.Candle, 0, len(rows))
+	for _, row := range rows {
+		c, err := rowToCandle(row)
+		if err != nil {
+			return nil, err
+		}
+		candles = append(candles, c)
+	}
+	return candles, nil
+}
+
+func (r *CandleRepo) LatestOpenTime(ctx context.Context, interval domain.CandleInterval) (time.Time, error) {
+	var latest *time.Time
+	err := r.db.GetContext(ctx, &latest,
+		`SELECT MAX(open_time) FROM candles WHERE resolution = $1`, string(interval))
+	if err != nil || latest == nil {
+		return time.Time{}, err
+	}
+	return *latest, nil
+}
+
+func (r *CandleRepo) Rebuild(ctx context.Context, interval domain.CandleInterval, since time.Time) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO candles (pair, resolution, open_time, open, high, low, close, base_volume, quote_volume, trade_count)
+		SELECT pair, $1, bucket,
+			(array_agg(market_price ORDER BY created_at, id))[1],
+			MAX(market_price), MIN(market_price),
+			(array_agg(market_price ORDER BY created_at DESC, id DESC))[1],
+			SUM(base_amount), SUM(quote_amount), COUNT(*)
+		FROM (
+			SELECT *, to_timestamp((floor(extract(epoch FROM created_at) / $2) * $2)::double precision) AS bucket,
+				COALESCE(clearing_price, price) AS market_price
+			FROM trades WHERE created_at >= $3
+		) t
+		GROUP BY pair, bucket
+		ON CONFLICT (pair, resolution, open_time) DO UPDATE SET
+			open = EXCLUDED.open,
+			high = EXCLUDED.high,
+			low = EXCLUDED.low,
+			close = EXCLUDED.close,
+			base_volume = EXCLUDED.base_volume,
+			quote_volume = EXCLUDED.quote_volume,
+			trade_count = EXCLUDED.trade_count`,
+		string(interval), int64(interval.Duration().Seconds()), since)
+	return err
+}
+
+func rowToCandle(row candleRow) (*domain.Candle, error) {
+	baseVolume, ok := parseBigInt(row.BaseVolume)
+	if !ok {
+		return nil, fmt.Errorf("invalid base_volume: %s", row.BaseVolume)
+	}
+	quoteVolume, ok := parseBigInt(row.QuoteVolume)
+	if !ok {
+		return nil, fmt.Errorf("invalid quote_volume: %s", row.QuoteVolume)
+	}
+	return &domain.Candle{
+		Pair:        row.Pair,
+		Interval:    domain.CandleInterval(row.Resolution),
+		OpenTime:    row.OpenTime.UTC(),
+		Open:        row.Open,
+		High:        row.High,
+		Low:         row.Low,
+		Close:       row.Close,
+		BaseVolume:  baseVolume,
+		QuoteVolume: quoteVolume,
+		TradeCount:  row.TradeCount,
+	}, nil
+}
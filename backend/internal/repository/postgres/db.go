@@ -0,0 +1,79 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/nexus-orderbook-dex/backend/internal/repository"
+)
+
+// DBTX is the query interface shared by *sqlx.DB and *sqlx.Tx, so that a
+// repository can run on the pool or inside a caller's transaction.
+type DBTX interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+}
+
+// Store bundles the repositories that take part in one unit of work. It
+// implements repository.Store.
+type Store struct {
+	db *sqlx.DB
+
+	orders  *OrderRepo
+	trades  *TradeRepo
+	groups  *GroupRepo
+	events  *EventRepo
+	outbox  *OutboxRepo
+	candles *CandleRepo
+	apiKeys *APIKeyRepo
+	risk    *RiskRepo
+}
+
+func NewStore(db *sqlx.DB) *Store {
+	s := newStore(db)
+	s.db = db
+	return s
+}
+
+func newStore(db DBTX) *Store {
+	return &Store{
+		orders:  NewOrderRepo(db),
+		trades:  NewTradeRepo(db),
+		groups:  NewGroupRepo(db),
+		events:  NewEventRepo(db),
+		outbox:  NewOutboxRepo(db),
+		candles: NewCandleRepo(db),
+		apiKeys: NewAPIKeyRepo(db),
+		risk:    NewRiskRepo(db),
+	}
+}
+
+func (s *Store) Orders() repository.OrderRepository   { return s.orders }
+func (s *Store) Trades() repository.TradeRepository   { return s.trades }
+func (s *Store) Groups() repository.GroupRepository   { return s.groups }
+func (s *Store) Events() repository.EventRepository   { return s.events }
+func (s *Store) Outbox() repository.OutboxRepository  { return s.outbox }
+func (s *Store) Candles() repository.CandleRepository { return s.candles }
+func (s *Store) APIKeys() repository.APIKeyRepository { return s.apiKeys }
+func (s *Store) Risk() repository.RiskRepository      { return s.risk }
+
+// InTx runs fn with repositories bound to a single transaction. The
+// transaction commits if fn returns nil and rolls back otherwise.
+func (s *Store) InTx(ctx context.Context, fn func(tx repository.Repositories) error) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(newStore(tx)); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+	return nil
+}
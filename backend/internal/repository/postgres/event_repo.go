@@ -0,0 +1,72 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	ob "github.com/nexus-orderbook-dex/backend/internal/orderbook"
+)
+
+// EventRepo stores the matching engine's event journal. Rows are only ever
+// inserted; (pair, seq) is unique so a journal cannot be rewritten.
+type EventRepo struct {
+	db DBTX
+}
+
+func NewEventRepo(db DBTX) *EventRepo {
+	return &EventRepo{db: db}
+}
+
+// Append writes a batch of events. Run it inside Store.InTx so that the
+// batch is journalled together with the changes it describes.
+func (r *EventRepo) Append(ctx context.Context, events []ob.Event) error {
+	for _, ev := range events {
+		payload, err := json.Marshal(ev)
+		if err != nil {
+			return fmt.Errorf("encode event %d: %w", ev.Seq, err)
+		}
+		_, err = r.db.ExecContext(ctx, `
+			INSERT INTO order_events (pair, seq, type, order_id, payload, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6)`,
+			ev.Pair, ev.Seq, string(ev.Type), nullString(ev.OrderID), payload, ev.Time,
+		)
+		if err != nil {
+			return fmt.Errorf("append event %s/%d: %w", ev.Pair, ev.Seq, err)
+		}
+	}
+	return nil
+}
+
+// ListByPair returns the events of a pair with seq > afterSeq in order.
+func (r *EventRepo) ListByPair(ctx context.Context, pair string, afterSeq uint64) ([]ob.Event, error) {
+	var payloads [][]byte
+	err := r.db.SelectContext(ctx, &payloads,
+		`SELECT payload FROM order_events WHERE pair = $1 AND seq > $2 ORDER BY seq ASC`, pair, afterSeq)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]ob.Event, len(payloads))
+	for i, payload := range payloads {
+		if err := json.Unmarshal(payload, &events[i]); err != nil {
+			return nil, fmt.Errorf("decode event: %w", err)
+		}
+	}
+	return events, nil
+}
+
+// LastSeq returns the highest journalled seq of a pair, 0 if none.
+func (r *EventRepo) LastSeq(ctx context.Context, pair string) (uint64, error) {
+	var seq uint64
+	err := r.db.GetContext(ctx, &seq,
+		`SELECT COALESCE(MAX(seq), 0) FROM order_events WHERE pair = $1`, pair)
+	return seq, err
+}
+
+// Pairs returns every pair that has journalled events.
+func (r *EventRepo) Pairs(ctx context.Context) ([]string, error) {
+	var pairs []string
+	err := r.db.SelectContext(ctx, &pairs, `SELECT DISTINCT pair FROM order_events ORDER BY pair`)
+	return pairs, err
+}
@@ -0,0 +1,59 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nexus-orderbook-dex/backend/internal/domain"
+)
+
+type GroupRepo struct {
+	db DBTX
+}
+
+func NewGroupRepo(db DBTX) *GroupRepo {
+	return &GroupRepo{db: db}
+}
+
+func (r *GroupRepo) Create(ctx context.Context, group *domain.OrderGroup) error {
+	if group.ID == "" {
+		group.ID = uuid.New().String()
+	}
+	now := time.Now()
+	group.CreatedAt = now
+	group.UpdatedAt = now
+
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO order_groups (id, type, pair, maker, status, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		group.ID, string(group.Type), group.Pair, group.Maker, string(group.Status),
+		group.CreatedAt, group.UpdatedAt,
+	)
+	return err
+}
+
+func (r *GroupRepo) GetByID(ctx context.Context, id string) (*domain.OrderGroup, error) {
+	var group domain.OrderGroup
+	err := r.db.GetContext(ctx, &group, `SELECT * FROM order_groups WHERE id = $1`, id)
+	if err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+func (r *GroupRepo) GetActiveByPair(ctx context.Context, pair string) ([]*domain.OrderGroup, error) {
+	var groups []*domain.OrderGroup
+	err := r.db.SelectContext(ctx, &groups,
+		`SELECT * FROM order_groups WHERE pair = $1 AND status = 'active' ORDER BY created_at ASC`, pair)
+	if err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+func (r *GroupRepo) UpdateStatus(ctx context.Context, id string, status domain.GroupStatus) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE order_groups SET status = $1, updated_at = NOW() WHERE id = $2`, string(status), id)
+	return err
+}
@@ -3,18 +3,18 @@ package postgres
 import (
 	"context"
 	"fmt"
+	"math/big"
 	"time"
 
 	"github.com/google/uuid"
-	"github.com/jmoiron/sqlx"
 	"github.com/nexus-orderbook-dex/backend/internal/domain"
 )
 
 type OrderRepo struct {
-	db *sqlx.DB
+	db DBTX
 }
 
-func NewOrderRepo(db *sqlx.DB) *OrderRepo {
+func NewOrderRepo(db DBTX) *OrderRepo {
 	return &OrderRepo{db: db}
 }
 
@@ -35,6 +35,17 @@ type orderRow struct {
 	Pair       string    `db:"pair"`
 	CreatedAt  time.Time `db:"created_at"`
 	UpdatedAt  time.Time `db:"updated_at"`
+
+	OrderType    string     `db:"order_type"`
+	TriggerPrice float64    `db:"trigger_price"`
+	TriggeredAt  *time.Time `db:"triggered_at"`
+
+	GroupID   *string `db:"group_id"`
+	GroupRole *string `db:"group_role"`
+	BaseCap   *string `db:"base_cap"`
+
+	DisplayBase *string `db:"display_base"`
+	ReplacesID  *string `db:"replaces_id"`
 }
 
 func (r *OrderRepo) Create(ctx context.Context, order *domain.Order) error {
@@ -44,15 +55,21 @@ func (r *OrderRepo) Create(ctx context.Context, order *domain.Order) error {
 	now := time.Now()
 	order.CreatedAt = now
 	order.UpdatedAt = now
+	if order.Type == "" {
+		order.Type = domain.OrderTypeLimit
+	}
 
 	_, err := r.db.ExecContext(ctx, `
-		INSERT INTO orders (id, maker, token_sell, token_buy, amount_sell, amount_buy, expiry, nonce, salt, signature, side, status, filled_base, pair, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)`,
+		INSERT INTO orders (id, maker, token_sell, token_buy, amount_sell, amount_buy, expiry, nonce, salt, signature, side, status, filled_base, pair, created_at, updated_at, order_type, trigger_price, triggered_at, group_id, group_role, base_cap, display_base, replaces_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24)`,
 		order.ID, order.Maker, order.TokenSell, order.TokenBuy,
 		order.AmountSell.String(), order.AmountBuy.String(),
 		order.Expiry, order.Nonce, order.Salt.String(),
 		order.Signature, string(order.Side), string(order.Status),
 		order.FilledBase.String(), order.Pair, order.CreatedAt, order.UpdatedAt,
+		string(order.Type), order.TriggerPrice, order.TriggeredAt,
+		nullString(order.GroupID), nullString(string(order.GroupRole)), nullBigInt(order.BaseCap),
+		nullBigInt(order.DisplayBase), nullString(order.ReplacesID),
 	)
 	return err
 }
@@ -68,13 +85,37 @@ func (r *OrderRepo) GetByID(ctx context.Context, id string) (*domain.Order, erro
 
 func (r *OrderRepo) GetByMaker(ctx context.Context, maker string) ([]*domain.Order, error) {
 	var rows []orderRow
-	err := r.db.SelectContext(ctx, &rows, `SELECT * FROM orders WHERE maker = $1 ORDER BY created_at DESC`, maker)
+	err := r.db.SelectContext(ctx, &rows, `SELECT * FROM orders WHERE LOWER(maker) = LOWER($1) ORDER BY created_at DESC`, maker)
 	if err != nil {
 		return nil, err
 	}
 	return rowsToOrders(rows)
 }
 
+// List returns a page of a maker's orders matching q, newest first.
+// Makers match in any case, on idx_orders_maker_created.
+func (r *OrderRepo) List(ctx context.Context, q domain.OrderQuery) ([]*domain.Order, error) {
+	var c conditions
+	c.add("LOWER(maker) = LOWER(?)", q.Maker)
+	if q.Pair != "" {
+		c.add("pair = ?", q.Pair)
+	}
+	if q.Side != "" {
+		c.add("side = ?", string(q.Side))
+	}
+	if q.Status != "" {
+		c.add("status = ?", string(q.Status))
+	}
+	c.timeRange(q.From, q.To)
+	query, args := c.page(`SELECT * FROM orders`, q.After, q.Limit)
+
+	var rows []orderRow
+	if err := r.db.SelectContext(ctx, &rows, query, args...); err != nil {
+		return nil, err
+	}
+	return rowsToOrders(rows)
+}
+
 func (r *OrderRepo) GetOpenByPair(ctx context.Context, pair string) ([]*domain.Order, error) {
 	var rows []orderRow
 	err := r.db.SelectContext(ctx, &rows,
@@ -85,6 +126,70 @@ func (r *OrderRepo) GetOpenByPair(ctx context.Context, pair string) ([]*domain.O
 	return rowsToOrders(rows)
 }
 
+// GetActivePairs returns every pair with open, partially filled or pending orders.
+func (r *OrderRepo) GetActivePairs(ctx context.Context) ([]string, error) {
+	var pairs []string
+	err := r.db.SelectContext(ctx, &pairs,
+		`SELECT DISTINCT pair FROM orders WHERE status IN ('open', 'partially_filled', 'pending') ORDER BY pair`)
+	return pairs, err
+}
+
+// GetLiveByMaker returns a maker's open, partially filled and pending
+// orders across pairs, matching the address in any case. It is served by
+// the partial index idx_orders_maker_live on LOWER(maker): the status list
+// must stay exactly the index's predicate, or the planner cannot use it.
+func (r *OrderRepo) GetLiveByMaker(ctx context.Context, maker string) ([]*domain.Order, error) {
+	var rows []orderRow
+	err := r.db.SelectContext(ctx, &rows, `
+		SELECT * FROM orders
+		WHERE LOWER(maker) = LOWER($1) AND status IN ('open', 'partially_filled', 'pending')
+		ORDER BY created_at ASC`, maker)
+	if err != nil {
+		return nil, err
+	}
+	return rowsToOrders(rows)
+}
+
+// GetPendingByPair returns conditional orders still waiting for their trigger.
+func (r *OrderRepo) GetPendingByPair(ctx context.Context, pair string) ([]*domain.Order, error) {
+	var rows []orderRow
+	err := r.db.SelectContext(ctx, &rows,
+		`SELECT * FROM orders WHERE pair = $1 AND status = 'pending' ORDER BY created_at ASC`, pair)
+	if err != nil {
+		return nil, err
+	}
+	return rowsToOrders(rows)
+}
+
+// MarkTriggered records that a conditional order was released to the book.
+func (r *OrderRepo) MarkTriggered(ctx context.Context, id string, triggeredAt time.Time) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE orders SET status = 'open', triggered_at = $1, updated_at = NOW() WHERE id = $2 AND status = 'pending'`,
+		triggeredAt, id)
+	return err
+}
+
+// GetByGroup returns the legs of an order group in submission order, with
+// a bracket's entry first.
+func (r *OrderRepo) GetByGroup(ctx context.Context, groupID string) ([]*domain.Order, error) {
+	var rows []orderRow
+	err := r.db.SelectContext(ctx, &rows,
+		`SELECT * FROM orders WHERE group_id = $1
+		 ORDER BY CASE group_role WHEN 'entry' THEN 0 ELSE 1 END, created_at ASC`, groupID)
+	if err != nil {
+		return nil, err
+	}
+	return rowsToOrders(rows)
+}
+
+// UpdateGroupLeg persists a status or cap change the engine applied to a group leg.
+func (r *OrderRepo) UpdateGroupLeg(ctx context.Context, id string, status domain.OrderStatus, filledBase string, baseCap *big.Int) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE orders SET status = $1, filled_base = $2, base_cap = $3, updated_at = NOW() WHERE id = $4`,
+		string(status), filledBase, nullBigInt(baseCap), id)
+	return err
+}
+
 func (r *OrderRepo) UpdateStatus(ctx context.Context, id string, status domain.OrderStatus, filledBase string) error {
 	_, err := r.db.ExecContext(ctx,
 		`UPDATE orders SET status = $1, filled_base = $2, updated_at = NOW() WHERE id = $3`,
@@ -109,6 +214,18 @@ func rowToOrder(row orderRow) (*domain.Order, error) {
 	if !ok {
 		return nil, fmt.Errorf("invalid filled_base: %s", row.FilledBase)
 	}
+	var baseCap *big.Int
+	if row.BaseCap != nil {
+		if baseCap, ok = parseBigInt(*row.BaseCap); !ok {
+			return nil, fmt.Errorf("invalid base_cap: %s", *row.BaseCap)
+		}
+	}
+	var displayBase *big.Int
+	if row.DisplayBase != nil {
+		if displayBase, ok = parseBigInt(*row.DisplayBase); !ok {
+			return nil, fmt.Errorf("invalid display_base: %s", *row.DisplayBase)
+		}
+	}
 
 	return &domain.Order{
 		ID:         row.ID,
@@ -127,6 +244,17 @@ func rowToOrder(row orderRow) (*domain.Order, error) {
 		Pair:       row.Pair,
 		CreatedAt:  row.CreatedAt,
 		UpdatedAt:  row.UpdatedAt,
+
+		Type:         domain.OrderType(row.OrderType),
+		TriggerPrice: row.TriggerPrice,
+		TriggeredAt:  row.TriggeredAt,
+
+		GroupID:   derefString(row.GroupID),
+		GroupRole: domain.GroupRole(derefString(row.GroupRole)),
+		BaseCap:   baseCap,
+
+		DisplayBase: displayBase,
+		ReplacesID:  derefString(row.ReplacesID),
 	}, nil
 }
 
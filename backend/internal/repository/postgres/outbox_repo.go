@@ -0,0 +1,79 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/nexus-orderbook-dex/backend/internal/repository"
+)
+
+type OutboxRepo struct {
+	db DBTX
+}
+
+func NewOutboxRepo(db DBTX) *OutboxRepo {
+	return &OutboxRepo{db: db}
+}
+
+// Enqueue records a message; payload is stored as JSON.
+func (r *OutboxRepo) Enqueue(ctx context.Context, topic string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("encode %s message: %w", topic, err)
+	}
+	_, err = r.db.ExecContext(ctx,
+		`INSERT INTO outbox (topic, payload) VALUES ($1, $2)`, topic, data)
+	return err
+}
+
+// Pending returns up to limit undelivered messages of a topic in insertion
+// order. Only the relay holding the lease reads them, so rows are not
+// locked.
+func (r *OutboxRepo) Pending(ctx context.Context, topic string, limit int) ([]*repository.OutboxMessage, error) {
+	var msgs []*repository.OutboxMessage
+	err := r.db.SelectContext(ctx, &msgs, `
+		SELECT id, topic, payload, attempts, created_at FROM outbox
+		WHERE topic = $1 AND processed_at IS NULL AND dead_at IS NULL
+		ORDER BY id ASC
+		LIMIT $2`, topic, limit)
+	return msgs, err
+}
+
+// MarkProcessed records that messages were delivered.
+func (r *OutboxRepo) MarkProcessed(ctx context.Context, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE outbox SET processed_at = NOW() WHERE id = ANY($1)`, pq.Array(ids))
+	return err
+}
+
+// MarkFailed records a failed delivery attempt; the message stays pending
+// unless it is dead.
+func (r *OutboxRepo) MarkFailed(ctx context.Context, id int64, cause error, dead bool) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE outbox SET attempts = attempts + 1, last_error = $1,
+			dead_at = CASE WHEN $2 THEN NOW() END
+		WHERE id = $3`, cause.Error(), dead, id)
+	return err
+}
+
+// Lease takes the relay lease if it is free or has expired, or renews it
+// for its holder.
+func (r *OutboxRepo) Lease(ctx context.Context, holder string, ttl time.Duration) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `
+		INSERT INTO outbox_lease (id, holder, expires_at)
+		VALUES (1, $1, NOW() + make_interval(secs => $2))
+		ON CONFLICT (id) DO UPDATE SET holder = EXCLUDED.holder, expires_at = EXCLUDED.expires_at
+		WHERE outbox_lease.holder = EXCLUDED.holder OR outbox_lease.expires_at < NOW()`,
+		holder, ttl.Seconds())
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
@@ -0,0 +1,48 @@
+package postgres
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nexus-orderbook-dex/backend/internal/domain"
+)
+
+// conditions builds the WHERE clause of a filtered query.
+type conditions struct {
+	where []string
+	args  []interface{}
+}
+
+// add appends a condition; each ? in cond is bound to the next arg.
+func (c *conditions) add(cond string, args ...interface{}) {
+	for _, arg := range args {
+		c.args = append(c.args, arg)
+		cond = strings.Replace(cond, "?", fmt.Sprintf("$%d", len(c.args)), 1)
+	}
+	c.where = append(c.where, cond)
+}
+
+// timeRange filters created_at to [from, to); zero bounds are open.
+func (c *conditions) timeRange(from, to time.Time) {
+	if !from.IsZero() {
+		c.add("created_at >= ?", from)
+	}
+	if !to.IsZero() {
+		c.add("created_at < ?", to)
+	}
+}
+
+// page completes a newest-first keyset query over (created_at, id).
+func (c *conditions) page(selectFrom string, after *domain.Cursor, limit int) (string, []interface{}) {
+	if after != nil {
+		c.add("(created_at, id) < (?, ?)", after.CreatedAt, after.ID)
+	}
+	query := selectFrom
+	if len(c.where) > 0 {
+		query += " WHERE " + strings.Join(c.where, " AND ")
+	}
+	c.args = append(c.args, limit)
+	query += fmt.Sprintf(" ORDER BY created_at DESC, id DESC LIMIT $%d", len(c.args))
+	return query, c.args
+}
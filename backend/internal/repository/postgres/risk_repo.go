@@ -0,0 +1,104 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/nexus-orderbook-dex/backend/internal/domain"
+)
+
+type RiskRepo struct {
+	db DBTX
+}
+
+func NewRiskRepo(db DBTX) *RiskRepo {
+	return &RiskRepo{db: db}
+}
+
+type riskOverrideRow struct {
+	Address          string    `db:"address"`
+	MaxOpenOrders    *int      `db:"max_open_orders"`
+	MaxOrderNotional []byte    `db:"max_order_notional"` // JSON object of token to amount
+	MaxOpenNotional  []byte    `db:"max_open_notional"`  // JSON object of token to amount
+	PriceBand        *float64  `db:"price_band"`
+	UpdatedAt        time.Time `db:"updated_at"`
+}
+
+func (row riskOverrideRow) override() (*domain.RiskOverride, error) {
+	o := &domain.RiskOverride{
+		Address:       row.Address,
+		MaxOpenOrders: row.MaxOpenOrders,
+		PriceBand:     row.PriceBand,
+		UpdatedAt:     row.UpdatedAt,
+	}
+	if row.MaxOrderNotional != nil {
+		if err := json.Unmarshal(row.MaxOrderNotional, &o.MaxOrderNotional); err != nil {
+			return nil, fmt.Errorf("invalid max_order_notional: %w", err)
+		}
+	}
+	if row.MaxOpenNotional != nil {
+		if err := json.Unmarshal(row.MaxOpenNotional, &o.MaxOpenNotional); err != nil {
+			return nil, fmt.Errorf("invalid max_open_notional: %w", err)
+		}
+	}
+	return o, nil
+}
+
+func (r *RiskRepo) GetOverride(ctx context.Context, address string) (*domain.RiskOverride, error) {
+	var row riskOverrideRow
+	if err := r.db.GetContext(ctx, &row, `SELECT * FROM risk_overrides WHERE address = $1`, address); err != nil {
+		return nil, err
+	}
+	return row.override()
+}
+
+func (r *RiskRepo) ListOverrides(ctx context.Context) ([]*domain.RiskOverride, error) {
+	var rows []riskOverrideRow
+	if err := r.db.SelectContext(ctx, &rows, `SELECT * FROM risk_overrides ORDER BY address`); err != nil {
+		return nil, err
+	}
+	overrides := make([]*domain.RiskOverride, len(rows))
+	for i, row := range rows {
+		o, err := row.override()
+		if err != nil {
+			return nil, err
+		}
+		overrides[i] = o
+	}
+	return overrides, nil
+}
+
+func (r *RiskRepo) PutOverride(ctx context.Context, o *domain.RiskOverride) error {
+	orderNotional, err := nullTokenAmounts(o.MaxOrderNotional)
+	if err != nil {
+		return err
+	}
+	openNotional, err := nullTokenAmounts(o.MaxOpenNotional)
+	if err != nil {
+		return err
+	}
+	o.UpdatedAt = time.Now()
+	_, err = r.db.ExecContext(ctx, `
+		INSERT INTO risk_overrides (address, max_open_orders, max_order_notional, max_open_notional, price_band, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (address) DO UPDATE SET
+			max_open_orders = EXCLUDED.max_open_orders,
+			max_order_notional = EXCLUDED.max_order_notional,
+			max_open_notional = EXCLUDED.max_open_notional,
+			price_band = EXCLUDED.price_band,
+			updated_at = EXCLUDED.updated_at`,
+		o.Address, o.MaxOpenOrders, orderNotional, openNotional, o.PriceBand, o.UpdatedAt,
+	)
+	return err
+}
+
+func (r *RiskRepo) DeleteOverride(ctx context.Context, address string) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM risk_overrides WHERE address = $1`, address)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
@@ -6,15 +6,14 @@ import (
 	"time"
 
 	"github.com/google/uuid"
-	"github.com/jmoiron/sqlx"
 	"github.com/nexus-orderbook-dex/backend/internal/domain"
 )
 
 type TradeRepo struct {
-	db *sqlx.DB
+	db DBTX
 }
 
-func NewTradeRepo(db *sqlx.DB) *TradeRepo {
+func NewTradeRepo(db DBTX) *TradeRepo {
 	return &TradeRepo{db: db}
 }
 
@@ -28,6 +27,7 @@ type tradeRow struct {
 	BaseAmount     string    `db:"base_amount"`
 	QuoteAmount    string    `db:"quote_amount"`
 	Price          float64   `db:"price"`
+	ClearingPrice  *float64  `db:"clearing_price"`
 	TxHash         string    `db:"tx_hash"`
 	SettledOnChain bool      `db:"settled_on_chain"`
 	CreatedAt      time.Time `db:"created_at"`
@@ -38,14 +38,19 @@ func (r *TradeRepo) Create(ctx context.Context, trade *domain.Trade) error {
 		trade.ID = uuid.New().String()
 	}
 	trade.CreatedAt = time.Now()
+	var clearingPrice *float64
+	if trade.ClearingPrice > 0 {
+		clearingPrice = &trade.ClearingPrice
+	}
 
 	_, err := r.db.ExecContext(ctx, `
-		INSERT INTO trades (id, buy_order_id, sell_order_id, buyer, seller, pair, base_amount, quote_amount, price, tx_hash, settled_on_chain, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`,
+		INSERT INTO trades (id, buy_order_id, sell_order_id, buyer, seller, pair, base_amount, quote_amount, price, tx_hash, settled_on_chain, created_at, clearing_price)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`,
 		trade.ID, trade.BuyOrderID, trade.SellOrderID,
 		trade.Buyer, trade.Seller, trade.Pair,
 		trade.BaseAmount.String(), trade.QuoteAmount.String(),
 		trade.Price, trade.TxHash, trade.SettledOnChain, trade.CreatedAt,
+		clearingPrice,
 	)
 	return err
 }
@@ -60,6 +65,33 @@ func (r *TradeRepo) GetByPair(ctx context.Context, pair string, limit int) ([]*d
 	return rowsToTrades(rows)
 }
 
+// List returns a page of trades matching q, newest first. Addresses match
+// in any case, on idx_trades_buyer_created and idx_trades_seller_created.
+func (r *TradeRepo) List(ctx context.Context, q domain.TradeQuery) ([]*domain.Trade, error) {
+	var c conditions
+	if q.Pair != "" {
+		c.add("pair = ?", q.Pair)
+	}
+	if q.Address != "" {
+		switch q.Side {
+		case domain.SideBuy:
+			c.add("LOWER(buyer) = LOWER(?)", q.Address)
+		case domain.SideSell:
+			c.add("LOWER(seller) = LOWER(?)", q.Address)
+		default:
+			c.add("(LOWER(buyer) = LOWER(?) OR LOWER(seller) = LOWER(?))", q.Address, q.Address)
+		}
+	}
+	c.timeRange(q.From, q.To)
+	query, args := c.page(`SELECT * FROM trades`, q.After, q.Limit)
+
+	var rows []tradeRow
+	if err := r.db.SelectContext(ctx, &rows, query, args...); err != nil {
+		return nil, err
+	}
+	return rowsToTrades(rows)
+}
+
 func (r *TradeRepo) MarkSettled(ctx context.Context, id string, txHash string) error {
 	_, err := r.db.ExecContext(ctx,
 		`UPDATE trades SET settled_on_chain = TRUE, tx_hash = $1 WHERE id = $2`, txHash, id)
@@ -86,6 +118,10 @@ func rowToTrade(row tradeRow) (*domain.Trade, error) {
 		return nil, fmt.Errorf("invalid quote_amount: %s", row.QuoteAmount)
 	}
 
+	var clearingPrice float64
+	if row.ClearingPrice != nil {
+		clearingPrice = *row.ClearingPrice
+	}
 	return &domain.Trade{
 		ID:             row.ID,
 		BuyOrderID:     row.BuyOrderID,
@@ -96,6 +132,7 @@ func rowToTrade(row tradeRow) (*domain.Trade, error) {
 		BaseAmount:     baseAmount,
 		QuoteAmount:    quoteAmount,
 		Price:          row.Price,
+		ClearingPrice:  clearingPrice,
 		TxHash:         row.TxHash,
 		SettledOnChain: row.SettledOnChain,
 		CreatedAt:      row.CreatedAt,
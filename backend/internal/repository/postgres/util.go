@@ -1,9 +1,50 @@
 package postgres
 
-import "math/big"
+import (
+	"encoding/json"
+	"math/big"
+)
 
 func parseBigInt(s string) (*big.Int, bool) {
 	n := new(big.Int)
 	_, ok := n.SetString(s, 10)
 	return n, ok
 }
+
+// nullString maps "" to SQL NULL.
+func nullString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// nullBigInt maps a nil *big.Int to SQL NULL.
+func nullBigInt(n *big.Int) *string {
+	if n == nil {
+		return nil
+	}
+	s := n.String()
+	return &s
+}
+
+// nullTokenAmounts encodes amounts by token as a JSON object, nil as SQL
+// NULL.
+func nullTokenAmounts(amounts map[string]*big.Int) (*string, error) {
+	if amounts == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(amounts)
+	if err != nil {
+		return nil, err
+	}
+	s := string(data)
+	return &s, nil
+}
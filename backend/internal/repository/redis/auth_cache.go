@@ -0,0 +1,65 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/nexus-orderbook-dex/backend/internal/domain"
+	"github.com/redis/go-redis/v9"
+)
+
+// AuthCache implements repository.AuthCache with keys that expire on their
+// own, so that every server sees the same sessions.
+type AuthCache struct {
+	client *redis.Client
+}
+
+func NewAuthCache(client *redis.Client) *AuthCache {
+	return &AuthCache{client: client}
+}
+
+func nonceKey(nonce string) string          { return "auth:nonce:" + nonce }
+func sessionKey(id string) string           { return "auth:session:" + id }
+func requestKey(keyID, nonce string) string { return "auth:request:" + keyID + ":" + nonce }
+
+func (c *AuthCache) AddNonce(ctx context.Context, nonce string, ttl time.Duration) error {
+	return c.client.Set(ctx, nonceKey(nonce), 1, ttl).Err()
+}
+
+// TakeNonce deletes the nonce; only the caller that deletes it may use it.
+func (c *AuthCache) TakeNonce(ctx context.Context, nonce string) (bool, error) {
+	n, err := c.client.Del(ctx, nonceKey(nonce)).Result()
+	return n == 1, err
+}
+
+func (c *AuthCache) SetSession(ctx context.Context, id string, session domain.Session, ttl time.Duration) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+	return c.client.Set(ctx, sessionKey(id), data, ttl).Err()
+}
+
+func (c *AuthCache) GetSession(ctx context.Context, id string) (*domain.Session, error) {
+	data, err := c.client.Get(ctx, sessionKey(id)).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var session domain.Session
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (c *AuthCache) DeleteSession(ctx context.Context, id string) error {
+	return c.client.Del(ctx, sessionKey(id)).Err()
+}
+
+func (c *AuthCache) ClaimRequestNonce(ctx context.Context, keyID, nonce string, ttl time.Duration) (bool, error) {
+	return c.client.SetNX(ctx, requestKey(keyID, nonce), 1, ttl).Result()
+}
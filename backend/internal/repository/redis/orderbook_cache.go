@@ -4,7 +4,10 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log"
+	"time"
 
+	"github.com/nexus-orderbook-dex/backend/internal/repository"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -16,58 +19,117 @@ func NewOrderbookCache(client *redis.Client) *OrderbookCache {
 	return &OrderbookCache{client: client}
 }
 
-type PriceLevelData struct {
-	Price  float64 `json:"price"`
-	Amount string  `json:"amount"`
-	Count  int     `json:"count"`
-}
+type PriceLevelData = repository.PriceLevelData
 
-func (c *OrderbookCache) SetSnapshot(ctx context.Context, pair string, bids, asks []PriceLevelData) error {
-	bidsJSON, err := json.Marshal(bids)
+// SetSnapshot stores a pair's book under a single key, so that readers
+// never see the bids and asks of different versions.
+func (c *OrderbookCache) SetSnapshot(ctx context.Context, pair string, snapshot repository.BookSnapshot) error {
+	data, err := json.Marshal(snapshot)
 	if err != nil {
 		return err
 	}
-	asksJSON, err := json.Marshal(asks)
+	return c.client.Set(ctx, snapshotKey(pair), data, 0).Err()
+}
+
+// GetSnapshot returns a pair's book, empty at sequence 0 if none is stored.
+func (c *OrderbookCache) GetSnapshot(ctx context.Context, pair string) (repository.BookSnapshot, error) {
+	var snapshot repository.BookSnapshot
+	data, err := c.client.Get(ctx, snapshotKey(pair)).Bytes()
+	if err == redis.Nil {
+		return snapshot, nil
+	}
 	if err != nil {
-		return err
+		return snapshot, err
 	}
-
-	pipe := c.client.Pipeline()
-	pipe.Set(ctx, fmt.Sprintf("ob:%s:bids", pair), bidsJSON, 0)
-	pipe.Set(ctx, fmt.Sprintf("ob:%s:asks", pair), asksJSON, 0)
-	_, err = pipe.Exec(ctx)
-	return err
+	err = json.Unmarshal(data, &snapshot)
+	return snapshot, err
 }
 
-func (c *OrderbookCache) GetSnapshot(ctx context.Context, pair string) (bids, asks []PriceLevelData, err error) {
-	bidsJSON, err := c.client.Get(ctx, fmt.Sprintf("ob:%s:bids", pair)).Bytes()
-	if err != nil && err != redis.Nil {
-		return nil, nil, err
-	}
-	asksJSON, err := c.client.Get(ctx, fmt.Sprintf("ob:%s:asks", pair)).Bytes()
-	if err != nil && err != redis.Nil {
-		return nil, nil, err
+// updatesStream carries the updates of every channel. Each server reads it
+// from its own offset and fans the entries out to its WebSocket clients.
+const (
+	updatesStream    = "ob:updates"
+	updatesStreamLen = 100000 // entries kept, approximately
+)
+
+// PublishUpdate appends a real-time update for the subscribers of a
+// channel to the updates stream.
+func (c *OrderbookCache) PublishUpdate(ctx context.Context, channel, key string, data interface{}) error {
+	msg, err := json.Marshal(data)
+	if err != nil {
+		return err
 	}
+	return c.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: updatesStream,
+		MaxLen: updatesStreamLen,
+		Approx: true,
+		Values: map[string]interface{}{"channel": channel, "key": key, "data": msg},
+	}).Err()
+}
+
+// Update is one entry of the updates stream.
+type Update struct {
+	ID      string
+	Channel string
+	Key     string // pair, or user key on the user channel
+	Data    []byte
+}
 
-	if len(bidsJSON) > 0 {
-		json.Unmarshal(bidsJSON, &bids)
+// ConsumeUpdates calls fn with every update appended to the stream from now
+// on, in order, until ctx is done. It keeps the ID of the last entry it
+// delivered as its offset and resumes from there after a read error, so a
+// lost connection skips nothing the stream still holds.
+func (c *OrderbookCache) ConsumeUpdates(ctx context.Context, fn func(Update)) error {
+	offset, err := c.streamTail(ctx)
+	if err != nil {
+		return fmt.Errorf("read updates stream: %w", err)
 	}
-	if len(asksJSON) > 0 {
-		json.Unmarshal(asksJSON, &asks)
+	for {
+		streams, err := c.client.XRead(ctx, &redis.XReadArgs{
+			Streams: []string{updatesStream, offset},
+			Count:   500,
+			Block:   5 * time.Second,
+		}).Result()
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			log.Printf("Updates stream read failed at %s: %v", offset, err)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+		for _, stream := range streams {
+			for _, msg := range stream.Messages {
+				channel, _ := msg.Values["channel"].(string)
+				key, _ := msg.Values["key"].(string)
+				data, _ := msg.Values["data"].(string)
+				fn(Update{ID: msg.ID, Channel: channel, Key: key, Data: []byte(data)})
+				offset = msg.ID
+			}
+		}
 	}
-	return bids, asks, nil
 }
 
-// PublishUpdate sends a real-time update to subscribers.
-func (c *OrderbookCache) PublishUpdate(ctx context.Context, pair string, data interface{}) error {
-	msg, err := json.Marshal(data)
+// streamTail returns the ID of the newest entry of the updates stream, or
+// the start of an empty stream.
+func (c *OrderbookCache) streamTail(ctx context.Context) (string, error) {
+	last, err := c.client.XRevRangeN(ctx, updatesStream, "+", "-", 1).Result()
 	if err != nil {
-		return err
+		return "", err
+	}
+	if len(last) == 0 {
+		return "0-0", nil
 	}
-	return c.client.Publish(ctx, fmt.Sprintf("ob:updates:%s", pair), msg).Err()
+	return last[0].ID, nil
 }
 
-// Subscribe returns a channel for orderbook updates.
-func (c *OrderbookCache) Subscribe(ctx context.Context, pair string) *redis.PubSub {
-	return c.client.Subscribe(ctx, fmt.Sprintf("ob:updates:%s", pair))
+func snapshotKey(pair string) string {
+	return fmt.Sprintf("ob:%s:book", pair)
 }
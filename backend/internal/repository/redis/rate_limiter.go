@@ -0,0 +1,102 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/nexus-orderbook-dex/backend/internal/repository"
+	"github.com/redis/go-redis/v9"
+)
+
+// takeTokens refills a request's buckets and takes from all of them only
+// if none is short, in one step, on the Redis clock so that servers with
+// skewed clocks share buckets fairly. KEYS are the buckets and ARGV the
+// cost followed by each bucket's rate and burst. It replies with the first
+// bucket that was short, or else the emptiest one. Buckets expire once
+// they would be full again.
+var takeTokens = redis.NewScript(`
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+local cost = tonumber(ARGV[1])
+
+local tokens = {}
+local short = 0
+for i, key in ipairs(KEYS) do
+  local rate = tonumber(ARGV[2 * i])
+  local burst = tonumber(ARGV[2 * i + 1])
+  if not (rate and burst and rate > 0 and burst > 0) then
+    return redis.error_reply('rate limit ' .. key .. ': rate and burst must be positive')
+  end
+  local b = redis.call('HMGET', key, 'tokens', 'ts')
+  local n = tonumber(b[1])
+  local ts = tonumber(b[2])
+  if n == nil then
+    n = burst
+    ts = now
+  end
+  tokens[i] = math.min(burst, n + math.max(0, now - ts) * rate / 1000)
+  if short == 0 and tokens[i] < cost then
+    short = i
+  end
+end
+
+local reply = {0, 1, '', 0}
+for i, key in ipairs(KEYS) do
+  local rate = tonumber(ARGV[2 * i])
+  local burst = tonumber(ARGV[2 * i + 1])
+  if short == 0 then
+    tokens[i] = tokens[i] - cost
+    if reply[3] == '' or tokens[i] < tonumber(reply[3]) then
+      reply = {1, i, tostring(tokens[i]), 0}
+    end
+  elseif short == i then
+    reply = {0, i, tostring(tokens[i]), math.ceil((cost - tokens[i]) * 1000 / rate)}
+  end
+  redis.call('HSET', key, 'tokens', tostring(tokens[i]), 'ts', now)
+  redis.call('PEXPIRE', key, math.ceil((burst - tokens[i]) * 1000 / rate) + 1000)
+end
+return reply
+`)
+
+// RateLimiter implements repository.RateLimiter with one hash per bucket.
+type RateLimiter struct {
+	client *redis.Client
+}
+
+func NewRateLimiter(client *redis.Client) *RateLimiter {
+	return &RateLimiter{client: client}
+}
+
+func (l *RateLimiter) Take(ctx context.Context, buckets []repository.RateBucket, cost float64) (repository.RateDecision, error) {
+	d := repository.RateDecision{Allowed: true, Remaining: math.Inf(1)}
+	if len(buckets) == 0 {
+		return d, nil
+	}
+	keys := make([]string, len(buckets))
+	args := []interface{}{cost}
+	for i, b := range buckets {
+		keys[i] = "rl:" + b.Key
+		args = append(args, b.Limit.Rate, b.Limit.Burst)
+	}
+	res, err := takeTokens.Run(ctx, l.client, keys, args...).Slice()
+	if err != nil {
+		return d, err
+	}
+	if len(res) != 4 {
+		return d, fmt.Errorf("unexpected rate limit reply %v", res)
+	}
+	allowed, _ := res[0].(int64)
+	bucket, _ := res[1].(int64)
+	remaining, _ := res[2].(string)
+	wait, _ := res[3].(int64)
+	d.Allowed = allowed == 1
+	d.Bucket = int(bucket) - 1
+	if d.Remaining, err = strconv.ParseFloat(remaining, 64); err != nil || d.Bucket < 0 || d.Bucket >= len(buckets) {
+		return d, fmt.Errorf("unexpected rate limit reply %v", res)
+	}
+	d.RetryAfter = time.Duration(wait) * time.Millisecond
+	return d, nil
+}
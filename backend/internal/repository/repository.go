@@ -0,0 +1,230 @@
+// Package repository defines the storage and cache interfaces the service
+// layer depends on. The postgres and redis packages implement them for
+// production; the memory package implements them for tests.
+package repository
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/nexus-orderbook-dex/backend/internal/domain"
+	ob "github.com/nexus-orderbook-dex/backend/internal/orderbook"
+)
+
+type OrderRepository interface {
+	Create(ctx context.Context, order *domain.Order) error
+	GetByID(ctx context.Context, id string) (*domain.Order, error)
+	GetByMaker(ctx context.Context, maker string) ([]*domain.Order, error)
+	// List returns up to q.Limit orders matching q, newest first.
+	List(ctx context.Context, q domain.OrderQuery) ([]*domain.Order, error)
+	GetOpenByPair(ctx context.Context, pair string) ([]*domain.Order, error)
+	// GetLiveByMaker returns a maker's open, partially filled and pending
+	// orders across pairs, matching the address in any case.
+	GetLiveByMaker(ctx context.Context, maker string) ([]*domain.Order, error)
+	GetActivePairs(ctx context.Context) ([]string, error)
+	GetPendingByPair(ctx context.Context, pair string) ([]*domain.Order, error)
+	MarkTriggered(ctx context.Context, id string, triggeredAt time.Time) error
+	// GetByGroup returns a group's legs in submission order, with a
+	// bracket's entry first.
+	GetByGroup(ctx context.Context, groupID string) ([]*domain.Order, error)
+	UpdateGroupLeg(ctx context.Context, id string, status domain.OrderStatus, filledBase string, baseCap *big.Int) error
+	UpdateStatus(ctx context.Context, id string, status domain.OrderStatus, filledBase string) error
+}
+
+type TradeRepository interface {
+	Create(ctx context.Context, trade *domain.Trade) error
+	GetByPair(ctx context.Context, pair string, limit int) ([]*domain.Trade, error)
+	// List returns up to q.Limit trades matching q, newest first.
+	List(ctx context.Context, q domain.TradeQuery) ([]*domain.Trade, error)
+	MarkSettled(ctx context.Context, id string, txHash string) error
+	GetUnsettled(ctx context.Context) ([]*domain.Trade, error)
+}
+
+type GroupRepository interface {
+	Create(ctx context.Context, group *domain.OrderGroup) error
+	GetByID(ctx context.Context, id string) (*domain.OrderGroup, error)
+	GetActiveByPair(ctx context.Context, pair string) ([]*domain.OrderGroup, error)
+	UpdateStatus(ctx context.Context, id string, status domain.GroupStatus) error
+}
+
+// EventRepository is the append-only journal of the matching engine.
+type EventRepository interface {
+	Append(ctx context.Context, events []ob.Event) error
+	ListByPair(ctx context.Context, pair string, afterSeq uint64) ([]ob.Event, error)
+	LastSeq(ctx context.Context, pair string) (uint64, error)
+	Pairs(ctx context.Context) ([]string, error)
+}
+
+// OutboxMessage is a side effect recorded in the same transaction as the
+// state change that caused it, waiting to be delivered by a relay.
+type OutboxMessage struct {
+	ID        int64     `db:"id"`
+	Topic     string    `db:"topic"`
+	Payload   []byte    `db:"payload"`
+	Attempts  int       `db:"attempts"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+type OutboxRepository interface {
+	// Enqueue records a message; payload is stored as JSON.
+	Enqueue(ctx context.Context, topic string, payload interface{}) error
+	// Pending returns up to limit undelivered messages of a topic in
+	// insertion order. Dead messages are not pending.
+	Pending(ctx context.Context, topic string, limit int) ([]*OutboxMessage, error)
+	MarkProcessed(ctx context.Context, ids []int64) error
+	// MarkFailed records a failed delivery attempt. A dead message is set
+	// aside for good after its last attempt.
+	MarkFailed(ctx context.Context, id int64, cause error, dead bool) error
+	// Lease takes or renews the right to deliver for holder until ttl from
+	// now. It reports false while another holder's lease runs.
+	Lease(ctx context.Context, holder string, ttl time.Duration) (bool, error)
+}
+
+// CandleRepository stores the OHLCV bars of every pair and interval.
+type CandleRepository interface {
+	// Merge folds c into the stored bar with the same pair, interval and open
+	// time, creating it if needed, and returns the merged bar.
+	Merge(ctx context.Context, c *domain.Candle) (*domain.Candle, error)
+	// List returns the newest limit bars opening in [from, to), oldest first.
+	List(ctx context.Context, pair string, interval domain.CandleInterval, from, to time.Time, limit int) ([]*domain.Candle, error)
+	// LatestOpenTime returns the open time of the newest bar of an interval
+	// across pairs, zero if there is none.
+	LatestOpenTime(ctx context.Context, interval domain.CandleInterval) (time.Time, error)
+	// Rebuild recomputes from the trades every bar of an interval opening
+	// at or after since, which must be a bar open time.
+	Rebuild(ctx context.Context, interval domain.CandleInterval, since time.Time) error
+}
+
+// APIKeyRepository stores the API keys of every address, revoked ones
+// included.
+type APIKeyRepository interface {
+	Create(ctx context.Context, key *domain.APIKey) error
+	GetByID(ctx context.Context, id string) (*domain.APIKey, error)
+	// ListByAddress returns an address's keys, newest first.
+	ListByAddress(ctx context.Context, address string) ([]*domain.APIKey, error)
+	// Revoke revokes a live key of address and reports whether there was one.
+	Revoke(ctx context.Context, id, address string) (bool, error)
+}
+
+// RiskRepository stores the per-address overrides of the global risk
+// limits, keyed by checksummed address.
+type RiskRepository interface {
+	// GetOverride returns sql.ErrNoRows if the address has no override.
+	GetOverride(ctx context.Context, address string) (*domain.RiskOverride, error)
+	ListOverrides(ctx context.Context) ([]*domain.RiskOverride, error)
+	// PutOverride creates or replaces the override of o.Address.
+	PutOverride(ctx context.Context, o *domain.RiskOverride) error
+	// DeleteOverride reports whether the address had an override.
+	DeleteOverride(ctx context.Context, address string) (bool, error)
+}
+
+// Repositories gives access to every repository, either directly or bound
+// to a transaction.
+type Repositories interface {
+	Orders() OrderRepository
+	Trades() TradeRepository
+	Groups() GroupRepository
+	Events() EventRepository
+	Outbox() OutboxRepository
+	Candles() CandleRepository
+	APIKeys() APIKeyRepository
+	Risk() RiskRepository
+}
+
+// Store is the service's storage. InTx runs fn with repositories bound to a
+// single transaction that commits if fn returns nil and rolls back otherwise.
+type Store interface {
+	Repositories
+	InTx(ctx context.Context, fn func(tx Repositories) error) error
+}
+
+// PriceLevelData is one aggregated price level as cached and published.
+type PriceLevelData struct {
+	Price  float64 `json:"price"`
+	Amount string  `json:"amount"`
+	Count  int     `json:"count"`
+}
+
+// BookSnapshot is the published view of a pair's aggregated book and the
+// journal sequence it reflects.
+type BookSnapshot struct {
+	Seq  uint64           `json:"seq"`
+	Bids []PriceLevelData `json:"bids"`
+	Asks []PriceLevelData `json:"asks"`
+}
+
+// SnapshotCache holds the latest aggregated book of each pair.
+type SnapshotCache interface {
+	SetSnapshot(ctx context.Context, pair string, snapshot BookSnapshot) error
+	GetSnapshot(ctx context.Context, pair string) (BookSnapshot, error)
+}
+
+// AuthCache holds the short-lived state of authentication: sign-in nonces,
+// sessions and the request nonces of API keys. Entries expire on their own.
+type AuthCache interface {
+	// AddNonce records a sign-in nonce for ttl.
+	AddNonce(ctx context.Context, nonce string, ttl time.Duration) error
+	// TakeNonce removes a sign-in nonce and reports whether it was
+	// outstanding, so that each nonce is used once.
+	TakeNonce(ctx context.Context, nonce string) (bool, error)
+	SetSession(ctx context.Context, id string, session domain.Session, ttl time.Duration) error
+	// GetSession returns a live session, nil if there is none.
+	GetSession(ctx context.Context, id string) (*domain.Session, error)
+	DeleteSession(ctx context.Context, id string) error
+	// ClaimRequestNonce records a request nonce of an API key for ttl and
+	// reports whether the key had not used it yet.
+	ClaimRequestNonce(ctx context.Context, keyID, nonce string, ttl time.Duration) (bool, error)
+}
+
+// RateLimit is a token bucket that holds up to Burst tokens and refills at
+// Rate tokens per second. Both must be positive.
+type RateLimit struct {
+	Rate  float64
+	Burst float64
+}
+
+// RateBucket is one bucket a request is charged to.
+type RateBucket struct {
+	Key   string
+	Limit RateLimit
+}
+
+// RateDecision is the outcome of taking tokens from a request's buckets.
+// It reports the first bucket that was short, or else the emptiest one.
+type RateDecision struct {
+	Allowed    bool
+	Bucket     int           // index of the bucket reported
+	Remaining  float64       // tokens left in it after the take
+	RetryAfter time.Duration // until it holds enough tokens, if not allowed
+}
+
+// RateLimiter keeps token buckets shared by every server.
+type RateLimiter interface {
+	// Take removes cost tokens from every bucket, each of which starts
+	// full, if all of them hold that many, and from none otherwise.
+	Take(ctx context.Context, buckets []RateBucket, cost float64) (RateDecision, error)
+}
+
+// WebSocket channels. Every pair has its own stream on each channel.
+const (
+	ChannelOrderbook = "orderbook" // level-2 book and group events
+	ChannelL3        = "l3"        // order-by-order changes
+	ChannelTrades    = "trades"    // public trades
+	ChannelTicker    = "ticker"    // 24h ticker
+	ChannelCandles   = "candles"   // live OHLCV bars
+	ChannelMarket    = "market"    // trading state of a pair
+	ChannelUser      = "user"      // a user's orders, fills and balances
+)
+
+// UserKey is the stream of an address on ChannelUser, which is keyed by
+// user instead of pair.
+func UserKey(address string) string {
+	return strings.ToLower(address)
+}
+
+// Publisher sends real-time updates to the subscribers of a pair's channel.
+type Publisher interface {
+	PublishUpdate(ctx context.Context, channel, pair string, data interface{}) error
+}
@@ -0,0 +1,298 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/nexus-orderbook-dex/backend/internal/domain"
+	"github.com/nexus-orderbook-dex/backend/internal/repository"
+	"github.com/nexus-orderbook-dex/backend/pkg/siwe"
+)
+
+// ErrUnauthorized is wrapped by every error that rejects credentials, as
+// opposed to failures to check them.
+var ErrUnauthorized = errors.New("unauthorized")
+
+func unauthorized(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrUnauthorized, fmt.Sprintf(format, args...))
+}
+
+const (
+	signInNonceTTL = 5 * time.Minute
+	sessionTTL     = 24 * time.Hour
+	// RequestWindow is how far the timestamp of a signed request may be
+	// from the server's clock. Request nonces are remembered twice as long.
+	RequestWindow = 30 * time.Second
+)
+
+// Principal is the address a request acts for and what it may do.
+type Principal struct {
+	Address string
+	KeyID   string // empty for sessions
+	Scopes  []domain.Scope
+}
+
+// Allows reports whether the principal holds scope.
+func (p *Principal) Allows(scope domain.Scope) bool {
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Owns reports whether address is the principal's.
+func (p *Principal) Owns(address string) bool {
+	return strings.EqualFold(p.Address, address)
+}
+
+// AuthService signs users in with Sign-In with Ethereum and manages their
+// API keys. Sessions hold every scope; an API key only its own.
+type AuthService struct {
+	store   repository.Store
+	cache   repository.AuthCache
+	domain  string // the host sign-in messages must name
+	chainID int64
+	now     func() time.Time
+}
+
+func NewAuthService(store repository.Store, cache repository.AuthCache, domain string, chainID *big.Int) *AuthService {
+	return &AuthService{
+		store:   store,
+		cache:   cache,
+		domain:  domain,
+		chainID: chainID.Int64(),
+		now:     time.Now,
+	}
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// sessionID is what the cache knows a session by, so that a leaked cache
+// does not leak bearer tokens.
+func sessionID(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Nonce issues a nonce for one sign-in message.
+func (s *AuthService) Nonce(ctx context.Context) (string, error) {
+	nonce, err := randomHex(16)
+	if err != nil {
+		return "", err
+	}
+	if err := s.cache.AddNonce(ctx, nonce, signInNonceTTL); err != nil {
+		return "", err
+	}
+	return nonce, nil
+}
+
+// Login verifies a signed EIP-4361 message and starts a session for its
+// address. The message must name this server's domain and chain and an
+// outstanding nonce, which it uses up.
+func (s *AuthService) Login(ctx context.Context, message, signature string) (string, *domain.Session, error) {
+	msg, err := siwe.Parse(message)
+	if err != nil {
+		return "", nil, unauthorized("invalid sign-in message: %v", err)
+	}
+	now := s.now()
+	switch {
+	case msg.Domain != s.domain:
+		return "", nil, unauthorized("message is for %s, not %s", msg.Domain, s.domain)
+	case msg.ChainID != s.chainID:
+		return "", nil, unauthorized("message is for chain %d, not %d", msg.ChainID, s.chainID)
+	}
+	if err := msg.Valid(now); err != nil {
+		return "", nil, unauthorized("%v", err)
+	}
+	sig, err := hexToBytes(signature)
+	if err != nil {
+		return "", nil, unauthorized("invalid signature hex: %v", err)
+	}
+	signer, err := siwe.RecoverSigner(message, sig)
+	if err != nil {
+		return "", nil, unauthorized("signature verification failed: %v", err)
+	}
+	if signer != msg.Address {
+		return "", nil, unauthorized("invalid signature: signer mismatch")
+	}
+	// Checked last so that a bad signature does not burn the nonce
+	ok, err := s.cache.TakeNonce(ctx, msg.Nonce)
+	if err != nil {
+		return "", nil, err
+	}
+	if !ok {
+		return "", nil, unauthorized("unknown or used nonce")
+	}
+
+	expiresAt := now.Add(sessionTTL)
+	if !msg.ExpirationTime.IsZero() && msg.ExpirationTime.Before(expiresAt) {
+		expiresAt = msg.ExpirationTime
+	}
+	token, err := randomHex(32)
+	if err != nil {
+		return "", nil, err
+	}
+	session := &domain.Session{Address: msg.Address.Hex(), ExpiresAt: expiresAt}
+	if err := s.cache.SetSession(ctx, sessionID(token), *session, expiresAt.Sub(now)); err != nil {
+		return "", nil, err
+	}
+	return token, session, nil
+}
+
+// Logout ends the session of token.
+func (s *AuthService) Logout(ctx context.Context, token string) error {
+	return s.cache.DeleteSession(ctx, sessionID(token))
+}
+
+// Session returns the principal of a bearer token.
+func (s *AuthService) Session(ctx context.Context, token string) (*Principal, error) {
+	session, err := s.cache.GetSession(ctx, sessionID(token))
+	if err != nil {
+		return nil, err
+	}
+	if session == nil {
+		return nil, unauthorized("invalid or expired session")
+	}
+	return &Principal{Address: session.Address, Scopes: domain.Scopes}, nil
+}
+
+// CreateAPIKey creates a key for address with the given scopes and returns
+// it with its secret, which is not shown again.
+func (s *AuthService) CreateAPIKey(ctx context.Context, address, label string, scopes []domain.Scope) (*domain.APIKey, error) {
+	if !common.IsHexAddress(address) {
+		return nil, fmt.Errorf("invalid address %q", address)
+	}
+	if len(scopes) == 0 {
+		return nil, fmt.Errorf("at least one scope is required")
+	}
+	seen := make(map[domain.Scope]bool)
+	var unique []domain.Scope
+	for _, scope := range scopes {
+		if !scope.Valid() {
+			return nil, fmt.Errorf("invalid scope %q", scope)
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			unique = append(unique, scope)
+		}
+	}
+
+	id, err := randomHex(16)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := randomHex(32)
+	if err != nil {
+		return nil, err
+	}
+	key := &domain.APIKey{
+		ID:      id,
+		Address: common.HexToAddress(address).Hex(),
+		Secret:  secret,
+		Label:   label,
+		Scopes:  unique,
+	}
+	if err := s.store.APIKeys().Create(ctx, key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// ListAPIKeys returns the keys of an address, newest first.
+func (s *AuthService) ListAPIKeys(ctx context.Context, address string) ([]*domain.APIKey, error) {
+	return s.store.APIKeys().ListByAddress(ctx, common.HexToAddress(address).Hex())
+}
+
+// RevokeAPIKey revokes a live key of address.
+func (s *AuthService) RevokeAPIKey(ctx context.Context, address, id string) error {
+	ok, err := s.store.APIKeys().Revoke(ctx, id, common.HexToAddress(address).Hex())
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("api key %s not found", id)
+	}
+	return nil
+}
+
+// SignedRequest is a request signed with an API key.
+type SignedRequest struct {
+	KeyID     string
+	Timestamp string // unix milliseconds
+	Nonce     string
+	Signature string // hex HMAC-SHA256 of the payload under the key's secret
+	Method    string
+	Path      string // path and query, as sent
+	Body      []byte
+}
+
+// SignRequest returns the signature of a request: the hex HMAC-SHA256
+// under secret of its timestamp, nonce, method, path and body, each but
+// the body followed by a newline.
+func SignRequest(secret, timestamp, nonce, method, path string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "\n" + nonce + "\n" + strings.ToUpper(method) + "\n" + path + "\n"))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyRequest checks a request signed with an API key and returns the
+// key's principal. The timestamp must be within RequestWindow of now and
+// the nonce new for the key, so that a captured request cannot be
+// replayed.
+func (s *AuthService) VerifyRequest(ctx context.Context, req SignedRequest) (*Principal, error) {
+	ms, err := strconv.ParseInt(req.Timestamp, 10, 64)
+	if err != nil {
+		return nil, unauthorized("invalid timestamp %q", req.Timestamp)
+	}
+	if skew := s.now().Sub(time.UnixMilli(ms)); skew > RequestWindow || skew < -RequestWindow {
+		return nil, unauthorized("timestamp outside the %v window", RequestWindow)
+	}
+	if req.Nonce == "" || len(req.Nonce) > 64 {
+		return nil, unauthorized("nonce must have 1 to 64 characters")
+	}
+
+	key, err := s.store.APIKeys().GetByID(ctx, req.KeyID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, unauthorized("unknown api key")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if key.RevokedAt != nil {
+		return nil, unauthorized("api key revoked")
+	}
+	want := SignRequest(key.Secret, req.Timestamp, req.Nonce, req.Method, req.Path, req.Body)
+	if !hmac.Equal([]byte(want), []byte(strings.ToLower(req.Signature))) {
+		return nil, unauthorized("invalid signature")
+	}
+
+	fresh, err := s.cache.ClaimRequestNonce(ctx, key.ID, req.Nonce, 2*RequestWindow)
+	if err != nil {
+		return nil, err
+	}
+	if !fresh {
+		return nil, unauthorized("nonce already used")
+	}
+	return &Principal{Address: key.Address, KeyID: key.ID, Scopes: key.Scopes}, nil
+}
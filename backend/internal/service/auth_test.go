@@ -0,0 +1,178 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/nexus-orderbook-dex/backend/internal/domain"
+	"github.com/nexus-orderbook-dex/backend/internal/repository/memory"
+	"github.com/nexus-orderbook-dex/backend/pkg/siwe"
+)
+
+const testAuthDomain = "localhost:3000"
+
+func newTestAuth() *AuthService {
+	return NewAuthService(memory.NewStore(), memory.NewAuthCache(), testAuthDomain, testChainID)
+}
+
+// signIn builds and signs a sign-in message for m with nonce.
+func (m *testMaker) signIn(t *testing.T, nonce string, edit func(*siwe.Message)) (string, string) {
+	t.Helper()
+	msg := &siwe.Message{
+		Domain:   testAuthDomain,
+		Address:  m.addr,
+		URI:      "http://" + testAuthDomain,
+		Version:  "1",
+		ChainID:  testChainID.Int64(),
+		Nonce:    nonce,
+		IssuedAt: time.Now(),
+	}
+	if edit != nil {
+		edit(msg)
+	}
+	text := msg.String()
+	sig, err := crypto.Sign(accounts.TextHash([]byte(text)), m.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig[64] += 27
+	return text, hexutil.Encode(sig)
+}
+
+func TestLogin_StartsASessionOncePerNonce(t *testing.T) {
+	ctx := context.Background()
+	auth := newTestAuth()
+	maker := newTestMaker(t)
+
+	nonce, err := auth.Nonce(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	message, sig := maker.signIn(t, nonce, nil)
+	token, session, err := auth.Login(ctx, message, sig)
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	if session.Address != maker.addr.Hex() {
+		t.Fatalf("session for %s, expected %s", session.Address, maker.addr.Hex())
+	}
+	p, err := auth.Session(ctx, token)
+	if err != nil || !p.Owns(maker.addr.Hex()) || !p.Allows(domain.ScopeCancel) {
+		t.Fatalf("expected a session with every scope, got %+v (%v)", p, err)
+	}
+
+	if _, _, err := auth.Login(ctx, message, sig); !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("expected a reused nonce to be rejected, got %v", err)
+	}
+
+	if err := auth.Logout(ctx, token); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := auth.Session(ctx, token); !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("expected the session to end, got %v", err)
+	}
+}
+
+func TestLogin_RejectsForeignOrForgedMessages(t *testing.T) {
+	ctx := context.Background()
+	auth := newTestAuth()
+	maker, other := newTestMaker(t), newTestMaker(t)
+
+	for name, edit := range map[string]func(*siwe.Message){
+		"domain":  func(m *siwe.Message) { m.Domain = "evil.example" },
+		"chain":   func(m *siwe.Message) { m.ChainID = 1 },
+		"expired": func(m *siwe.Message) { m.ExpirationTime = time.Now().Add(-time.Minute) },
+		"signer":  func(m *siwe.Message) { m.Address = other.addr },
+	} {
+		nonce, err := auth.Nonce(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		message, sig := maker.signIn(t, nonce, edit)
+		if _, _, err := auth.Login(ctx, message, sig); !errors.Is(err, ErrUnauthorized) {
+			t.Errorf("%s: expected the login to be rejected, got %v", name, err)
+		}
+	}
+
+	message, sig := maker.signIn(t, "neverissued", nil)
+	if _, _, err := auth.Login(ctx, message, sig); !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("expected an unknown nonce to be rejected, got %v", err)
+	}
+}
+
+func TestVerifyRequest_ChecksSignatureWindowAndReplay(t *testing.T) {
+	ctx := context.Background()
+	auth := newTestAuth()
+	maker := newTestMaker(t)
+
+	key, err := auth.CreateAPIKey(ctx, maker.addr.Hex(), "bot", []domain.Scope{domain.ScopeRead, domain.ScopeRead})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(key.Scopes) != 1 || key.Secret == "" {
+		t.Fatalf("unexpected key %+v", key)
+	}
+
+	body := []byte(`{"x":1}`)
+	signed := func(nonce string, at time.Time) SignedRequest {
+		ts := strconv.FormatInt(at.UnixMilli(), 10)
+		return SignedRequest{
+			KeyID:     key.ID,
+			Timestamp: ts,
+			Nonce:     nonce,
+			Signature: SignRequest(key.Secret, ts, nonce, "POST", "/api/orders", body),
+			Method:    "POST",
+			Path:      "/api/orders",
+			Body:      body,
+		}
+	}
+
+	p, err := auth.VerifyRequest(ctx, signed("n1", time.Now()))
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if p.KeyID != key.ID || !p.Allows(domain.ScopeRead) || p.Allows(domain.ScopeTrade) {
+		t.Fatalf("unexpected principal %+v", p)
+	}
+
+	tampered := signed("n2", time.Now())
+	tampered.Body = []byte(`{"x":2}`)
+	stale := signed("n3", time.Now().Add(-2*RequestWindow))
+	for name, req := range map[string]SignedRequest{
+		"replay":   signed("n1", time.Now()),
+		"tampered": tampered,
+		"stale":    stale,
+	} {
+		if _, err := auth.VerifyRequest(ctx, req); !errors.Is(err, ErrUnauthorized) {
+			t.Errorf("%s: expected the request to be rejected, got %v", name, err)
+		}
+	}
+
+	if err := auth.RevokeAPIKey(ctx, maker.addr.Hex(), key.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := auth.VerifyRequest(ctx, signed("n4", time.Now())); !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("expected a revoked key to be rejected, got %v", err)
+	}
+	keys, err := auth.ListAPIKeys(ctx, maker.addr.Hex())
+	if err != nil || len(keys) != 1 || keys[0].RevokedAt == nil {
+		t.Fatalf("expected the revoked key to be listed, got %v (%v)", keys, err)
+	}
+}
+
+func TestCreateAPIKey_RejectsUnknownScopes(t *testing.T) {
+	auth := newTestAuth()
+	maker := newTestMaker(t)
+	for _, scopes := range [][]domain.Scope{nil, {"admin"}} {
+		if _, err := auth.CreateAPIKey(context.Background(), maker.addr.Hex(), "", scopes); err == nil {
+			t.Errorf("expected scopes %v to be rejected", scopes)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package service
+
+import (
+	"sync"
+
+	ob "github.com/nexus-orderbook-dex/backend/internal/orderbook"
+)
+
+// PublishedBookView is the part of a book kept in the snapshot cache and
+// sent to WebSocket subscribers.
+var PublishedBookView = ob.BookView{Depth: 100}
+
+// CachedBookViews are the views recomputed after every command, so that
+// orderbook requests for them never wait on the pair's actor.
+var CachedBookViews = []ob.BookView{
+	{Depth: 20},
+	{Depth: 50},
+	{Depth: 100},
+	{Depth: 20, Group: 0.1},
+	{Depth: 20, Group: 1},
+	{Depth: 20, Group: 10},
+}
+
+// bookViews holds the cached views of the committed state of every book.
+type bookViews struct {
+	mu    sync.RWMutex
+	pairs map[string]map[ob.BookView]ob.Snapshot
+}
+
+func (v *bookViews) set(pair string, snapshot ob.Snapshot) {
+	views := make(map[ob.BookView]ob.Snapshot, len(CachedBookViews))
+	for _, view := range CachedBookViews {
+		views[view] = snapshot.View(view)
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.pairs[pair] = views
+}
+
+// get returns a copy of a cached view, so callers may keep or modify it.
+func (v *bookViews) get(pair string, view ob.BookView) (ob.Snapshot, bool) {
+	v.mu.RLock()
+	snapshot, ok := v.pairs[pair][view]
+	v.mu.RUnlock()
+	if !ok {
+		return ob.Snapshot{}, false
+	}
+	return snapshot.View(ob.BookView{}), true
+}
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/nexus-orderbook-dex/backend/internal/domain"
+	"github.com/nexus-orderbook-dex/backend/internal/repository"
+)
+
+// MaxCandles caps the bars returned by one candles query.
+const MaxCandles = 1000
+
+// recordCandles merges the trades of a command into the bars of every
+// interval and queues the updated live bars for WebSocket subscribers.
+// Trades arrive in execution order, so the last one closes each bar.
+func (s *OrderService) recordCandles(ctx context.Context, tx repository.Repositories, pair string, trades []*domain.Trade) error {
+	if len(trades) == 0 {
+		return nil
+	}
+	live := make([]*domain.Candle, 0, len(domain.CandleIntervals))
+	for _, interval := range domain.CandleIntervals {
+		var merged *domain.Candle
+		for _, trade := range trades {
+			var err error
+			if merged, err = tx.Candles().Merge(ctx, domain.NewCandle(interval, trade)); err != nil {
+				return fmt.Errorf("failed to update %s candle: %w", interval, err)
+			}
+		}
+		live = append(live, merged)
+	}
+	return publish(ctx, tx, repository.ChannelCandles, pair, map[string]interface{}{
+		"type":    "candles",
+		"pair":    pair,
+		"candles": live,
+	})
+}
+
+// GetCandles returns the bars of a pair opening in [from, to), oldest
+// first. A zero to means now and a zero from means MaxCandles bars before
+// to; longer ranges return their newest MaxCandles bars.
+func (s *OrderService) GetCandles(ctx context.Context, pair string, interval domain.CandleInterval, from, to time.Time) ([]*domain.Candle, error) {
+	if to.IsZero() {
+		to = time.Now()
+	}
+	if from.IsZero() {
+		from = to.Add(-MaxCandles * interval.Duration())
+	}
+	return s.store.Candles().List(ctx, pair, interval, interval.OpenTime(from), to, MaxCandles)
+}
+
+// BackfillCandles rebuilds from the trades table the bars that may be
+// missing or incomplete: for each interval, the newest stored bar and
+// everything after it, or every bar when none is stored yet. Bars are
+// written in the same transaction as their trades, so this only catches
+// up trades from before candles existed or from a restored database.
+func (s *OrderService) BackfillCandles(ctx context.Context) error {
+	for _, interval := range domain.CandleIntervals {
+		since, err := s.store.Candles().LatestOpenTime(ctx, interval)
+		if err != nil {
+			return fmt.Errorf("latest %s candle: %w", interval, err)
+		}
+		if err := s.store.Candles().Rebuild(ctx, interval, since); err != nil {
+			return fmt.Errorf("rebuild %s candles: %w", interval, err)
+		}
+		if since.IsZero() {
+			log.Printf("Backfilled %s candles from all trades", interval)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/nexus-orderbook-dex/backend/internal/domain"
+	"github.com/nexus-orderbook-dex/backend/internal/repository"
+)
+
+func TestSubmitOrder_RecordsCandles(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t, nil)
+	seller, buyer := newTestMaker(t), newTestMaker(t)
+
+	for _, price := range []int64{100, 103, 98} {
+		if _, _, err := env.svc.SubmitOrder(ctx, seller.order(t, domain.SideSell, price, 2)); err != nil {
+			t.Fatalf("submit sell: %v", err)
+		}
+		if _, _, err := env.svc.SubmitOrder(ctx, buyer.order(t, domain.SideBuy, price, 2)); err != nil {
+			t.Fatalf("submit buy: %v", err)
+		}
+	}
+
+	for _, interval := range domain.CandleIntervals {
+		candles, err := env.svc.GetCandles(ctx, testPair, interval, time.Time{}, time.Now().Add(time.Second))
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The trades may straddle a minute boundary; sum what is there
+		var count int
+		volume := new(big.Int)
+		for _, c := range candles {
+			count += c.TradeCount
+			volume.Add(volume, c.BaseVolume)
+		}
+		if count != 3 || volume.Int64() != 6 {
+			t.Fatalf("%s: expected 3 trades of volume 6, got %d of %s", interval, count, volume)
+		}
+		if interval == domain.Interval1d && len(candles) == 1 {
+			c := candles[0]
+			if c.Open != 100 || c.High != 103 || c.Low != 98 || c.Close != 98 {
+				t.Fatalf("unexpected OHLC %v %v %v %v", c.Open, c.High, c.Low, c.Close)
+			}
+		}
+	}
+
+	// Each matching command publishes its live bars
+	if _, err := env.relay.drain(ctx); err != nil {
+		t.Fatalf("drain: %v", err)
+	}
+	var updates int
+	for _, raw := range env.cache.Published(repository.ChannelCandles, testPair) {
+		var msg struct {
+			Type    string           `json:"type"`
+			Candles []*domain.Candle `json:"candles"`
+		}
+		if err := json.Unmarshal(raw, &msg); err != nil {
+			t.Fatal(err)
+		}
+		if msg.Type == "candles" {
+			updates++
+			if len(msg.Candles) != len(domain.CandleIntervals) {
+				t.Fatalf("expected a bar per interval, got %d", len(msg.Candles))
+			}
+		}
+	}
+	if updates != 3 {
+		t.Fatalf("expected 3 candle updates, got %d", updates)
+	}
+}
+
+func TestBackfillCandles_RebuildsFromTrades(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t, nil)
+
+	// Trades written without candles, as before the aggregator existed
+	for _, price := range []float64{10, 12, 11} {
+		trade := &domain.Trade{
+			Pair:        testPair,
+			BaseAmount:  big.NewInt(1),
+			QuoteAmount: big.NewInt(int64(price)),
+			Price:       price,
+		}
+		if err := env.store.Trades().Create(ctx, trade); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := env.svc.BackfillCandles(ctx); err != nil {
+			t.Fatalf("backfill: %v", err)
+		}
+	}
+
+	candles, err := env.svc.GetCandles(ctx, testPair, domain.Interval1d, time.Time{}, time.Now().Add(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var count int
+	for _, c := range candles {
+		count += c.TradeCount
+	}
+	if count != 3 {
+		t.Fatalf("expected 3 trades after two backfills, got %d in %d bars", count, len(candles))
+	}
+}
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"context"
+	"log"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/nexus-orderbook-dex/backend/internal/repository"
+)
+
+// ChainEvents handles the vault events of the indexer, pushing deposits and
+// withdrawals to the user channel of their owner as balance changes.
+type ChainEvents struct {
+	store repository.Store
+	relay *OutboxRelay
+}
+
+func NewChainEvents(store repository.Store, relay *OutboxRelay) *ChainEvents {
+	return &ChainEvents{store: store, relay: relay}
+}
+
+func (e *ChainEvents) OnDeposit(user, token common.Address, amount *big.Int) {
+	log.Printf("Event: Deposit user=%s token=%s amount=%s", user.Hex(), token.Hex(), amount.String())
+	e.publishBalance(user, token, amount, balanceDeposit)
+}
+
+func (e *ChainEvents) OnWithdraw(user, token common.Address, amount *big.Int) {
+	log.Printf("Event: Withdraw user=%s token=%s amount=%s", user.Hex(), token.Hex(), amount.String())
+	e.publishBalance(user, token, new(big.Int).Neg(amount), balanceWithdraw)
+}
+
+// OnTradeSettled only logs: fills are confirmed to users by the relay as
+// soon as the settlement transaction is mined.
+func (e *ChainEvents) OnTradeSettled(buyHash, sellHash common.Hash, buyer, seller common.Address, baseAmount, quoteAmount *big.Int) {
+	log.Printf("Event: TradeSettled buy=%s sell=%s", buyHash.Hex(), sellHash.Hex())
+}
+
+func (e *ChainEvents) publishBalance(user, token common.Address, delta *big.Int, reason string) {
+	ctx := context.Background()
+	err := e.store.InTx(ctx, func(tx repository.Repositories) error {
+		return publishUser(ctx, tx, user.Hex(), balanceMessage{
+			Type:   "balance",
+			Token:  token.Hex(),
+			Delta:  delta.String(),
+			Reason: reason,
+		})
+	})
+	if err != nil {
+		log.Printf("Failed to queue %s of %s: %v", reason, user.Hex(), err)
+		return
+	}
+	e.relay.Notify()
+}
@@ -0,0 +1,39 @@
+package service
+
+import (
+	"context"
+
+	ob "github.com/nexus-orderbook-dex/backend/internal/orderbook"
+	"github.com/nexus-orderbook-dex/backend/internal/repository"
+)
+
+// l2Message is one update of the aggregated book: the levels of the
+// published view whose amount changed, with their new amount and count. A
+// zero amount removes the level. Sequences are those of the l3 feed.
+type l2Message struct {
+	Type    string                      `json:"type"`
+	Pair    string                      `json:"pair"`
+	PrevSeq uint64                      `json:"prevSeq"`
+	Seq     uint64                      `json:"seq"`
+	Bids    []repository.PriceLevelData `json:"bids"`
+	Asks    []repository.PriceLevelData `json:"asks"`
+}
+
+// publishL2 queues the level changes of a command against the last view
+// published for the committed book, and returns the view to keep once the
+// command commits. Like publishL3 it publishes even without changes.
+func publishL2(ctx context.Context, tx repository.Repositories, a *pairActor, events []ob.Event) (ob.Snapshot, error) {
+	view := a.book.GetSnapshot().View(PublishedBookView)
+	bids, asks := bookLevels(ob.Snapshot{
+		Bids: ob.DiffLevels(a.l2.Bids, view.Bids),
+		Asks: ob.DiffLevels(a.l2.Asks, view.Asks),
+	})
+	return view, publish(ctx, tx, repository.ChannelOrderbook, a.pair, l2Message{
+		Type:    "l2",
+		Pair:    a.pair,
+		PrevSeq: events[0].Seq - 1,
+		Seq:     events[len(events)-1].Seq,
+		Bids:    bids,
+		Asks:    asks,
+	})
+}
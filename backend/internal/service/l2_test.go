@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/nexus-orderbook-dex/backend/internal/domain"
+	"github.com/nexus-orderbook-dex/backend/internal/repository"
+)
+
+// l2Replica is a client-side copy of the published book kept from a cached
+// snapshot and the level changes that follow it.
+type l2Replica struct {
+	seq        uint64
+	bids, asks map[float64]repository.PriceLevelData
+}
+
+func newL2Replica(snapshot repository.BookSnapshot) *l2Replica {
+	r := &l2Replica{
+		seq:  snapshot.Seq,
+		bids: make(map[float64]repository.PriceLevelData),
+		asks: make(map[float64]repository.PriceLevelData),
+	}
+	applyLevels(r.bids, snapshot.Bids)
+	applyLevels(r.asks, snapshot.Asks)
+	return r
+}
+
+func applyLevels(side map[float64]repository.PriceLevelData, levels []repository.PriceLevelData) {
+	for _, lvl := range levels {
+		if lvl.Amount == "0" {
+			delete(side, lvl.Price)
+		} else {
+			side[lvl.Price] = lvl
+		}
+	}
+}
+
+// apply folds in one update, reporting whether it was needed; updates the
+// snapshot already reflects are skipped.
+func (r *l2Replica) apply(t *testing.T, msg l2Message) bool {
+	t.Helper()
+	if msg.Seq <= r.seq {
+		return false
+	}
+	if msg.PrevSeq != r.seq {
+		t.Fatalf("gap in the feed: at %d, got update %d..%d", r.seq, msg.PrevSeq, msg.Seq)
+	}
+	applyLevels(r.bids, msg.Bids)
+	applyLevels(r.asks, msg.Asks)
+	r.seq = msg.Seq
+	return true
+}
+
+func (r *l2Replica) matches(t *testing.T, side map[float64]repository.PriceLevelData, levels []repository.PriceLevelData) {
+	t.Helper()
+	if len(side) != len(levels) {
+		t.Fatalf("replica has %d levels, snapshot %d", len(side), len(levels))
+	}
+	for _, lvl := range levels {
+		if side[lvl.Price] != lvl {
+			t.Fatalf("replica has %+v, snapshot %+v", side[lvl.Price], lvl)
+		}
+	}
+}
+
+func TestL2Feed_ResumesFromTheCachedSnapshot(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t, nil)
+	seller, buyer := newTestMaker(t), newTestMaker(t)
+
+	submit := func(m *testMaker, side domain.Side, price, base int64) *domain.Order {
+		t.Helper()
+		order, _, err := env.svc.SubmitOrder(ctx, m.order(t, side, price, base))
+		if err != nil {
+			t.Fatalf("submit: %v", err)
+		}
+		return order
+	}
+
+	ask := submit(seller, domain.SideSell, 100, 10)
+	submit(seller, domain.SideSell, 101, 5)
+
+	// A client joining here starts from the cached snapshot
+	joined, _ := env.cache.GetSnapshot(ctx, testPair)
+	if joined.Seq == 0 || len(joined.Asks) != 2 {
+		t.Fatalf("expected a sequenced snapshot with 2 asks, got %+v", joined)
+	}
+
+	bid := submit(buyer, domain.SideBuy, 95, 3)
+	submit(buyer, domain.SideBuy, 100, 4) // partially fills the ask, never rests
+	if err := env.svc.CancelOrder(ctx, bid.ID); err != nil {
+		t.Fatalf("cancel: %v", err)
+	}
+	if _, _, _, err := env.svc.ReplaceOrder(ctx, ask.ID, seller.order(t, domain.SideSell, 102, 6)); err != nil {
+		t.Fatalf("replace: %v", err)
+	}
+	submit(buyer, domain.SideBuy, 90, 2)
+
+	if _, err := env.relay.drain(ctx); err != nil {
+		t.Fatalf("drain: %v", err)
+	}
+	replica := newL2Replica(joined)
+	var skipped, applied int
+	for _, raw := range env.cache.Published(repository.ChannelOrderbook, testPair) {
+		var msg l2Message
+		if err := json.Unmarshal(raw, &msg); err != nil {
+			t.Fatal(err)
+		}
+		if msg.Type != "l2" {
+			continue
+		}
+		if replica.apply(t, msg) {
+			applied++
+		} else {
+			skipped++
+		}
+	}
+	if skipped != 2 || applied != 5 {
+		t.Fatalf("expected 2 skipped and 5 applied updates, got %d and %d", skipped, applied)
+	}
+
+	latest, _ := env.cache.GetSnapshot(ctx, testPair)
+	if latest.Seq != replica.seq {
+		t.Fatalf("replica at seq %d, snapshot at %d", replica.seq, latest.Seq)
+	}
+	replica.matches(t, replica.bids, latest.Bids)
+	replica.matches(t, replica.asks, latest.Asks)
+	if len(latest.Bids) != 1 || len(latest.Asks) != 2 {
+		t.Fatalf("expected 1 bid and 2 asks, got %+v", latest)
+	}
+}
@@ -0,0 +1,50 @@
+package service
+
+import (
+	"context"
+
+	ob "github.com/nexus-orderbook-dex/backend/internal/orderbook"
+	"github.com/nexus-orderbook-dex/backend/internal/repository"
+)
+
+// l3Message is one update of the order-by-order feed. Seq is the journal
+// sequence of the last event of the command and PrevSeq the sequence the
+// client's replica must be at to apply it; a mismatch means a missed update
+// and the client should fetch a new snapshot.
+type l3Message struct {
+	Type    string        `json:"type"`
+	Pair    string        `json:"pair"`
+	PrevSeq uint64        `json:"prevSeq"`
+	Seq     uint64        `json:"seq"`
+	Changes []ob.L3Change `json:"changes"`
+}
+
+// publishL3 queues the order-by-order changes of a command. Every command
+// that journals events publishes, even without changes, so that sequence
+// numbers reach clients without gaps.
+func publishL3(ctx context.Context, tx repository.Repositories, pair string, events []ob.Event, changes []ob.L3Change) error {
+	if changes == nil {
+		changes = []ob.L3Change{}
+	}
+	return publish(ctx, tx, repository.ChannelL3, pair, l3Message{
+		Type:    "l3",
+		Pair:    pair,
+		PrevSeq: events[0].Seq - 1,
+		Seq:     events[len(events)-1].Seq,
+		Changes: changes,
+	})
+}
+
+// GetL3Snapshot returns every resting order of a pair with the journal
+// sequence it reflects, empty for a pair without orders.
+func (s *OrderService) GetL3Snapshot(ctx context.Context, pair string) (ob.L3Snapshot, error) {
+	snapshot := ob.L3Snapshot{Pair: pair, Bids: []ob.L3Order{}, Asks: []ob.L3Order{}}
+	a, ok := s.lookupActor(pair)
+	if !ok {
+		return snapshot, nil
+	}
+	err := a.do(ctx, func() {
+		snapshot = a.book.L3Snapshot()
+	})
+	return snapshot, err
+}
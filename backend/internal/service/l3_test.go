@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/nexus-orderbook-dex/backend/internal/domain"
+	ob "github.com/nexus-orderbook-dex/backend/internal/orderbook"
+	"github.com/nexus-orderbook-dex/backend/internal/repository"
+)
+
+// l3Replica is a client-side copy of a book kept from the L3 feed.
+type l3Replica struct {
+	seq    uint64
+	orders map[string]ob.L3Order
+}
+
+func (r *l3Replica) apply(t *testing.T, msg l3Message) {
+	t.Helper()
+	if msg.Seq <= r.seq {
+		return
+	}
+	if msg.PrevSeq != r.seq {
+		t.Fatalf("gap in the feed: at %d, got update %d..%d", r.seq, msg.PrevSeq, msg.Seq)
+	}
+	for _, c := range msg.Changes {
+		switch c.Type {
+		case ob.L3Add:
+			if _, ok := r.orders[c.Order.ID]; ok {
+				t.Fatalf("add of known order %s", c.Order.ID)
+			}
+			r.orders[c.Order.ID] = c.Order
+		case ob.L3Modify:
+			prev, ok := r.orders[c.Order.ID]
+			if !ok || prev.Seq != c.Order.Seq {
+				t.Fatalf("modify of unknown order %s", c.Order.ID)
+			}
+			r.orders[c.Order.ID] = c.Order
+		case ob.L3Delete:
+			if _, ok := r.orders[c.Order.ID]; !ok {
+				t.Fatalf("delete of unknown order %s", c.Order.ID)
+			}
+			delete(r.orders, c.Order.ID)
+		}
+	}
+	r.seq = msg.Seq
+}
+
+func TestL3Feed_KeepsAnExactReplica(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t, nil)
+	seller, buyer := newTestMaker(t), newTestMaker(t)
+
+	submit := func(m *testMaker, side domain.Side, price, base int64) *domain.Order {
+		t.Helper()
+		order, _, err := env.svc.SubmitOrder(ctx, m.order(t, side, price, base))
+		if err != nil {
+			t.Fatalf("submit: %v", err)
+		}
+		return order
+	}
+
+	ask := submit(seller, domain.SideSell, 100, 10)
+	submit(seller, domain.SideSell, 101, 5)
+	bid := submit(buyer, domain.SideBuy, 95, 3)
+	submit(buyer, domain.SideBuy, 100, 4) // partially fills the ask, never rests
+	if err := env.svc.CancelOrder(ctx, bid.ID); err != nil {
+		t.Fatalf("cancel: %v", err)
+	}
+	if _, _, _, err := env.svc.ReplaceOrder(ctx, ask.ID, seller.order(t, domain.SideSell, 102, 6)); err != nil {
+		t.Fatalf("replace: %v", err)
+	}
+	submit(buyer, domain.SideBuy, 90, 2)
+
+	if _, err := env.relay.drain(ctx); err != nil {
+		t.Fatalf("drain: %v", err)
+	}
+	replica := &l3Replica{orders: make(map[string]ob.L3Order)}
+	var modified bool
+	for _, raw := range env.cache.Published(repository.ChannelL3, testPair) {
+		var msg l3Message
+		if err := json.Unmarshal(raw, &msg); err != nil {
+			t.Fatal(err)
+		}
+		for _, c := range msg.Changes {
+			modified = modified || c.Type == ob.L3Modify
+		}
+		replica.apply(t, msg)
+	}
+	if !modified {
+		t.Fatal("expected the partial fill to modify the ask")
+	}
+
+	snapshot, err := env.svc.GetL3Snapshot(ctx, testPair)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snapshot.Seq != replica.seq {
+		t.Fatalf("replica at seq %d, snapshot at %d", replica.seq, snapshot.Seq)
+	}
+	resting := append(snapshot.Bids, snapshot.Asks...)
+	if len(resting) != len(replica.orders) || len(resting) != 3 {
+		t.Fatalf("expected 3 resting orders in both, snapshot %d, replica %d", len(resting), len(replica.orders))
+	}
+	for _, o := range resting {
+		r, ok := replica.orders[o.ID]
+		if !ok || r.Price != o.Price || r.Size.Cmp(o.Size) != 0 || r.Seq != o.Seq || r.Side != o.Side {
+			t.Fatalf("replica has %+v, snapshot %+v", r, o)
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/nexus-orderbook-dex/backend/pkg/eip712"
+)
+
+// VerifyLogin checks that signature is the account's EIP-712 signature of a
+// login for nonce, under the same domain as orders.
+func (s *OrderService) VerifyLogin(account common.Address, nonce common.Hash, signature string) error {
+	sig, err := hexToBytes(signature)
+	if err != nil {
+		return fmt.Errorf("invalid signature hex: %w", err)
+	}
+	digest := eip712.HashTypedData(s.domain.Hash(), eip712.HashLogin(eip712.LoginData{Account: account, Nonce: nonce}))
+	signer, err := eip712.RecoverSigner(digest, sig)
+	if err != nil {
+		return fmt.Errorf("signature verification failed: %w", err)
+	}
+	if signer != account {
+		return fmt.Errorf("invalid signature: signer mismatch")
+	}
+	return nil
+}
@@ -0,0 +1,331 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nexus-orderbook-dex/backend/internal/domain"
+	ob "github.com/nexus-orderbook-dex/backend/internal/orderbook"
+	"github.com/nexus-orderbook-dex/backend/internal/repository"
+)
+
+// MarketError is a command refused by the state of its pair.
+type MarketError struct {
+	Pair  string
+	State domain.MarketState
+}
+
+func (e *MarketError) Error() string {
+	return fmt.Sprintf("%s is %s", e.Pair, strings.ReplaceAll(string(e.State), "_", "-"))
+}
+
+// CircuitBreaker halts a pair before a fill moves the price by more than
+// Move, as a fraction of any trade within the last Window. The halt ends on
+// its own after Halt, or only by hand if Halt is zero. The pair then
+// collects orders in a call auction for Auction before it uncrosses and
+// trades continuously again, or reopens at once if Auction is zero. A zero
+// Move turns the breaker off.
+type CircuitBreaker struct {
+	Move    float64
+	Window  time.Duration
+	Halt    time.Duration
+	Auction time.Duration
+}
+
+// SetCircuitBreaker configures the volatility halts of every pair. It must
+// be called before the service starts taking orders.
+func (s *OrderService) SetCircuitBreaker(cb CircuitBreaker) {
+	s.breaker = cb
+}
+
+// MarketView is the state of a pair and, during an auction, what it would
+// trade if it uncrossed now.
+type MarketView struct {
+	domain.MarketStatus
+	Indicative *ob.Indicative `json:"indicative,omitempty"`
+}
+
+// marketViewOf returns the market view of a book. It must run on the
+// book's actor.
+func marketViewOf(book *ob.OrderBook) MarketView {
+	view := MarketView{MarketStatus: book.Market()}
+	if view.State == domain.MarketAuction {
+		ind := book.Indicative()
+		view.Indicative = &ind
+	}
+	return view
+}
+
+// marketStates holds the committed market view of every pair.
+type marketStates struct {
+	mu    sync.RWMutex
+	pairs map[string]MarketView
+}
+
+func (m *marketStates) get(pair string) MarketView {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if view, ok := m.pairs[pair]; ok {
+		return view
+	}
+	return MarketView{MarketStatus: domain.MarketStatus{Pair: pair, State: domain.MarketOpen}}
+}
+
+func (m *marketStates) set(view MarketView) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pairs[view.Pair] = view
+}
+
+// GetMarketStatus returns the state of a pair, open for a pair without
+// orders.
+func (s *OrderService) GetMarketStatus(pair string) MarketView {
+	return s.markets.get(pair)
+}
+
+// GetMarketStatuses returns the state of every known pair, by pair.
+func (s *OrderService) GetMarketStatuses() []MarketView {
+	actors := s.allActors()
+	statuses := make([]MarketView, len(actors))
+	for i, a := range actors {
+		statuses[i] = s.markets.get(a.pair)
+	}
+	return statuses
+}
+
+// SetMarketState moves a pair to state by hand, ending any automatic halt
+// or auction. Opening the pair uncrosses the orders that rested crossed
+// meanwhile at a single price.
+func (s *OrderService) SetMarketState(ctx context.Context, pair string, state domain.MarketState, reason string) (domain.MarketStatus, error) {
+	if !state.Valid() {
+		return domain.MarketStatus{}, fmt.Errorf("invalid market state %q", state)
+	}
+	status := domain.MarketStatus{
+		Pair:   pair,
+		State:  state,
+		Source: domain.MarketSourceAdmin,
+		Reason: reason,
+		Since:  time.Now().UTC(),
+	}
+	a := s.actor(pair)
+	err := s.exec(ctx, a, func(tx repository.Repositories) error {
+		return s.changeMarket(ctx, tx, a, status)
+	})
+	return status, err
+}
+
+// changeMarket moves the actor's pair to status and persists the fills of
+// uncrossing it. A pair that takes orders again releases the conditional
+// orders held while it was halted. It must run on the actor, inside exec.
+func (s *OrderService) changeMarket(ctx context.Context, tx repository.Repositories, a *pairActor, status domain.MarketStatus) error {
+	matches := a.book.SetMarket(status)
+	if err := s.afterMatch(ctx, tx, a.pair, a.book, matches); err != nil {
+		return err
+	}
+	if !status.State.AcceptsOrders() {
+		return nil
+	}
+	return s.releaseCrossed(ctx, tx, a)
+}
+
+// releaseCrossed places the conditional orders whose trigger the last trade
+// price crossed while the pair did not take orders. It must run on the
+// actor, inside exec.
+func (s *OrderService) releaseCrossed(ctx context.Context, tx repository.Repositories, a *pairActor) error {
+	for _, held := range a.triggers.Crossed() {
+		if err := s.markTriggered(ctx, tx, held); err != nil {
+			return err
+		}
+		if _, err := s.placeOrder(ctx, tx, held); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// admit returns why orders of one maker cannot enter the actor's book: the
+// pair does not take orders or they break the maker's risk limits. Like
+// checkRisk it reports a rejection apart from errors.
+func (s *OrderService) admit(ctx context.Context, tx repository.Repositories, a *pairActor, orders []*domain.Order, replacedID string) (rejected error, err error) {
+	if state := a.book.Market().State; !state.AcceptsOrders() {
+		return &MarketError{Pair: a.pair, State: state}, nil
+	}
+	risk, err := s.checkRisk(ctx, tx, a, orders, replacedID)
+	if err != nil || risk == nil {
+		return nil, err
+	}
+	return risk, nil
+}
+
+// cancelRefused returns why orders cannot leave the actor's book, nil if
+// they can.
+func cancelRefused(a *pairActor) error {
+	if state := a.book.Market().State; !state.AcceptsCancels() {
+		return &MarketError{Pair: a.pair, State: state}
+	}
+	return nil
+}
+
+// pricePoint is a committed trade the circuit breaker measures moves from.
+type pricePoint struct {
+	at    time.Time
+	price float64
+}
+
+// priceBand returns the band the actor's next command may fill within: no
+// further than the circuit breaker's move from any trade of its window. The
+// matcher halts the pair at the first fill outside it. It is nil while the
+// breaker is off or the window has no trades. It must run on the actor.
+func (s *OrderService) priceBand(a *pairActor) *ob.PriceBand {
+	cb := s.breaker
+	if cb.Move <= 0 {
+		return nil
+	}
+	now := time.Now().UTC()
+	low, high := math.Inf(1), math.Inf(-1)
+	for _, p := range a.prices {
+		if !p.at.Before(now.Add(-cb.Window)) {
+			low, high = math.Min(low, p.price), math.Max(high, p.price)
+		}
+	}
+	if math.IsInf(low, 1) {
+		return nil
+	}
+
+	halt := domain.MarketStatus{
+		State:  domain.MarketHalted,
+		Source: domain.MarketSourceCircuitBreaker,
+		Since:  now,
+	}
+	if cb.Halt > 0 {
+		resumeAt := now.Add(cb.Halt)
+		halt.ResumeAt = &resumeAt
+	}
+	return &ob.PriceBand{Low: high * (1 - cb.Move), High: low * (1 + cb.Move), Halt: halt}
+}
+
+// recordPrices keeps the committed trades of a command for the circuit
+// breaker, dropping those older than its window. A change of state starts
+// the window again, so that a reopened pair is measured from its new
+// prices.
+func (s *OrderService) recordPrices(a *pairActor, trades []*domain.Trade, stateChanged bool) {
+	if s.breaker.Move <= 0 {
+		return
+	}
+	if stateChanged {
+		a.prices = nil
+	}
+	now := time.Now()
+	for _, t := range trades {
+		a.prices = append(a.prices, pricePoint{at: now, price: t.MarketPrice()})
+	}
+	cutoff := now.Add(-s.breaker.Window)
+	i := sort.Search(len(a.prices), func(i int) bool { return !a.prices[i].at.Before(cutoff) })
+	a.prices = a.prices[i:]
+}
+
+// publishMarket queues the market changes among a command's events and,
+// during an auction, a new indicative price and volume. It returns the
+// pair's market view after the command, nil if neither changed. It must
+// run on the actor, inside exec.
+func (s *OrderService) publishMarket(ctx context.Context, tx repository.Repositories, a *pairActor, events []ob.Event) (*MarketView, error) {
+	changed := false
+	for _, ev := range events {
+		if ev.Type != ob.EventMarketChanged {
+			continue
+		}
+		changed = true
+		if ev.Market.Source == domain.MarketSourceCircuitBreaker {
+			log.Printf("Circuit breaker halted %s: %s", ev.Pair, ev.Market.Reason)
+		}
+		err := publish(ctx, tx, repository.ChannelMarket, ev.Pair, map[string]interface{}{
+			"type":   "market_status",
+			"status": ev.Market,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	prev, next := s.markets.get(a.pair), marketViewOf(a.book)
+	if next.Indicative != nil && (changed || !sameIndicative(prev.Indicative, next.Indicative)) {
+		changed = true
+		err := publish(ctx, tx, repository.ChannelMarket, a.pair, map[string]interface{}{
+			"type":       "auction_indicative",
+			"pair":       a.pair,
+			"indicative": next.Indicative,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !changed {
+		return nil, nil
+	}
+	return &next, nil
+}
+
+func sameIndicative(a, b *ob.Indicative) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Price == b.Price && a.Volume.Cmp(b.Volume) == 0 && a.Surplus.Cmp(b.Surplus) == 0 && a.SurplusSide == b.SurplusSide
+}
+
+// RunMarketSchedule ends automatic halts once they are due, every interval
+// until ctx is done.
+func (s *OrderService) RunMarketSchedule(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.ResumeMarkets(ctx, now)
+		}
+	}
+}
+
+// ResumeMarkets moves on every pair whose automatic halt or auction ended
+// at or before now: a halt to a call auction, or straight back to open if
+// the breaker has none, and an auction to open, uncrossing it.
+func (s *OrderService) ResumeMarkets(ctx context.Context, now time.Time) {
+	for _, a := range s.allActors() {
+		if status := s.markets.get(a.pair); status.ResumeAt == nil || status.ResumeAt.After(now) {
+			continue
+		}
+		err := s.exec(ctx, a, func(tx repository.Repositories) error {
+			// An admin may have taken over the pair since
+			status := a.book.Market()
+			if status.ResumeAt == nil || status.ResumeAt.After(now) {
+				return nil
+			}
+			next := domain.MarketStatus{
+				State:  domain.MarketOpen,
+				Source: domain.MarketSourceSchedule,
+				Reason: "auction uncrossed",
+				Since:  now.UTC(),
+			}
+			if status.State == domain.MarketHalted {
+				next.Reason = "automatic halt ended"
+				if auction := s.breaker.Auction; auction > 0 {
+					uncrossAt := now.UTC().Add(auction)
+					next.State = domain.MarketAuction
+					next.Reason = "call auction after an automatic halt"
+					next.ResumeAt = &uncrossAt
+				}
+			}
+			return s.changeMarket(ctx, tx, a, next)
+		})
+		if err != nil {
+			log.Printf("Failed to resume %s: %v", a.pair, err)
+		}
+	}
+}
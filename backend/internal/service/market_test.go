@@ -0,0 +1,321 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nexus-orderbook-dex/backend/internal/domain"
+	ob "github.com/nexus-orderbook-dex/backend/internal/orderbook"
+	"github.com/nexus-orderbook-dex/backend/internal/repository"
+)
+
+func marketState(err error) domain.MarketState {
+	var market *MarketError
+	if errors.As(err, &market) {
+		return market.State
+	}
+	return ""
+}
+
+func TestMarket_CircuitBreakerHaltsThenResumes(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t, nil)
+	env.svc.SetCircuitBreaker(CircuitBreaker{Move: 0.1, Window: time.Minute, Halt: time.Minute})
+	seller, buyer := newTestMaker(t), newTestMaker(t)
+
+	if _, _, err := env.svc.SubmitOrder(ctx, seller.order(t, domain.SideSell, 100, 1)); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := env.svc.SubmitOrder(ctx, buyer.order(t, domain.SideBuy, 100, 1)); err != nil {
+		t.Fatal(err)
+	}
+	// A fat finger sweeps a thin book: only the fills within 10% of the
+	// trade at 100 are made, then the pair halts with the rest of the bid
+	// resting
+	var asks []*domain.Order
+	for _, price := range []int64{102, 108, 115, 125} {
+		ask, _, err := env.svc.SubmitOrder(ctx, seller.order(t, domain.SideSell, price, 1))
+		if err != nil {
+			t.Fatal(err)
+		}
+		asks = append(asks, ask)
+	}
+	bid, matches, err := env.svc.SubmitOrder(ctx, buyer.order(t, domain.SideBuy, 130, 4))
+	if err != nil || len(matches) != 2 {
+		t.Fatalf("expected the sweep to fill twice, got %d (%v)", len(matches), err)
+	}
+	for _, m := range matches {
+		if m.Price > 110 {
+			t.Fatalf("expected fills within the band, got one at %g", m.Price)
+		}
+	}
+	stored, err := env.store.Orders().GetByID(ctx, bid.ID)
+	if err != nil || stored.Status != domain.OrderStatusPartiallyFilled || stored.FilledBase.Int64() != 2 {
+		t.Fatalf("expected the bid to rest half filled, got %+v (%v)", stored, err)
+	}
+	for _, ask := range asks[2:] {
+		if stored, err := env.store.Orders().GetByID(ctx, ask.ID); err != nil || stored.Status != domain.OrderStatusOpen {
+			t.Fatalf("expected the ask out of the band untouched, got %+v (%v)", stored, err)
+		}
+	}
+	resting := asks[2]
+
+	status := env.svc.GetMarketStatus(testPair)
+	if status.State != domain.MarketHalted || status.Source != domain.MarketSourceCircuitBreaker || status.ResumeAt == nil {
+		t.Fatalf("expected an automatic halt, got %+v", status)
+	}
+	if _, _, err := env.svc.SubmitOrder(ctx, buyer.order(t, domain.SideBuy, 100, 1)); marketState(err) != domain.MarketHalted {
+		t.Fatalf("expected the halt to refuse orders, got %v", err)
+	}
+	if err := env.svc.CancelOrder(ctx, resting.ID); marketState(err) != domain.MarketHalted {
+		t.Fatalf("expected the halt to refuse cancels, got %v", err)
+	}
+
+	if _, err := env.relay.drain(ctx); err != nil {
+		t.Fatal(err)
+	}
+	published := env.cache.Published(repository.ChannelMarket, testPair)
+	if len(published) != 1 {
+		t.Fatalf("expected 1 market message, got %d", len(published))
+	}
+	var msg struct {
+		Type   string              `json:"type"`
+		Status domain.MarketStatus `json:"status"`
+	}
+	if err := json.Unmarshal(published[0], &msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg.Type != "market_status" || msg.Status.State != domain.MarketHalted {
+		t.Fatalf("unexpected market message %s", published[0])
+	}
+
+	// Not due yet
+	env.svc.ResumeMarkets(ctx, time.Now())
+	if state := env.svc.GetMarketStatus(testPair).State; state != domain.MarketHalted {
+		t.Fatalf("expected the pair still halted, got %s", state)
+	}
+	env.svc.ResumeMarkets(ctx, status.ResumeAt.Add(time.Second))
+	if status := env.svc.GetMarketStatus(testPair); status.State != domain.MarketOpen || status.Source != domain.MarketSourceSchedule {
+		t.Fatalf("expected the pair reopened, got %+v", status)
+	}
+	// Reopening uncrosses the bid with the asks it swept past
+	if stored, err := env.store.Orders().GetByID(ctx, bid.ID); err != nil || stored.Status != domain.OrderStatusFilled {
+		t.Fatalf("expected the bid filled on reopening, got %+v (%v)", stored, err)
+	}
+	// The window starts again from the reopened prices
+	if _, _, err := env.svc.SubmitOrder(ctx, seller.order(t, domain.SideSell, 120, 1)); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := env.svc.SubmitOrder(ctx, buyer.order(t, domain.SideBuy, 120, 1)); err != nil {
+		t.Fatal(err)
+	}
+	if state := env.svc.GetMarketStatus(testPair).State; state != domain.MarketOpen {
+		t.Fatalf("expected the pair to stay open, got %s", state)
+	}
+}
+
+func TestMarket_HeldStopSurvivesRestart(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t, nil)
+	env.svc.SetCircuitBreaker(CircuitBreaker{Move: 0.1, Window: time.Minute, Halt: time.Minute})
+	seller, buyer, stopper := newTestMaker(t), newTestMaker(t), newTestMaker(t)
+
+	if _, _, err := env.svc.SubmitOrder(ctx, seller.order(t, domain.SideSell, 100, 1)); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := env.svc.SubmitOrder(ctx, buyer.order(t, domain.SideBuy, 100, 1)); err != nil {
+		t.Fatal(err)
+	}
+	sub := stopper.order(t, domain.SideBuy, 130, 1)
+	sub.Type, sub.TriggerPrice = domain.OrderTypeStopLoss, 105
+	stop, _, err := env.svc.SubmitOrder(ctx, sub)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The sweep trades at 108, crossing the stop, then halts the pair
+	for _, price := range []int64{102, 108, 115} {
+		if _, _, err := env.svc.SubmitOrder(ctx, seller.order(t, domain.SideSell, price, 1)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, _, err := env.svc.SubmitOrder(ctx, buyer.order(t, domain.SideBuy, 130, 3)); err != nil {
+		t.Fatal(err)
+	}
+	if state := env.svc.GetMarketStatus(testPair).State; state != domain.MarketHalted {
+		t.Fatalf("expected the pair halted, got %s", state)
+	}
+	if stored, err := env.store.Orders().GetByID(ctx, stop.ID); err != nil || stored.Status != domain.OrderStatusPending {
+		t.Fatalf("expected the stop held pending, got %+v (%v)", stored, err)
+	}
+
+	// A restarted service holds it again and places it when the pair reopens
+	restarted := newTestEnv(t, env.store)
+	if err := restarted.svc.RestoreBooks(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := restarted.svc.SetMarketState(ctx, testPair, domain.MarketOpen, ""); err != nil {
+		t.Fatal(err)
+	}
+	stored, err := restarted.store.Orders().GetByID(ctx, stop.ID)
+	if err != nil || stored.Status != domain.OrderStatusOpen || stored.TriggeredAt == nil {
+		t.Fatalf("expected the stop placed on reopening, got %+v (%v)", stored, err)
+	}
+	if bid, ok := restarted.svc.actor(testPair).book.BestBid(); !ok || bid != 130 {
+		t.Fatalf("expected the stop resting at 130, got %g", bid)
+	}
+}
+
+func TestMarket_AdminStatesSurviveRestart(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t, nil)
+	seller, buyer := newTestMaker(t), newTestMaker(t)
+
+	ask, _, err := env.svc.SubmitOrder(ctx, seller.order(t, domain.SideSell, 100, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.svc.SetMarketState(ctx, testPair, domain.MarketCancelOnly, "maintenance"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := env.svc.SubmitOrder(ctx, buyer.order(t, domain.SideBuy, 100, 1)); marketState(err) != domain.MarketCancelOnly {
+		t.Fatalf("expected cancel-only to refuse orders, got %v", err)
+	}
+	if err := env.svc.CancelOrder(ctx, ask.ID); err != nil {
+		t.Fatalf("expected cancel-only to take cancels: %v", err)
+	}
+
+	// Orders rest crossed during an auction and match when the pair opens
+	if _, err := env.svc.SetMarketState(ctx, testPair, domain.MarketAuction, ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, matches, err := env.svc.SubmitOrder(ctx, seller.order(t, domain.SideSell, 100, 1)); err != nil || len(matches) != 0 {
+		t.Fatalf("expected the ask to rest, got %d fills (%v)", len(matches), err)
+	}
+	if _, matches, err := env.svc.SubmitOrder(ctx, buyer.order(t, domain.SideBuy, 110, 1)); err != nil || len(matches) != 0 {
+		t.Fatalf("expected the bid to rest, got %d fills (%v)", len(matches), err)
+	}
+
+	restarted := newTestEnv(t, env.store)
+	if err := restarted.svc.RestoreBooks(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if status := restarted.svc.GetMarketStatus(testPair); status.State != domain.MarketAuction || status.Source != domain.MarketSourceAdmin {
+		t.Fatalf("expected the auction restored, got %+v", status)
+	}
+	if _, err := restarted.svc.SetMarketState(ctx, testPair, domain.MarketOpen, ""); err != nil {
+		t.Fatal(err)
+	}
+	trades, err := restarted.store.Trades().GetByPair(ctx, testPair, 10)
+	if err != nil || len(trades) != 1 {
+		t.Fatalf("expected the crossed orders to trade on open, got %d (%v)", len(trades), err)
+	}
+}
+
+func TestMarket_HaltReopensThroughACallAuction(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t, nil)
+	env.svc.SetCircuitBreaker(CircuitBreaker{Move: 0.1, Window: time.Minute, Halt: time.Minute, Auction: time.Minute})
+	seller, buyer := newTestMaker(t), newTestMaker(t)
+
+	for _, price := range []int64{100, 120} {
+		if _, _, err := env.svc.SubmitOrder(ctx, seller.order(t, domain.SideSell, price, 1)); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := env.svc.SubmitOrder(ctx, buyer.order(t, domain.SideBuy, price, 1)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	halt := env.svc.GetMarketStatus(testPair)
+	if halt.State != domain.MarketHalted {
+		t.Fatalf("expected a halt, got %+v", halt)
+	}
+
+	env.svc.ResumeMarkets(ctx, halt.ResumeAt.Add(time.Second))
+	auction := env.svc.GetMarketStatus(testPair)
+	if auction.State != domain.MarketAuction || auction.ResumeAt == nil || auction.Indicative == nil {
+		t.Fatalf("expected a call auction, got %+v", auction)
+	}
+
+	// The bid and ask the halt left crossed at 120 join the auction. 115
+	// and 118 both trade 3 and leave nothing over: the price closest to the
+	// last trade at 100 wins
+	for _, o := range []struct {
+		m           *testMaker
+		side        domain.Side
+		price, base int64
+	}{
+		{seller, domain.SideSell, 100, 1},
+		{seller, domain.SideSell, 102, 1},
+		{seller, domain.SideSell, 115, 1},
+		{buyer, domain.SideBuy, 118, 2},
+		{buyer, domain.SideBuy, 105, 1},
+	} {
+		if _, matches, err := env.svc.SubmitOrder(ctx, o.m.order(t, o.side, o.price, o.base)); err != nil || len(matches) != 0 {
+			t.Fatalf("expected the order to rest, got %d fills (%v)", len(matches), err)
+		}
+	}
+	ind := env.svc.GetMarketStatus(testPair).Indicative
+	if ind == nil || ind.Price != 115 || ind.Volume.Int64() != 3 || ind.Surplus.Int64() != 0 {
+		t.Fatalf("unexpected indicative %+v", ind)
+	}
+
+	if _, err := env.relay.drain(ctx); err != nil {
+		t.Fatal(err)
+	}
+	var last struct {
+		Type       string        `json:"type"`
+		Indicative ob.Indicative `json:"indicative"`
+	}
+	for _, raw := range env.cache.Published(repository.ChannelMarket, testPair) {
+		if err := json.Unmarshal(raw, &last); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if last.Type != "auction_indicative" || last.Indicative.Price != 115 {
+		t.Fatalf("expected the indicative price published last, got %+v", last)
+	}
+
+	env.svc.ResumeMarkets(ctx, auction.ResumeAt.Add(time.Second))
+	if status := env.svc.GetMarketStatus(testPair); status.State != domain.MarketOpen || status.Indicative != nil {
+		t.Fatalf("expected the pair open, got %+v", status)
+	}
+	trades, err := env.store.Trades().GetByPair(ctx, testPair, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The three fills of the uncross and the trade before the halt
+	if len(trades) != 4 {
+		t.Fatalf("expected 4 trades, got %d", len(trades))
+	}
+	// Each fill executes at its seller's price, at or below the clearing
+	// price, and reports the price its quote was paid at beside the
+	// clearing price
+	for _, trade := range trades[:3] {
+		if trade.Price > 115 || trade.QuoteAmount.Int64() != trade.BaseAmount.Int64()*int64(trade.Price) || trade.ClearingPrice != 115 {
+			t.Fatalf("unexpected uncross fill of %s for %s at %g (clearing %g)", trade.BaseAmount, trade.QuoteAmount, trade.Price, trade.ClearingPrice)
+		}
+	}
+	if trades[3].ClearingPrice != 0 {
+		t.Fatalf("expected no clearing price on a continuous fill, got %g", trades[3].ClearingPrice)
+	}
+	// The market is marked at the clearing price alone
+	if tk, ok := env.svc.GetTicker(testPair); !ok || tk.LastPrice != 115 {
+		t.Fatalf("expected the ticker at the clearing price, got %+v", tk)
+	}
+	candles, err := env.svc.GetCandles(ctx, testPair, domain.Interval1m, time.Time{}, time.Time{})
+	if err != nil || len(candles) == 0 {
+		t.Fatalf("expected candles, got %d (%v)", len(candles), err)
+	}
+	if c := candles[len(candles)-1]; c.Close != 115 || c.High != 115 || c.TradeCount < 3 {
+		t.Fatalf("expected the bar closed at the clearing price, got %+v", c)
+	}
+	// The auction started the breaker's window again, so the jump from
+	// the trade before the halt does not count
+	if state := env.svc.GetMarketStatus(testPair).State; state != domain.MarketOpen {
+		t.Fatalf("expected the pair to stay open, got %s", state)
+	}
+}
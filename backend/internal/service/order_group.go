@@ -0,0 +1,227 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+
+	"github.com/nexus-orderbook-dex/backend/internal/domain"
+	ob "github.com/nexus-orderbook-dex/backend/internal/orderbook"
+	"github.com/nexus-orderbook-dex/backend/internal/repository"
+)
+
+// SubmitGroup verifies and persists every leg of an OCO or bracket group,
+// registers the group with the engine and places its active legs.
+func (s *OrderService) SubmitGroup(ctx context.Context, sub domain.GroupSubmission) (*domain.OrderGroup, []*domain.Order, error) {
+	if sub.Type != domain.GroupTypeOCO && sub.Type != domain.GroupTypeBracket {
+		return nil, nil, fmt.Errorf("invalid group type: %s", sub.Type)
+	}
+	if len(sub.Legs) < 2 {
+		return nil, nil, fmt.Errorf("a group needs at least two legs")
+	}
+
+	legs := make([]*domain.Order, 0, len(sub.Legs))
+	for i, legSub := range sub.Legs {
+		leg, err := s.newOrder(legSub)
+		if err != nil {
+			return nil, nil, fmt.Errorf("leg %d: %w", i, err)
+		}
+		legs = append(legs, leg)
+	}
+
+	entry := legs[0]
+	for i, leg := range legs {
+		if !strings.EqualFold(leg.Maker, entry.Maker) || leg.Pair != entry.Pair {
+			return nil, nil, fmt.Errorf("leg %d: all legs must share maker and pair", i)
+		}
+		switch {
+		case sub.Type == domain.GroupTypeOCO:
+			leg.GroupRole = domain.GroupRoleLeg
+		case i == 0:
+			leg.GroupRole = domain.GroupRoleEntry
+		default:
+			if leg.Side == entry.Side {
+				return nil, nil, fmt.Errorf("leg %d: bracket exits must be on the opposite side of the entry", i)
+			}
+			// Exits stay dormant until the entry fills
+			leg.GroupRole = domain.GroupRoleExit
+			leg.Status = domain.OrderStatusPending
+			leg.BaseCap = big.NewInt(0)
+		}
+	}
+
+	group := &domain.OrderGroup{
+		Type:   sub.Type,
+		Pair:   entry.Pair,
+		Maker:  entry.Maker,
+		Status: domain.GroupStatusActive,
+	}
+
+	// The group, its legs and their trades commit together; from here on
+	// the group belongs to the pair's actor and callers get copies
+	var rejected error
+	a := s.actor(group.Pair)
+	err := s.exec(ctx, a, func(tx repository.Repositories) error {
+		var err error
+		if rejected, err = s.admit(ctx, tx, a, legs, ""); err != nil || rejected != nil {
+			return err
+		}
+		if err := tx.Groups().Create(ctx, group); err != nil {
+			return fmt.Errorf("failed to persist group: %w", err)
+		}
+		for _, leg := range legs {
+			leg.GroupID = group.ID
+			if err := tx.Orders().Create(ctx, leg); err != nil {
+				return fmt.Errorf("failed to persist group leg: %w", err)
+			}
+			if err := publishOrder(ctx, tx, leg, orderAccepted); err != nil {
+				return err
+			}
+		}
+
+		a.book.AddGroup(group, legs)
+		for _, leg := range legs {
+			if leg.GroupRole == domain.GroupRoleExit {
+				continue
+			}
+			if _, err := s.enterOrder(ctx, tx, leg); err != nil {
+				return err
+			}
+		}
+
+		g := *group
+		group = &g
+		for i, leg := range legs {
+			legs[i] = leg.Clone()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if rejected != nil {
+		return nil, nil, rejected
+	}
+	return group, legs, nil
+}
+
+// GetGroup returns a group and its legs.
+func (s *OrderService) GetGroup(ctx context.Context, groupID string) (*domain.OrderGroup, []*domain.Order, error) {
+	group, err := s.store.Groups().GetByID(ctx, groupID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("group not found: %w", err)
+	}
+	legs, err := s.store.Orders().GetByGroup(ctx, groupID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return group, legs, nil
+}
+
+// CancelGroup cancels every live leg of a group.
+func (s *OrderService) CancelGroup(ctx context.Context, groupID string) error {
+	group, err := s.store.Groups().GetByID(ctx, groupID)
+	if err != nil {
+		return fmt.Errorf("group not found: %w", err)
+	}
+
+	a := s.actor(group.Pair)
+	var cancelled bool
+	var rejected error
+	err = s.exec(ctx, a, func(tx repository.Repositories) error {
+		if rejected = cancelRefused(a); rejected != nil {
+			return nil
+		}
+		if cancelled = a.book.CancelGroup(groupID); !cancelled {
+			return nil
+		}
+		return s.handleGroupEvents(ctx, tx, group.Pair, a.book.TakeGroupEvents())
+	})
+	if err != nil {
+		return err
+	}
+	if rejected != nil {
+		return rejected
+	}
+	if !cancelled {
+		return fmt.Errorf("group %s is not active", groupID)
+	}
+	return nil
+}
+
+// handleGroupEvents persists and publishes the sibling changes the engine
+// made, and places bracket exits the engine activated.
+func (s *OrderService) handleGroupEvents(ctx context.Context, tx repository.Repositories, pair string, events []ob.GroupEvent) error {
+	for _, ev := range events {
+		if ev.Type == ob.GroupEventCompleted {
+			if err := tx.Groups().UpdateStatus(ctx, ev.Group.ID, ev.Group.Status); err != nil {
+				return fmt.Errorf("failed to update group %s: %w", ev.Group.ID, err)
+			}
+		} else {
+			leg := ev.Order
+			if ev.Type == ob.GroupEventSuspended || ev.Type == ob.GroupEventCancelled {
+				s.actor(pair).triggers.Remove(leg.ID)
+			}
+			if err := tx.Orders().UpdateGroupLeg(ctx, leg.ID, leg.Status, leg.FilledBase.String(), leg.BaseCap); err != nil {
+				return fmt.Errorf("failed to update group leg %s: %w", leg.ID, err)
+			}
+			if err := publishOrder(ctx, tx, leg, orderEventOf(leg.Status)); err != nil {
+				return err
+			}
+		}
+
+		if err := publish(ctx, tx, repository.ChannelOrderbook, pair, groupEventMessage(ev)); err != nil {
+			return err
+		}
+
+		if ev.Type == ob.GroupEventActivated {
+			if _, err := s.enterOrder(ctx, tx, ev.Order); err != nil {
+				return fmt.Errorf("failed to place group leg %s: %w", ev.Order.ID, err)
+			}
+		}
+	}
+	return nil
+}
+
+// loadActiveGroups registers the active groups of a pair with its book and
+// returns their legs by order ID.
+func (s *OrderService) loadActiveGroups(ctx context.Context, pair string, book *ob.OrderBook) (map[string]*domain.Order, error) {
+	groups, err := s.store.Groups().GetActiveByPair(ctx, pair)
+	if err != nil {
+		return nil, err
+	}
+	legs := make(map[string]*domain.Order)
+	for _, group := range groups {
+		groupLegs, err := s.store.Orders().GetByGroup(ctx, group.ID)
+		if err != nil {
+			return nil, err
+		}
+		book.AddGroup(group, groupLegs)
+		for _, leg := range groupLegs {
+			legs[leg.ID] = leg
+		}
+	}
+	log.Printf("Loaded %d active order groups for %s", len(groups), pair)
+	return legs, nil
+}
+
+func groupEventMessage(ev ob.GroupEvent) map[string]interface{} {
+	msg := map[string]interface{}{
+		"type":        "group",
+		"event":       ev.Type,
+		"groupId":     ev.Group.ID,
+		"groupType":   ev.Group.Type,
+		"groupStatus": ev.Group.Status,
+	}
+	if ev.Order != nil {
+		msg["orderId"] = ev.Order.ID
+		msg["status"] = ev.Order.Status
+		msg["filledBase"] = ev.Order.FilledBase.String()
+		if ev.Order.BaseCap != nil {
+			msg["baseCap"] = ev.Order.BaseCap.String()
+		}
+	}
+	return msg
+}
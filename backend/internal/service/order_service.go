@@ -3,74 +3,262 @@ package service
 import (
 	"context"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"log"
 	"math/big"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/nexus-orderbook-dex/backend/internal/blockchain"
+	"github.com/google/uuid"
 	"github.com/nexus-orderbook-dex/backend/internal/domain"
 	ob "github.com/nexus-orderbook-dex/backend/internal/orderbook"
-	"github.com/nexus-orderbook-dex/backend/internal/repository/postgres"
-	redisRepo "github.com/nexus-orderbook-dex/backend/internal/repository/redis"
+	"github.com/nexus-orderbook-dex/backend/internal/repository"
 	"github.com/nexus-orderbook-dex/backend/pkg/eip712"
 )
 
 type OrderService struct {
-	orderRepo  *postgres.OrderRepo
-	tradeRepo  *postgres.TradeRepo
-	cache      *redisRepo.OrderbookCache
-	orderbooks map[string]*ob.OrderBook
-	domain     eip712.DomainSeparator
-	settleCh   chan blockchain.SettleJob
+	store       repository.Store
+	cache       repository.SnapshotCache
+	relay       *OutboxRelay
+	domain      eip712.DomainSeparator
+	snapshotDir string
+	riskLimits  domain.RiskLimits // of addresses without an override
+	breaker     CircuitBreaker
+
+	mu      sync.RWMutex
+	actors  map[string]*pairActor // pair -> single writer of its book
+	tickers *tickerStats
+	views   bookViews
+	markets marketStates
 }
 
 func NewOrderService(
-	orderRepo *postgres.OrderRepo,
-	tradeRepo *postgres.TradeRepo,
-	cache *redisRepo.OrderbookCache,
+	store repository.Store,
+	cache repository.SnapshotCache,
+	relay *OutboxRelay,
 	chainID *big.Int,
 	contractAddr common.Address,
-	settleCh chan blockchain.SettleJob,
+	snapshotDir string,
 ) *OrderService {
 	return &OrderService{
-		orderRepo:  orderRepo,
-		tradeRepo:  tradeRepo,
-		cache:      cache,
-		orderbooks: make(map[string]*ob.OrderBook),
-		domain:     eip712.NewDomainSeparator(chainID, contractAddr),
-		settleCh:   settleCh,
+		store:       store,
+		cache:       cache,
+		relay:       relay,
+		actors:      make(map[string]*pairActor),
+		tickers:     newTickerStats(),
+		views:       bookViews{pairs: make(map[string]map[ob.BookView]ob.Snapshot)},
+		markets:     marketStates{pairs: make(map[string]MarketView)},
+		domain:      eip712.NewDomainSeparator(chainID, contractAddr),
+		snapshotDir: snapshotDir,
 	}
 }
 
-func (s *OrderService) GetOrCreateOrderBook(pair string) *ob.OrderBook {
-	if book, ok := s.orderbooks[pair]; ok {
-		return book
+func (s *OrderService) SubmitOrder(ctx context.Context, sub domain.OrderSubmission) (*domain.Order, []ob.MatchResult, error) {
+	order, err := s.newOrder(sub)
+	if err != nil {
+		return nil, nil, err
 	}
-	book := ob.NewOrderBook(pair)
-	s.orderbooks[pair] = book
-	return book
+
+	// The order row, its trades and the journal commit together; from here
+	// on the order belongs to the pair's actor and callers get copies
+	var matches []ob.MatchResult
+	var rejected error
+	a := s.actor(order.Pair)
+	err = s.exec(ctx, a, func(tx repository.Repositories) error {
+		var err error
+		if rejected, err = s.admit(ctx, tx, a, []*domain.Order{order}, ""); err != nil || rejected != nil {
+			return err
+		}
+		if err := tx.Orders().Create(ctx, order); err != nil {
+			return fmt.Errorf("failed to persist order: %w", err)
+		}
+		if err := publishOrder(ctx, tx, order, orderAccepted); err != nil {
+			return err
+		}
+		m, err := s.enterOrder(ctx, tx, order)
+		if err != nil {
+			return err
+		}
+		order = order.Clone()
+		matches = cloneMatches(m)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if rejected != nil {
+		return nil, nil, rejected
+	}
+	return order, matches, nil
 }
 
-func (s *OrderService) SubmitOrder(ctx context.Context, sub domain.OrderSubmission) (*domain.Order, []ob.MatchResult, error) {
+// exec runs one engine command on a pair's actor as a single unit of work.
+// fn changes the book and writes the rows and outbox messages that go with
+// it through tx; the events the book emitted are journalled and an
+// orderbook update is queued in the same transaction. If anything fails the
+// transaction rolls back and the book is reloaded from the committed state,
+// so a crash or a database error never leaves the book and the database
+// apart. Once queued, the command runs to completion even if ctx is done.
+func (s *OrderService) exec(ctx context.Context, a *pairActor, fn func(tx repository.Repositories) error) error {
+	var execErr error
+	err := a.do(ctx, func() {
+		ctx := context.WithoutCancel(ctx)
+		if a.stale {
+			if err := s.reload(ctx, a); err != nil {
+				execErr = fmt.Errorf("orderbook %s is unavailable: %w", a.pair, err)
+				return
+			}
+		}
+
+		a.trades = nil
+		a.book.SetPriceBand(s.priceBand(a))
+		var ticker *pairTicker
+		var l2 *ob.Snapshot
+		var l3 []ob.L3Change
+		var market *MarketView
+		execErr = s.store.InTx(ctx, func(tx repository.Repositories) error {
+			if err := fn(tx); err != nil {
+				return err
+			}
+			events := a.book.TakeEvents()
+			if len(events) == 0 {
+				return nil
+			}
+			if err := tx.Events().Append(ctx, events); err != nil {
+				return fmt.Errorf("failed to journal engine events: %w", err)
+			}
+			view, err := publishL2(ctx, tx, a, events)
+			if err != nil {
+				return err
+			}
+			l2 = &view
+			l3 = a.l3.Changes(a.book, a.book.TakeTouchedOrders())
+			if err := publishL3(ctx, tx, a.pair, events, l3); err != nil {
+				return err
+			}
+			if market, err = s.publishMarket(ctx, tx, a, events); err != nil {
+				return err
+			}
+			ticker, err = s.nextTicker(ctx, tx, a)
+			return err
+		})
+		if execErr != nil {
+			if err := s.reload(ctx, a); err != nil {
+				log.Printf("Failed to reload %s after a rolled back command: %v", a.pair, err)
+			}
+			return
+		}
+
+		if l2 != nil {
+			a.l2 = *l2
+		}
+		s.cacheBook(ctx, a.pair, a.book)
+		a.l3.Apply(l3)
+		if ticker != nil {
+			s.tickers.set(ticker)
+		}
+		stateChanged := false
+		if market != nil {
+			stateChanged = market.State != s.markets.get(a.pair).State
+			s.markets.set(*market)
+		}
+		s.recordPrices(a, a.trades, stateChanged)
+		s.relay.Notify()
+	})
+	if err != nil {
+		return err
+	}
+	return execErr
+}
+
+// reload replaces the book and trigger store of a pair with their last
+// committed state. A pair that cannot be reloaded is marked stale and
+// refuses commands until a later reload succeeds.
+func (s *OrderService) reload(ctx context.Context, a *pairActor) error {
+	a.stale = true
+	book, err := s.loadBook(ctx, a.pair)
+	if err != nil {
+		return err
+	}
+	a.book = book
+	a.triggers = ob.NewTriggerStore(a.pair)
+	if _, err := s.loadPendingOrders(ctx, a); err != nil {
+		return err
+	}
+	a.l2 = book.GetSnapshot().View(PublishedBookView)
+	a.l3 = ob.NewL3Tracker(book)
+	a.stale = false
+	s.cacheBook(ctx, a.pair, a.book)
+	s.markets.set(marketViewOf(book))
+	return nil
+}
+
+// enterOrder releases a persisted order to the engine. Conditional orders
+// wait in the trigger store unless the last trade already crossed them.
+// Like every helper below that touches a book, it must run on the pair's
+// actor, inside exec.
+func (s *OrderService) enterOrder(ctx context.Context, tx repository.Repositories, order *domain.Order) ([]ob.MatchResult, error) {
+	switch order.Status {
+	case domain.OrderStatusCancelled, domain.OrderStatusFilled, domain.OrderStatusExpired:
+		return nil, nil
+	}
+
+	if order.IsConditional() && order.TriggeredAt == nil {
+		if !s.actor(order.Pair).triggers.Add(order) {
+			return nil, nil
+		}
+		// Trigger already crossed by the last trade: release it right away
+		if err := s.markTriggered(ctx, tx, order); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.placeOrder(ctx, tx, order)
+}
+
+// newOrder parses a submission and verifies its EIP-712 signature.
+func (s *OrderService) newOrder(sub domain.OrderSubmission) (*domain.Order, error) {
+	orderType := sub.Type
+	if orderType == "" {
+		orderType = domain.OrderTypeLimit
+	}
+	switch orderType {
+	case domain.OrderTypeLimit:
+	case domain.OrderTypeStopLoss, domain.OrderTypeTakeProfit:
+		if sub.TriggerPrice <= 0 {
+			return nil, fmt.Errorf("triggerPrice is required for %s orders", orderType)
+		}
+	default:
+		return nil, fmt.Errorf("invalid order type: %s", orderType)
+	}
+
 	amountSell, ok := new(big.Int).SetString(sub.AmountSell, 10)
 	if !ok {
-		return nil, nil, fmt.Errorf("invalid amountSell")
+		return nil, fmt.Errorf("invalid amountSell")
 	}
 	amountBuy, ok := new(big.Int).SetString(sub.AmountBuy, 10)
 	if !ok {
-		return nil, nil, fmt.Errorf("invalid amountBuy")
+		return nil, fmt.Errorf("invalid amountBuy")
 	}
 	salt, ok := new(big.Int).SetString(sub.Salt, 10)
 	if !ok {
-		return nil, nil, fmt.Errorf("invalid salt")
+		return nil, fmt.Errorf("invalid salt")
+	}
+	var displayBase *big.Int
+	if sub.DisplayAmount != "" {
+		if displayBase, ok = new(big.Int).SetString(sub.DisplayAmount, 10); !ok || displayBase.Sign() <= 0 {
+			return nil, fmt.Errorf("invalid displayAmount")
+		}
 	}
 
 	// Verify EIP-712 signature
 	sigBytes, err := hexToBytes(sub.Signature)
 	if err != nil {
-		return nil, nil, fmt.Errorf("invalid signature hex: %w", err)
+		return nil, fmt.Errorf("invalid signature hex: %w", err)
 	}
 
 	orderData := eip712.OrderData{
@@ -86,10 +274,10 @@ func (s *OrderService) SubmitOrder(ctx context.Context, sub domain.OrderSubmissi
 
 	valid, err := eip712.VerifyOrderSignature(s.domain, orderData, sigBytes)
 	if err != nil {
-		return nil, nil, fmt.Errorf("signature verification failed: %w", err)
+		return nil, fmt.Errorf("signature verification failed: %w", err)
 	}
 	if !valid {
-		return nil, nil, fmt.Errorf("invalid signature: signer mismatch")
+		return nil, fmt.Errorf("invalid signature: signer mismatch")
 	}
 
 	order := &domain.Order{
@@ -106,138 +294,594 @@ func (s *OrderService) SubmitOrder(ctx context.Context, sub domain.OrderSubmissi
 		Status:     domain.OrderStatusOpen,
 		FilledBase: big.NewInt(0),
 		Pair:       sub.Pair,
-	}
 
-	// Persist order
-	if err := s.orderRepo.Create(ctx, order); err != nil {
-		return nil, nil, fmt.Errorf("failed to persist order: %w", err)
+		Type:         orderType,
+		TriggerPrice: sub.TriggerPrice,
+
+		DisplayBase: displayBase,
+	}
+	if order.IsConditional() {
+		order.Status = domain.OrderStatusPending
 	}
+	if order.IsIceberg() && order.DisplayBase.Cmp(order.BaseAmount()) >= 0 {
+		return nil, fmt.Errorf("displayAmount must be below the order's base amount")
+	}
+	return order, nil
+}
 
-	// Add to in-memory orderbook and match
-	book := s.GetOrCreateOrderBook(order.Pair)
+// placeOrder adds an order to its book, persists the resulting trades and
+// releases any conditional orders whose trigger the trades crossed.
+func (s *OrderService) placeOrder(ctx context.Context, tx repository.Repositories, order *domain.Order) ([]ob.MatchResult, error) {
+	book := s.actor(order.Pair).book
 	matches := book.AddOrder(order)
+	if err := s.afterMatch(ctx, tx, order.Pair, book, matches); err != nil {
+		return nil, err
+	}
+	return matches, nil
+}
+
+// afterMatch persists the outcome of an engine operation on a pair.
+func (s *OrderService) afterMatch(ctx context.Context, tx repository.Repositories, pair string, book *ob.OrderBook, matches []ob.MatchResult) error {
+	if err := s.processMatches(ctx, tx, pair, matches); err != nil {
+		return err
+	}
+	if err := s.handleGroupEvents(ctx, tx, pair, book.TakeGroupEvents()); err != nil {
+		return err
+	}
+
+	// Each released order may trade in turn and release further triggers
+	triggers := s.actor(pair).triggers
+	for _, triggered := range triggers.OnMatches(matches) {
+		// A fill halted the pair: the order waits for it to reopen
+		if !book.Market().State.AcceptsOrders() {
+			triggers.Hold(triggered)
+			continue
+		}
+		if err := s.markTriggered(ctx, tx, triggered); err != nil {
+			return err
+		}
+		if _, err := s.placeOrder(ctx, tx, triggered); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReplaceOrder atomically swaps a resting order for a newly signed one.
+// The replacement's signature is verified like in SubmitOrder. A size
+// decrease at the same price keeps the original queue position.
+func (s *OrderService) ReplaceOrder(ctx context.Context, orderID string, sub domain.OrderSubmission) (*domain.Order, []ob.MatchResult, bool, error) {
+	old, err := s.store.Orders().GetByID(ctx, orderID)
+	if err != nil {
+		return nil, nil, false, fmt.Errorf("order not found: %w", err)
+	}
+	if old.GroupID != "" {
+		return nil, nil, false, fmt.Errorf("group legs cannot be amended")
+	}
+	if old.Status != domain.OrderStatusOpen && old.Status != domain.OrderStatusPartiallyFilled {
+		return nil, nil, false, fmt.Errorf("only resting orders can be amended, order is %s", old.Status)
+	}
+
+	replacement, err := s.newOrder(sub)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	if !strings.EqualFold(replacement.Maker, old.Maker) || replacement.Pair != old.Pair || replacement.Side != old.Side {
+		return nil, nil, false, fmt.Errorf("replacement must keep maker, pair and side")
+	}
+	if replacement.IsConditional() {
+		return nil, nil, false, fmt.Errorf("replacement must be a limit order")
+	}
+	replacement.ReplacesID = old.ID
+	// The engine needs the ID before the row exists
+	replacement.ID = uuid.New().String()
+
+	var matches []ob.MatchResult
+	var keptPriority, ok bool
+	var rejected error
+	a := s.actor(old.Pair)
+	err = s.exec(ctx, a, func(tx repository.Repositories) error {
+		var err error
+		if rejected, err = s.admit(ctx, tx, a, []*domain.Order{replacement}, old.ID); err != nil || rejected != nil {
+			return err
+		}
+		var m []ob.MatchResult
+		var replaced *domain.Order
+		replaced, m, keptPriority, ok = a.book.ReplaceOrder(orderID, replacement)
+		if !ok {
+			return nil
+		}
+		// The replacement is stored as it left the engine, then its fills.
+		// The original keeps the fills the book gave it, which may be more
+		// than the row read before this command.
+		if err := tx.Orders().Create(ctx, replacement); err != nil {
+			return fmt.Errorf("failed to persist order: %w", err)
+		}
+		if err := tx.Orders().UpdateStatus(ctx, orderID, replaced.Status, replaced.FilledBase.String()); err != nil {
+			return fmt.Errorf("failed to cancel replaced order: %w", err)
+		}
+		if err := publishOrder(ctx, tx, replaced.Clone(), orderCancelled); err != nil {
+			return err
+		}
+		if err := publishOrder(ctx, tx, replacement, orderAccepted); err != nil {
+			return err
+		}
+		if err := s.afterMatch(ctx, tx, old.Pair, a.book, m); err != nil {
+			return err
+		}
+		replacement = replacement.Clone()
+		matches = cloneMatches(m)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, false, err
+	}
+	if rejected != nil {
+		return nil, nil, false, rejected
+	}
+	if !ok {
+		return nil, nil, false, fmt.Errorf("order %s is no longer in the book", orderID)
+	}
+
+	return replacement, matches, keptPriority, nil
+}
+
+// RunExpiry expires orders past their signed expiry every interval until ctx is done.
+func (s *OrderService) RunExpiry(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.ExpireOrders(ctx, now)
+		}
+	}
+}
+
+// ExpireOrders removes every order whose expiry is at or before now from the
+// books and trigger stores and marks it expired.
+func (s *OrderService) ExpireOrders(ctx context.Context, now time.Time) {
+	for _, a := range s.allActors() {
+		err := s.exec(ctx, a, func(tx repository.Repositories) error {
+			expired := a.book.ExpireOrders(now)
+			for _, order := range expired {
+				// Expired group legs may be waiting for their trigger
+				a.triggers.Remove(order.ID)
+			}
+			expired = append(expired, a.triggers.Expire(now)...)
+			if len(expired) == 0 {
+				return nil
+			}
+
+			for _, order := range expired {
+				if err := tx.Orders().UpdateStatus(ctx, order.ID, domain.OrderStatusExpired, order.FilledBase.String()); err != nil {
+					return fmt.Errorf("failed to expire order %s: %w", order.ID, err)
+				}
+				order.Status = domain.OrderStatusExpired
+				if err := publishOrder(ctx, tx, order, orderExpired); err != nil {
+					return err
+				}
+			}
+			log.Printf("Expired %d orders for %s", len(expired), a.pair)
+			return s.handleGroupEvents(ctx, tx, a.pair, a.book.TakeGroupEvents())
+		})
+		if err != nil {
+			log.Printf("Failed to expire orders for %s: %v", a.pair, err)
+		}
+	}
+}
+
+func (s *OrderService) markTriggered(ctx context.Context, tx repository.Repositories, order *domain.Order) error {
+	now := time.Now()
+	order.Status = domain.OrderStatusOpen
+	order.TriggeredAt = &now
+	if err := tx.Orders().MarkTriggered(ctx, order.ID, now); err != nil {
+		return fmt.Errorf("failed to mark order triggered: %w", err)
+	}
+	if err := publishOrder(ctx, tx, order, orderTriggered); err != nil {
+		return err
+	}
+	log.Printf("Order %s triggered at last price %f", order.ID, s.actor(order.Pair).triggers.LastPrice())
+	return nil
+}
 
-	// Process matches
+// processMatches persists the trades of a command, folds them into the
+// pair's candles, publishes them and queues each of them for settlement
+// once the command commits.
+func (s *OrderService) processMatches(ctx context.Context, tx repository.Repositories, pair string, matches []ob.MatchResult) error {
+	trades := make([]*domain.Trade, 0, len(matches))
 	for _, match := range matches {
 		trade := &domain.Trade{
-			BuyOrderID:  match.BuyOrder.ID,
-			SellOrderID: match.SellOrder.ID,
-			Buyer:       match.BuyOrder.Maker,
-			Seller:      match.SellOrder.Maker,
-			Pair:        order.Pair,
-			BaseAmount:  match.FillAmount,
-			QuoteAmount: match.QuoteAmount,
-			Price:       match.Price,
-		}
-
-		if err := s.tradeRepo.Create(ctx, trade); err != nil {
-			log.Printf("Failed to persist trade: %v", err)
-			continue
+			BuyOrderID:    match.BuyOrder.ID,
+			SellOrderID:   match.SellOrder.ID,
+			Buyer:         match.BuyOrder.Maker,
+			Seller:        match.SellOrder.Maker,
+			Pair:          pair,
+			BaseAmount:    match.FillAmount,
+			QuoteAmount:   match.QuoteAmount,
+			Price:         match.Price,
+			ClearingPrice: match.ClearingPrice,
+		}
+
+		if err := tx.Trades().Create(ctx, trade); err != nil {
+			return fmt.Errorf("failed to persist trade: %w", err)
+		}
+		trades = append(trades, trade)
+		if err := publishTrade(ctx, tx, trade, match.TakerSide); err != nil {
+			return err
 		}
 
 		// Update order statuses in DB
-		s.orderRepo.UpdateStatus(ctx, match.BuyOrder.ID, match.BuyOrder.Status, match.BuyOrder.FilledBase.String())
-		s.orderRepo.UpdateStatus(ctx, match.SellOrder.ID, match.SellOrder.Status, match.SellOrder.FilledBase.String())
-
-		// Submit to settlement worker
-		resultCh := make(chan blockchain.SettleResult, 1)
-		s.settleCh <- blockchain.SettleJob{
-			Match:   match,
-			TradeID: trade.ID,
-			Result:  resultCh,
-		}
-
-		// Handle settlement result async
-		go func(tradeID string, ch chan blockchain.SettleResult) {
-			result := <-ch
-			if result.Err != nil {
-				log.Printf("Settlement failed for trade %s: %v", tradeID, result.Err)
-				return
+		for _, order := range []*domain.Order{match.BuyOrder, match.SellOrder} {
+			if err := tx.Orders().UpdateStatus(ctx, order.ID, order.Status, order.FilledBase.String()); err != nil {
+				return fmt.Errorf("failed to update order %s: %w", order.ID, err)
 			}
-			if err := s.tradeRepo.MarkSettled(context.Background(), tradeID, result.TxHash); err != nil {
-				log.Printf("Failed to mark trade %s settled: %v", tradeID, err)
+			if err := publishOrder(ctx, tx, order, orderEventOf(order.Status)); err != nil {
+				return err
 			}
-			log.Printf("Trade %s settled: tx %s", tradeID, result.TxHash)
-		}(trade.ID, resultCh)
-	}
+		}
+		if err := publishFills(ctx, tx, trade.ID, match, SettlementPending, "", ""); err != nil {
+			return err
+		}
 
-	// Update cache
-	s.updateCache(ctx, order.Pair, book)
+		// The relay submits the trade to the settlement worker after commit
+		msg := settlementMessage{TradeID: trade.ID, Match: match}
+		if err := tx.Outbox().Enqueue(ctx, topicSettlement, msg); err != nil {
+			return fmt.Errorf("failed to queue settlement: %w", err)
+		}
+	}
+	a := s.actor(pair)
+	a.trades = append(a.trades, trades...)
+	return s.recordCandles(ctx, tx, pair, trades)
+}
 
-	return order, matches, nil
+// GetOrder returns an order as stored.
+func (s *OrderService) GetOrder(ctx context.Context, orderID string) (*domain.Order, error) {
+	order, err := s.store.Orders().GetByID(ctx, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("order not found: %w", err)
+	}
+	return order, nil
 }
 
 func (s *OrderService) CancelOrder(ctx context.Context, orderID string) error {
-	order, err := s.orderRepo.GetByID(ctx, orderID)
+	order, err := s.store.Orders().GetByID(ctx, orderID)
 	if err != nil {
 		return fmt.Errorf("order not found: %w", err)
 	}
 
-	book := s.GetOrCreateOrderBook(order.Pair)
-	if _, ok := book.CancelOrder(orderID); !ok {
-		// Order might already be filled or not in the book
+	var rejected error
+	a := s.actor(order.Pair)
+	err = s.exec(ctx, a, func(tx repository.Repositories) error {
+		if rejected = cancelRefused(a); rejected != nil {
+			return nil
+		}
+		// The row read above may be stale by now: the book and the trigger
+		// store hold the order's current fills, and only orders still in
+		// one of them can be cancelled
+		cancelled, ok := a.book.CancelOrder(orderID)
+		// Conditional orders waiting for a trigger are held outside the book
+		if held, waiting := a.triggers.Cancel(orderID); !ok {
+			cancelled, ok = held, waiting
+		}
+		if !ok {
+			rejected = fmt.Errorf("order %s is not open", orderID)
+			return nil
+		}
+
+		if err := tx.Orders().UpdateStatus(ctx, orderID, cancelled.Status, cancelled.FilledBase.String()); err != nil {
+			return fmt.Errorf("failed to update order: %w", err)
+		}
+		if err := publishOrder(ctx, tx, cancelled.Clone(), orderCancelled); err != nil {
+			return err
+		}
+		return s.handleGroupEvents(ctx, tx, order.Pair, a.book.TakeGroupEvents())
+	})
+	if err != nil {
+		return err
 	}
+	return rejected
+}
 
-	if err := s.orderRepo.UpdateStatus(ctx, orderID, domain.OrderStatusCancelled, order.FilledBase.String()); err != nil {
-		return fmt.Errorf("failed to update order: %w", err)
+// GetOrderbook returns the part of a pair's aggregated book selected by
+// view, empty for a pair without orders. Common views are served from the
+// copies cached after each command; others are computed on the actor.
+func (s *OrderService) GetOrderbook(ctx context.Context, pair string, view ob.BookView) ob.Snapshot {
+	if snapshot, ok := s.views.get(pair, view); ok {
+		return snapshot
 	}
+	var snapshot ob.Snapshot
+	if a, ok := s.lookupActor(pair); ok {
+		a.do(ctx, func() {
+			snapshot = a.book.GetSnapshot().View(view)
+		})
+	}
+	return snapshot
+}
 
-	s.updateCache(ctx, order.Pair, book)
-	return nil
+// History pages hold DefaultPageSize items unless the caller asks for up to
+// MaxPageSize.
+const (
+	DefaultPageSize = 50
+	MaxPageSize     = 500
+)
+
+func pageSize(limit int) int {
+	if limit <= 0 {
+		return DefaultPageSize
+	}
+	return min(limit, MaxPageSize)
 }
 
-func (s *OrderService) GetOrderbook(pair string) ob.Snapshot {
-	book := s.GetOrCreateOrderBook(pair)
-	return book.GetSnapshot()
+// ListOrders returns a page of a maker's orders, newest first, and the
+// cursor of the next page, empty on the last page.
+func (s *OrderService) ListOrders(ctx context.Context, q domain.OrderQuery) ([]*domain.Order, string, error) {
+	q.Limit = pageSize(q.Limit)
+	limit := q.Limit
+	// One extra row tells whether another page follows
+	q.Limit++
+	orders, err := s.store.Orders().List(ctx, q)
+	if err != nil || len(orders) <= limit {
+		return orders, "", err
+	}
+	orders = orders[:limit]
+	last := orders[limit-1]
+	return orders, domain.Cursor{CreatedAt: last.CreatedAt, ID: last.ID}.String(), nil
 }
 
-func (s *OrderService) GetOrdersByMaker(ctx context.Context, maker string) ([]*domain.Order, error) {
-	return s.orderRepo.GetByMaker(ctx, maker)
+// ListTrades returns a page of trades, newest first, and the cursor of the
+// next page, empty on the last page.
+func (s *OrderService) ListTrades(ctx context.Context, q domain.TradeQuery) ([]*domain.Trade, string, error) {
+	q.Limit = pageSize(q.Limit)
+	limit := q.Limit
+	q.Limit++
+	trades, err := s.store.Trades().List(ctx, q)
+	if err != nil || len(trades) <= limit {
+		return trades, "", err
+	}
+	trades = trades[:limit]
+	last := trades[limit-1]
+	return trades, domain.Cursor{CreatedAt: last.CreatedAt, ID: last.ID}.String(), nil
 }
 
-func (s *OrderService) GetTrades(ctx context.Context, pair string, limit int) ([]*domain.Trade, error) {
-	if limit <= 0 {
-		limit = 50
+// RestoreBooks restores the book of every pair that has a snapshot, journal
+// events or live orders. A book is loaded from its latest snapshot and the
+// journal events after it are re-applied, so it comes back with the exact
+// time priority it had and no trades are created at startup.
+func (s *OrderService) RestoreBooks(ctx context.Context) error {
+	snapshotPairs, err := ob.SnapshotPairs(s.snapshotDir)
+	if err != nil {
+		return fmt.Errorf("failed to list snapshots: %w", err)
+	}
+	journalPairs, err := s.store.Events().Pairs(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list journalled pairs: %w", err)
+	}
+	activePairs, err := s.store.Orders().GetActivePairs(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list active pairs: %w", err)
 	}
-	return s.tradeRepo.GetByPair(ctx, pair, limit)
+
+	seen := make(map[string]bool)
+	var pairs []string
+	for _, list := range [][]string{snapshotPairs, journalPairs, activePairs} {
+		for _, pair := range list {
+			if !seen[pair] {
+				seen[pair] = true
+				pairs = append(pairs, pair)
+			}
+		}
+	}
+	sort.Strings(pairs)
+
+	for _, pair := range pairs {
+		if err := s.restoreBook(ctx, pair); err != nil {
+			return fmt.Errorf("failed to restore %s: %w", pair, err)
+		}
+	}
+	return nil
 }
 
-// LoadOpenOrders rebuilds the in-memory orderbook from the database on startup.
-func (s *OrderService) LoadOpenOrders(ctx context.Context, pair string) error {
-	orders, err := s.orderRepo.GetOpenByPair(ctx, pair)
+func (s *OrderService) restoreBook(ctx context.Context, pair string) error {
+	book, err := s.loadBook(ctx, pair)
+	if err != nil {
+		return err
+	}
+
+	a := newPairActor(book)
+	s.mu.Lock()
+	s.actors[pair] = a
+	s.mu.Unlock()
+
+	var crossed bool
+	var loadErr error
+	err = a.do(ctx, func() {
+		if crossed, loadErr = s.loadPendingOrders(ctx, a); loadErr != nil {
+			return
+		}
+		s.cacheBook(ctx, pair, book)
+		s.markets.set(marketViewOf(book))
+	})
+	if err != nil {
+		return err
+	}
+	if loadErr != nil || !crossed {
+		return loadErr
+	}
+
+	// Orders held through a halt that ended while the server was down
+	return s.exec(ctx, a, func(tx repository.Repositories) error {
+		if !a.book.Market().State.AcceptsOrders() {
+			return nil
+		}
+		return s.releaseCrossed(ctx, tx, a)
+	})
+}
+
+// loadBook rebuilds the committed book of a pair from its latest snapshot
+// and the journal events after it.
+func (s *OrderService) loadBook(ctx context.Context, pair string) (*ob.OrderBook, error) {
+	book, err := ob.LoadSnapshotFile(s.snapshotDir, pair)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load snapshot: %w", err)
+	}
+	if book == nil {
+		book = ob.NewOrderBook(pair)
+	}
+
+	events, err := s.store.Events().ListByPair(ctx, pair, book.EventSeq())
+	if err != nil {
+		return nil, err
+	}
+	if book.EventSeq() > 0 || len(events) > 0 {
+		if err := book.Apply(events); err != nil {
+			return nil, fmt.Errorf("failed to replay journal: %w", err)
+		}
+		log.Printf("Restored %s at event %d (%d journal events after snapshot)", pair, book.EventSeq(), len(events))
+	} else {
+		// Nothing journalled yet: bootstrap the journal from the order rows
+		if err := s.loadOpenOrders(ctx, book); err != nil {
+			return nil, err
+		}
+		err := s.store.InTx(ctx, func(tx repository.Repositories) error {
+			return tx.Events().Append(ctx, book.TakeEvents())
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to journal open orders: %w", err)
+		}
+	}
+	book.TakeGroupEvents()
+	return book, nil
+}
+
+// loadOpenOrders places the open orders and active groups stored for a pair
+// in an empty book. Resting orders in a consistent book do not cross, so
+// this does not match.
+func (s *OrderService) loadOpenOrders(ctx context.Context, book *ob.OrderBook) error {
+	pair := book.Pair()
+
+	// Register active groups first so their legs share state with the book
+	legs, err := s.loadActiveGroups(ctx, pair, book)
+	if err != nil {
+		return err
+	}
+
+	orders, err := s.store.Orders().GetOpenByPair(ctx, pair)
 	if err != nil {
 		return err
 	}
-	book := s.GetOrCreateOrderBook(pair)
 	for _, order := range orders {
+		if leg, ok := legs[order.ID]; ok {
+			order = leg
+		}
 		book.AddOrder(order)
 	}
 	log.Printf("Loaded %d open orders for %s", len(orders), pair)
 	return nil
 }
 
-func (s *OrderService) updateCache(ctx context.Context, pair string, book *ob.OrderBook) {
-	snapshot := book.GetSnapshot()
+// loadPendingOrders fills the trigger store of a restored book. Pending
+// group legs are taken from the book so that they share state with it.
+// Orders whose trigger has crossed were held while the pair was halted;
+// they are held again, and crossed reports whether there are any.
+func (s *OrderService) loadPendingOrders(ctx context.Context, a *pairActor) (crossed bool, err error) {
+	pair := a.pair
+	pending, err := s.store.Orders().GetPendingByPair(ctx, pair)
+	if err != nil {
+		return false, err
+	}
+	triggers := a.triggers
+	if last, err := s.store.Trades().GetByPair(ctx, pair, 1); err == nil && len(last) > 0 {
+		triggers.SetLastPrice(last[0].MarketPrice())
+	}
+	for _, order := range pending {
+		if leg := a.book.GroupLeg(order.ID); leg != nil {
+			order = leg
+		}
+		// Bracket exits waiting for their entry to fill have nothing to trigger yet
+		if order.Status != domain.OrderStatusPending || order.RemainingBase().Sign() <= 0 {
+			continue
+		}
+		if triggers.Add(order) {
+			triggers.Hold(order)
+			crossed = true
+		}
+	}
+	log.Printf("Loaded %d pending conditional orders for %s", triggers.Pending(), pair)
+	return crossed, nil
+}
+
+// RunSnapshots writes a snapshot of every book to disk every interval until
+// ctx is done.
+func (s *OrderService) RunSnapshots(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.SaveSnapshots()
+		}
+	}
+}
+
+// SaveSnapshots writes a snapshot of every book to disk. Each snapshot is
+// taken between two commands of its pair.
+func (s *OrderService) SaveSnapshots() {
+	for _, a := range s.allActors() {
+		a.do(context.Background(), func() {
+			if a.stale {
+				// The book may hold changes that were rolled back
+				return
+			}
+			if err := ob.SaveSnapshotFile(s.snapshotDir, a.book); err != nil {
+				log.Printf("Failed to snapshot %s: %v", a.pair, err)
+			}
+		})
+	}
+}
 
-	bids := make([]redisRepo.PriceLevelData, len(snapshot.Bids))
+// bookLevels converts the aggregated levels of a book to their cached form.
+func bookLevels(snapshot ob.Snapshot) (bids, asks []repository.PriceLevelData) {
+	bids = make([]repository.PriceLevelData, len(snapshot.Bids))
 	for i, b := range snapshot.Bids {
-		bids[i] = redisRepo.PriceLevelData{Price: b.Price, Amount: b.Amount.String(), Count: b.Count}
+		bids[i] = repository.PriceLevelData{Price: b.Price, Amount: b.Amount.String(), Count: b.Count}
 	}
-	asks := make([]redisRepo.PriceLevelData, len(snapshot.Asks))
+	asks = make([]repository.PriceLevelData, len(snapshot.Asks))
 	for i, a := range snapshot.Asks {
-		asks[i] = redisRepo.PriceLevelData{Price: a.Price, Amount: a.Amount.String(), Count: a.Count}
+		asks[i] = repository.PriceLevelData{Price: a.Price, Amount: a.Amount.String(), Count: a.Count}
 	}
+	return bids, asks
+}
 
-	if err := s.cache.SetSnapshot(ctx, pair, bids, asks); err != nil {
+// cacheBook stores the committed state of a book: its common views in
+// process and its published view, with the sequence it reflects, in the
+// cache.
+func (s *OrderService) cacheBook(ctx context.Context, pair string, book *ob.OrderBook) {
+	snapshot := book.GetSnapshot()
+	s.views.set(pair, snapshot)
+	bids, asks := bookLevels(snapshot.View(PublishedBookView))
+	cached := repository.BookSnapshot{Seq: book.EventSeq(), Bids: bids, Asks: asks}
+	if err := s.cache.SetSnapshot(ctx, pair, cached); err != nil {
 		log.Printf("Failed to update cache: %v", err)
 	}
+}
 
-	// Publish update for WebSocket subscribers
-	s.cache.PublishUpdate(ctx, pair, map[string]interface{}{
-		"type": "orderbook",
-		"bids": bids,
-		"asks": asks,
-	})
+// publish queues a message for the WebSocket subscribers of a pair's
+// channel; the relay sends it once the transaction commits.
+func publish(ctx context.Context, tx repository.Repositories, channel, pair string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	msg := publishMessage{Channel: channel, Pair: pair, Data: payload}
+	if err := tx.Outbox().Enqueue(ctx, topicPublish, msg); err != nil {
+		return fmt.Errorf("failed to queue update: %w", err)
+	}
+	return nil
 }
 
 func hexToBytes(h string) ([]byte, error) {
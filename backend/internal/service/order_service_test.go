@@ -0,0 +1,530 @@
+package service
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/nexus-orderbook-dex/backend/internal/blockchain"
+	"github.com/nexus-orderbook-dex/backend/internal/domain"
+	ob "github.com/nexus-orderbook-dex/backend/internal/orderbook"
+	"github.com/nexus-orderbook-dex/backend/internal/repository"
+	"github.com/nexus-orderbook-dex/backend/internal/repository/memory"
+	"github.com/nexus-orderbook-dex/backend/pkg/eip712"
+)
+
+const testPair = "TKA-TKB"
+
+var (
+	testChainID  = big.NewInt(31337)
+	testContract = common.HexToAddress("0x5FbDB2315678afecb367f032d93F642f64180aa3")
+	testBase     = common.HexToAddress("0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa")
+	testQuote    = common.HexToAddress("0xbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb")
+)
+
+type testEnv struct {
+	svc   *OrderService
+	store repository.Store
+	cache *memory.Cache
+	relay *OutboxRelay
+}
+
+func newTestEnv(t *testing.T, store repository.Store) *testEnv {
+	t.Helper()
+	if store == nil {
+		store = memory.NewStore()
+	}
+	cache := memory.NewCache()
+
+	// Settle every job right away, like main does without a chain
+	settleCh := make(chan blockchain.SettleJob, 100)
+	go func() {
+		for job := range settleCh {
+			job.Result <- blockchain.SettleResult{TxHash: "0x_mock"}
+		}
+	}()
+	t.Cleanup(func() { close(settleCh) })
+
+	relay := NewOutboxRelay(store, cache, settleCh)
+	svc := NewOrderService(store, cache, relay, testChainID, testContract, t.TempDir())
+	return &testEnv{svc: svc, store: store, cache: cache, relay: relay}
+}
+
+type testMaker struct {
+	key  *ecdsa.PrivateKey
+	addr common.Address
+	salt int64
+}
+
+func newTestMaker(t *testing.T) *testMaker {
+	t.Helper()
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	return &testMaker{key: key, addr: crypto.PubkeyToAddress(key.PublicKey)}
+}
+
+// order returns a signed limit order for base units of TKA at price TKB each.
+func (m *testMaker) order(t *testing.T, side domain.Side, price, base int64) domain.OrderSubmission {
+	t.Helper()
+	m.salt++
+	data := eip712.OrderData{
+		Maker:  m.addr,
+		Expiry: big.NewInt(time.Now().Add(time.Hour).Unix()),
+		Nonce:  big.NewInt(0),
+		Salt:   big.NewInt(m.salt),
+	}
+	if side == domain.SideBuy {
+		data.TokenSell, data.TokenBuy = testQuote, testBase
+		data.AmountSell, data.AmountBuy = big.NewInt(price*base), big.NewInt(base)
+	} else {
+		data.TokenSell, data.TokenBuy = testBase, testQuote
+		data.AmountSell, data.AmountBuy = big.NewInt(base), big.NewInt(price*base)
+	}
+
+	domainSep := eip712.NewDomainSeparator(testChainID, testContract)
+	digest := eip712.HashTypedData(domainSep.Hash(), eip712.HashOrder(data))
+	sig, err := crypto.Sign(digest.Bytes(), m.key)
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	sig[64] += 27
+
+	return domain.OrderSubmission{
+		Maker:      m.addr.Hex(),
+		TokenSell:  data.TokenSell.Hex(),
+		TokenBuy:   data.TokenBuy.Hex(),
+		AmountSell: data.AmountSell.String(),
+		AmountBuy:  data.AmountBuy.String(),
+		Expiry:     data.Expiry.Uint64(),
+		Nonce:      data.Nonce.Uint64(),
+		Salt:       data.Salt.String(),
+		Signature:  "0x" + hex.EncodeToString(sig),
+		Side:       side,
+		Pair:       testPair,
+	}
+}
+
+func eventTypes(events []ob.Event) []ob.EventType {
+	types := make([]ob.EventType, len(events))
+	for i, ev := range events {
+		types[i] = ev.Type
+	}
+	return types
+}
+
+func TestSubmitOrder_RejectsInvalidSignature(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t, nil)
+	maker, other := newTestMaker(t), newTestMaker(t)
+
+	wrongSigner := maker.order(t, domain.SideBuy, 100, 5)
+	wrongSigner.Maker = other.addr.Hex()
+
+	tampered := maker.order(t, domain.SideBuy, 100, 5)
+	tampered.AmountBuy = "50"
+
+	for name, sub := range map[string]domain.OrderSubmission{
+		"wrong signer":    wrongSigner,
+		"tampered amount": tampered,
+	} {
+		if _, _, err := env.svc.SubmitOrder(ctx, sub); err == nil || !strings.Contains(err.Error(), "signer mismatch") {
+			t.Errorf("%s: expected a signer mismatch, got %v", name, err)
+		}
+	}
+
+	for _, addr := range []common.Address{maker.addr, other.addr} {
+		if orders, _ := env.store.Orders().GetByMaker(ctx, addr.Hex()); len(orders) != 0 {
+			t.Fatalf("rejected orders were persisted: %d", len(orders))
+		}
+	}
+	if snap := env.svc.GetOrderbook(ctx, testPair, ob.BookView{}); len(snap.Bids) != 0 {
+		t.Fatal("rejected order reached the book")
+	}
+}
+
+func TestSubmitOrder_MatchesAndPersistsTrade(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t, nil)
+	seller, buyer := newTestMaker(t), newTestMaker(t)
+
+	sell, matches, err := env.svc.SubmitOrder(ctx, seller.order(t, domain.SideSell, 100, 10))
+	if err != nil {
+		t.Fatalf("submit sell: %v", err)
+	}
+	if len(matches) != 0 {
+		t.Fatalf("resting order matched: %d", len(matches))
+	}
+
+	buy, matches, err := env.svc.SubmitOrder(ctx, buyer.order(t, domain.SideBuy, 101, 4))
+	if err != nil {
+		t.Fatalf("submit buy: %v", err)
+	}
+	if len(matches) != 1 {
+		t.Fatalf("expected 1 match, got %d", len(matches))
+	}
+	if m := matches[0]; m.FillAmount.Int64() != 4 || m.Price != 100 {
+		t.Fatalf("expected 4 filled at the resting price 100, got %s at %v", m.FillAmount, m.Price)
+	}
+	if buy.Status != domain.OrderStatusFilled {
+		t.Fatalf("expected buy filled, got %s", buy.Status)
+	}
+
+	trades, err := env.store.Trades().GetByPair(ctx, testPair, 10)
+	if err != nil || len(trades) != 1 {
+		t.Fatalf("expected 1 stored trade, got %d (%v)", len(trades), err)
+	}
+	if trades[0].BuyOrderID != buy.ID || trades[0].SellOrderID != sell.ID || trades[0].BaseAmount.Int64() != 4 {
+		t.Fatalf("unexpected trade %+v", trades[0])
+	}
+
+	stored, err := env.store.Orders().GetByID(ctx, sell.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Status != domain.OrderStatusPartiallyFilled || stored.FilledBase.Int64() != 4 {
+		t.Fatalf("expected stored sell partially filled 4, got %s %s", stored.Status, stored.FilledBase)
+	}
+
+	snap := env.svc.GetOrderbook(ctx, testPair, ob.BookView{})
+	if len(snap.Bids) != 0 || len(snap.Asks) != 1 || snap.Asks[0].Amount.Int64() != 6 {
+		t.Fatalf("expected only 6 left on the ask, got %+v", snap)
+	}
+
+	events, _ := env.store.Events().ListByPair(ctx, testPair, 0)
+	want := []ob.EventType{ob.EventAccepted, ob.EventAccepted, ob.EventMatched}
+	if got := eventTypes(events); len(got) != len(want) || got[0] != want[0] || got[1] != want[1] || got[2] != want[2] {
+		t.Fatalf("expected journal %v, got %v", want, got)
+	}
+
+	// The trade is queued for settlement with the command that created it
+	if pending, _ := env.store.Outbox().Pending(ctx, topicSettlement, relayBatchSize); len(pending) != 1 {
+		t.Fatalf("expected 1 queued settlement, got %d", len(pending))
+	}
+}
+
+func TestCancelOrder_RemovesFromBookAndStore(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t, nil)
+	maker := newTestMaker(t)
+
+	order, _, err := env.svc.SubmitOrder(ctx, maker.order(t, domain.SideBuy, 100, 5))
+	if err != nil {
+		t.Fatalf("submit: %v", err)
+	}
+	if err := env.svc.CancelOrder(ctx, order.ID); err != nil {
+		t.Fatalf("cancel: %v", err)
+	}
+
+	if snap := env.svc.GetOrderbook(ctx, testPair, ob.BookView{}); len(snap.Bids) != 0 {
+		t.Fatalf("cancelled order still in the book: %+v", snap.Bids)
+	}
+	stored, err := env.store.Orders().GetByID(ctx, order.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Status != domain.OrderStatusCancelled {
+		t.Fatalf("expected stored status cancelled, got %s", stored.Status)
+	}
+	events, _ := env.store.Events().ListByPair(ctx, testPair, 0)
+	if last := events[len(events)-1]; last.Type != ob.EventCancelled || last.OrderID != order.ID {
+		t.Fatalf("expected a journalled cancel, got %s of %s", last.Type, last.OrderID)
+	}
+
+	if err := env.svc.CancelOrder(ctx, "missing"); err == nil {
+		t.Fatal("expected an error cancelling an unknown order")
+	}
+}
+
+func TestCancelOrder_KeepsTheFillsOfTheBook(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t, nil)
+	seller, buyer := newTestMaker(t), newTestMaker(t)
+
+	partial, _, err := env.svc.SubmitOrder(ctx, seller.order(t, domain.SideSell, 101, 5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	filled, _, err := env.svc.SubmitOrder(ctx, seller.order(t, domain.SideSell, 100, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := env.svc.SubmitOrder(ctx, buyer.order(t, domain.SideBuy, 101, 5)); err != nil {
+		t.Fatal(err)
+	}
+
+	// The order filled after the caller last saw it: it cannot be cancelled
+	if err := env.svc.CancelOrder(ctx, filled.ID); err == nil {
+		t.Fatal("expected cancelling a filled order to fail")
+	}
+	if stored, _ := env.store.Orders().GetByID(ctx, filled.ID); stored.Status != domain.OrderStatusFilled {
+		t.Fatalf("expected the order to stay filled, got %s", stored.Status)
+	}
+
+	// A partially filled order is cancelled with the fills it has
+	if err := env.svc.CancelOrder(ctx, partial.ID); err != nil {
+		t.Fatal(err)
+	}
+	stored, _ := env.store.Orders().GetByID(ctx, partial.ID)
+	if stored.Status != domain.OrderStatusCancelled || stored.FilledBase.Int64() != 3 {
+		t.Fatalf("expected cancelled with 3 filled, got %s with %s", stored.Status, stored.FilledBase)
+	}
+}
+
+func TestSubmitOrder_PublishesCommittedBook(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t, nil)
+	maker := newTestMaker(t)
+
+	if _, _, err := env.svc.SubmitOrder(ctx, maker.order(t, domain.SideBuy, 100, 5)); err != nil {
+		t.Fatalf("submit: %v", err)
+	}
+
+	cached, _ := env.cache.GetSnapshot(ctx, testPair)
+	if bids := cached.Bids; len(bids) != 1 || bids[0].Price != 100 || bids[0].Amount != "5" {
+		t.Fatalf("expected cached bid 5 at 100, got %+v", bids)
+	}
+
+	// Updates go out through the outbox, not from inside the command
+	if n := len(env.cache.Published(repository.ChannelOrderbook, testPair)); n != 0 {
+		t.Fatalf("published %d updates before the relay ran", n)
+	}
+	if _, err := env.relay.drain(ctx); err != nil {
+		t.Fatalf("drain: %v", err)
+	}
+
+	// The level change, and the ticker for the new best bid on its own channel
+	published := env.cache.Published(repository.ChannelOrderbook, testPair)
+	if len(published) != 1 {
+		t.Fatalf("expected 1 published update, got %d", len(published))
+	}
+	if n := len(env.cache.Published(repository.ChannelTicker, testPair)); n != 1 {
+		t.Fatalf("expected 1 ticker update, got %d", n)
+	}
+	var msg struct {
+		Type string                      `json:"type"`
+		Bids []repository.PriceLevelData `json:"bids"`
+	}
+	if err := json.Unmarshal(published[0], &msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg.Type != "l2" || len(msg.Bids) != 1 || msg.Bids[0].Amount != "5" {
+		t.Fatalf("unexpected update %s", published[0])
+	}
+	if pending, _ := env.store.Outbox().Pending(ctx, topicPublish, 10); len(pending) != 0 {
+		t.Fatalf("%d outbox messages left after draining", len(pending))
+	}
+}
+
+// failingStore fails every journal append while failAppend is set.
+type failingStore struct {
+	*memory.Store
+	failAppend bool
+}
+
+func (s *failingStore) InTx(ctx context.Context, fn func(tx repository.Repositories) error) error {
+	return s.Store.InTx(ctx, func(tx repository.Repositories) error {
+		if s.failAppend {
+			tx = failingEvents{tx}
+		}
+		return fn(tx)
+	})
+}
+
+type failingEvents struct{ repository.Repositories }
+
+func (f failingEvents) Events() repository.EventRepository {
+	return failingEventRepo{f.Repositories.Events()}
+}
+
+type failingEventRepo struct{ repository.EventRepository }
+
+func (failingEventRepo) Append(ctx context.Context, events []ob.Event) error {
+	return errors.New("disk full")
+}
+
+func TestSubmitOrder_RollsBackOnStoreFailure(t *testing.T) {
+	ctx := context.Background()
+	store := &failingStore{Store: memory.NewStore()}
+	env := newTestEnv(t, store)
+	seller, buyer := newTestMaker(t), newTestMaker(t)
+
+	sell, _, err := env.svc.SubmitOrder(ctx, seller.order(t, domain.SideSell, 100, 10))
+	if err != nil {
+		t.Fatalf("submit sell: %v", err)
+	}
+
+	store.failAppend = true
+	if _, _, err := env.svc.SubmitOrder(ctx, buyer.order(t, domain.SideBuy, 100, 4)); err == nil {
+		t.Fatal("expected the failed journal append to fail the order")
+	}
+	store.failAppend = false
+
+	// Neither the book nor the database kept any part of the failed command
+	snap := env.svc.GetOrderbook(ctx, testPair, ob.BookView{})
+	if len(snap.Asks) != 1 || snap.Asks[0].Amount.Int64() != 10 {
+		t.Fatalf("expected the ask untouched, got %+v", snap.Asks)
+	}
+	if orders, _ := env.store.Orders().GetByMaker(ctx, buyer.addr.Hex()); len(orders) != 0 {
+		t.Fatal("the failed order was persisted")
+	}
+	if trades, _ := env.store.Trades().GetByPair(ctx, testPair, 10); len(trades) != 0 {
+		t.Fatal("a trade of the failed order was persisted")
+	}
+
+	// The reloaded book keeps working
+	_, matches, err := env.svc.SubmitOrder(ctx, buyer.order(t, domain.SideBuy, 100, 4))
+	if err != nil {
+		t.Fatalf("submit after rollback: %v", err)
+	}
+	if len(matches) != 1 || matches[0].SellOrder.ID != sell.ID {
+		t.Fatalf("expected the retry to fill against %s, got %d matches", sell.ID, len(matches))
+	}
+}
+
+func TestListOrders_PagesNewestFirst(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t, nil)
+	maker := newTestMaker(t)
+
+	var ids []string
+	for price := int64(100); price < 105; price++ {
+		order, _, err := env.svc.SubmitOrder(ctx, maker.order(t, domain.SideBuy, price, 5))
+		if err != nil {
+			t.Fatalf("submit: %v", err)
+		}
+		ids = append(ids, order.ID)
+	}
+
+	var seen []string
+	q := domain.OrderQuery{Maker: maker.addr.Hex(), Limit: 2}
+	for pages := 0; ; pages++ {
+		if pages == 3 {
+			t.Fatal("expected the last page after 3 pages")
+		}
+		orders, next, err := env.svc.ListOrders(ctx, q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, o := range orders {
+			seen = append(seen, o.ID)
+		}
+		if next == "" {
+			break
+		}
+		if q.After, err = domain.ParseCursor(next); err != nil {
+			t.Fatalf("parse cursor: %v", err)
+		}
+	}
+	if len(seen) != len(ids) {
+		t.Fatalf("expected %d orders across pages, got %d", len(ids), len(seen))
+	}
+	for i, id := range seen {
+		if id != ids[len(ids)-1-i] {
+			t.Fatalf("expected newest first, got %v for submissions %v", seen, ids)
+		}
+	}
+
+	sells, next, err := env.svc.ListOrders(ctx, domain.OrderQuery{Maker: maker.addr.Hex(), Side: domain.SideSell})
+	if err != nil || len(sells) != 0 || next != "" {
+		t.Fatalf("expected no sell orders, got %d (next %q, err %v)", len(sells), next, err)
+	}
+}
+
+func TestHistory_MatchesAddressesInAnyCase(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t, nil)
+	seller, buyer := newTestMaker(t), newTestMaker(t)
+
+	if _, _, err := env.svc.SubmitOrder(ctx, seller.order(t, domain.SideSell, 100, 1)); err != nil {
+		t.Fatal(err)
+	}
+	bid := buyer.order(t, domain.SideBuy, 100, 1)
+	bid.Maker = strings.ToLower(bid.Maker)
+	if _, _, err := env.svc.SubmitOrder(ctx, bid); err != nil {
+		t.Fatal(err)
+	}
+
+	hex := buyer.addr.Hex()
+	for _, maker := range []string{hex, strings.ToLower(hex), "0x" + strings.ToUpper(hex[2:])} {
+		orders, _, err := env.svc.ListOrders(ctx, domain.OrderQuery{Maker: maker})
+		if err != nil || len(orders) != 1 {
+			t.Fatalf("expected the buyer's order for %s, got %d (%v)", maker, len(orders), err)
+		}
+		for _, side := range []domain.Side{domain.SideBuy, ""} {
+			trades, _, err := env.svc.ListTrades(ctx, domain.TradeQuery{Address: maker, Side: side})
+			if err != nil || len(trades) != 1 {
+				t.Fatalf("expected the buyer's trade for %s on side %q, got %d (%v)", maker, side, len(trades), err)
+			}
+		}
+	}
+	if trades, _, err := env.svc.ListTrades(ctx, domain.TradeQuery{Address: strings.ToLower(seller.addr.Hex()), Side: domain.SideSell}); err != nil || len(trades) != 1 {
+		t.Fatalf("expected the seller's trade, got %d (%v)", len(trades), err)
+	}
+}
+
+func TestGetOrderbook_ServesDepthAndGroupedViews(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t, nil)
+	maker := newTestMaker(t)
+
+	for _, price := range []int64{101, 105, 112} {
+		if _, _, err := env.svc.SubmitOrder(ctx, maker.order(t, domain.SideBuy, price, 5)); err != nil {
+			t.Fatalf("submit: %v", err)
+		}
+	}
+
+	// A cached view and an uncached one agree with the committed book
+	for _, view := range []ob.BookView{{Depth: 20, Group: 10}, {Depth: 1, Group: 10}} {
+		snap := env.svc.GetOrderbook(ctx, testPair, view)
+		if snap.Bids[0].Price != 110 || snap.Bids[0].Amount.Int64() != 5 {
+			t.Fatalf("%+v: expected 5 at 110 first, got %+v", view, snap.Bids)
+		}
+		want := 2
+		if view.Depth == 1 {
+			want = 1
+		}
+		if len(snap.Bids) != want || want == 2 && (snap.Bids[1].Price != 100 || snap.Bids[1].Count != 2) {
+			t.Fatalf("%+v: unexpected bids %+v", view, snap.Bids)
+		}
+	}
+
+	full := env.svc.GetOrderbook(ctx, testPair, ob.BookView{})
+	if len(full.Bids) != 3 {
+		t.Fatalf("expected the full book, got %+v", full.Bids)
+	}
+}
+
+func TestGetGroup_ReturnsTheBracketEntryFirst(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t, nil)
+	m := newTestMaker(t)
+	group, _, err := env.svc.SubmitGroup(ctx, domain.GroupSubmission{
+		Type: domain.GroupTypeBracket,
+		Legs: []domain.OrderSubmission{
+			m.order(t, domain.SideBuy, 100, 1),
+			m.order(t, domain.SideSell, 120, 1),
+			m.order(t, domain.SideSell, 110, 1),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, legs, err := env.svc.GetGroup(ctx, group.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(legs) != 3 || legs[0].GroupRole != domain.GroupRoleEntry || legs[1].Price() != 120 || legs[2].Price() != 110 {
+		t.Fatalf("expected the entry then the exits in submission order, got %+v", legs)
+	}
+}
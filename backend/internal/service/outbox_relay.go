@@ -0,0 +1,233 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nexus-orderbook-dex/backend/internal/blockchain"
+	ob "github.com/nexus-orderbook-dex/backend/internal/orderbook"
+	"github.com/nexus-orderbook-dex/backend/internal/repository"
+)
+
+// Outbox topics.
+const (
+	topicSettlement = "settlement" // settlementMessage, submitted to the settlement worker
+	topicPublish    = "publish"    // publishMessage, sent to WebSocket subscribers of a pair's channel
+)
+
+type settlementMessage struct {
+	TradeID string         `json:"tradeId"`
+	Match   ob.MatchResult `json:"match"`
+}
+
+type publishMessage struct {
+	Channel string          `json:"channel,omitempty"` // empty in messages queued before channels existed
+	Pair    string          `json:"pair"`              // the user key on ChannelUser
+	Data    json.RawMessage `json:"data"`
+}
+
+// Relay limits.
+const (
+	relayBatchSize   = 100
+	relayMaxAttempts = 20               // a message that fails this often is dead
+	relayLease       = 30 * time.Second // how long one drain may hold the relay lease
+)
+
+// relayTopics are delivered independently of each other, so that e.g. a
+// Redis outage holding up publications does not hold up settlement.
+var relayTopics = []string{topicSettlement, topicPublish}
+
+// OutboxRelay delivers outbox messages of each topic in the order they were
+// committed. Delivery is at least once: a crash between handing a message
+// over and marking it processed delivers it again after restart. Only the
+// relay holding the store's lease delivers, so that several instances keep
+// that order.
+type OutboxRelay struct {
+	id       string // lease holder
+	store    repository.Store
+	pub      repository.Publisher
+	settleCh chan<- blockchain.SettleJob
+	wake     chan struct{}
+	interval time.Duration
+	timeout  time.Duration // bounds each delivery
+}
+
+func NewOutboxRelay(store repository.Store, pub repository.Publisher, settleCh chan<- blockchain.SettleJob) *OutboxRelay {
+	return &OutboxRelay{
+		id:       uuid.New().String(),
+		store:    store,
+		pub:      pub,
+		settleCh: settleCh,
+		wake:     make(chan struct{}, 1),
+		interval: time.Second,
+		timeout:  5 * time.Second,
+	}
+}
+
+// Notify wakes the relay after a transaction wrote to the outbox.
+func (r *OutboxRelay) Notify() {
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run drains the outbox whenever it is notified, and at least once per
+// interval, until ctx is done.
+func (r *OutboxRelay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		for {
+			n, err := r.drain(ctx)
+			if err != nil {
+				log.Printf("Outbox relay: %v", err)
+				break
+			}
+			if n < relayBatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-r.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// drain delivers one batch of pending messages of every topic, if this
+// relay holds the lease, and returns the size of the largest batch. No
+// transaction stays open while messages are delivered.
+func (r *OutboxRelay) drain(ctx context.Context) (int, error) {
+	held, err := r.store.Outbox().Lease(ctx, r.id, relayLease)
+	if err != nil || !held {
+		return 0, err
+	}
+	// Stop delivering well before the lease runs out
+	until := time.Now().Add(relayLease / 2)
+
+	most := 0
+	for _, topic := range relayTopics {
+		n, err := r.drainTopic(ctx, topic, until)
+		if err != nil {
+			return most, err
+		}
+		most = max(most, n)
+	}
+	return most, nil
+}
+
+// drainTopic delivers one batch of a topic's pending messages and returns
+// how many it took off the queue. A failed delivery stops the batch so that
+// later messages are not delivered ahead of it, unless it was the message's
+// last attempt: the message is then dead and the batch goes on.
+func (r *OutboxRelay) drainTopic(ctx context.Context, topic string, until time.Time) (int, error) {
+	outbox := r.store.Outbox()
+	msgs, err := outbox.Pending(ctx, topic, relayBatchSize)
+	if err != nil {
+		return 0, err
+	}
+	var ids []int64
+	dead := 0
+	for _, msg := range msgs {
+		if time.Now().After(until) {
+			break
+		}
+		err := r.deliver(ctx, msg)
+		if err == nil {
+			ids = append(ids, msg.ID)
+			continue
+		}
+		last := msg.Attempts+1 >= relayMaxAttempts
+		if last {
+			log.Printf("Outbox relay: message %d (%s) is dead after %d attempts: %v", msg.ID, msg.Topic, msg.Attempts+1, err)
+		} else {
+			log.Printf("Outbox relay: message %d (%s) failed: %v", msg.ID, msg.Topic, err)
+		}
+		if err := outbox.MarkFailed(ctx, msg.ID, err, last); err != nil {
+			return len(ids) + dead, err
+		}
+		if !last {
+			break
+		}
+		dead++
+	}
+	return len(ids) + dead, outbox.MarkProcessed(ctx, ids)
+}
+
+// deliver hands a message over, giving up after the relay's timeout.
+func (r *OutboxRelay) deliver(ctx context.Context, msg *repository.OutboxMessage) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	switch msg.Topic {
+	case topicSettlement:
+		var m settlementMessage
+		if err := json.Unmarshal(msg.Payload, &m); err != nil {
+			return err
+		}
+		resultCh := make(chan blockchain.SettleResult, 1)
+		select {
+		case r.settleCh <- blockchain.SettleJob{Match: m.Match, TradeID: m.TradeID, Result: resultCh}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		// Handle settlement result async
+		go func(m settlementMessage, ch chan blockchain.SettleResult) {
+			result := <-ch
+			if err := r.settled(context.Background(), m, result); err != nil {
+				log.Printf("Failed to record settlement of trade %s: %v", m.TradeID, err)
+			}
+		}(m, resultCh)
+		return nil
+
+	case topicPublish:
+		var m publishMessage
+		if err := json.Unmarshal(msg.Payload, &m); err != nil {
+			return err
+		}
+		if m.Channel == "" {
+			m.Channel = repository.ChannelOrderbook
+		}
+		return r.pub.PublishUpdate(ctx, m.Channel, m.Pair, m.Data)
+	}
+	return fmt.Errorf("unknown topic %q", msg.Topic)
+}
+
+// settled records the outcome of a trade's settlement and queues its
+// confirmation on the pair's trades channel and the fill and balance
+// updates of both makers.
+func (r *OutboxRelay) settled(ctx context.Context, m settlementMessage, result blockchain.SettleResult) error {
+	err := r.store.InTx(ctx, func(tx repository.Repositories) error {
+		if result.Err != nil {
+			log.Printf("Settlement failed for trade %s: %v", m.TradeID, result.Err)
+			return publishFills(ctx, tx, m.TradeID, m.Match, SettlementFailed, "", result.Err.Error())
+		}
+		if err := tx.Trades().MarkSettled(ctx, m.TradeID, result.TxHash); err != nil {
+			return fmt.Errorf("failed to mark trade settled: %w", err)
+		}
+		if err := publishTradeSettled(ctx, tx, m.Match.BuyOrder.Pair, m.TradeID, result.TxHash); err != nil {
+			return err
+		}
+		if err := publishFills(ctx, tx, m.TradeID, m.Match, SettlementSettled, result.TxHash, ""); err != nil {
+			return err
+		}
+		return publishTradeBalances(ctx, tx, m.TradeID, m.Match, result.TxHash)
+	})
+	if err != nil {
+		return err
+	}
+	if result.Err == nil {
+		log.Printf("Trade %s settled: tx %s", m.TradeID, result.TxHash)
+	}
+	r.Notify()
+	return nil
+}
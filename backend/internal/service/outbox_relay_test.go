@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/nexus-orderbook-dex/backend/internal/blockchain"
+	"github.com/nexus-orderbook-dex/backend/internal/domain"
+	"github.com/nexus-orderbook-dex/backend/internal/repository"
+	"github.com/nexus-orderbook-dex/backend/internal/repository/memory"
+)
+
+// downPublisher fails every publication, like Redis during an outage.
+type downPublisher struct{}
+
+func (downPublisher) PublishUpdate(ctx context.Context, channel, pair string, data interface{}) error {
+	return errors.New("connection refused")
+}
+
+func TestOutboxRelay_SettlesWhilePublishingFails(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	err := store.InTx(ctx, func(tx repository.Repositories) error {
+		if err := publish(ctx, tx, repository.ChannelOrderbook, testPair, map[string]string{"type": "l2"}); err != nil {
+			return err
+		}
+		return tx.Outbox().Enqueue(ctx, topicSettlement, settlementMessage{TradeID: "trade-1"})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	settleCh := make(chan blockchain.SettleJob, 1)
+	relay := NewOutboxRelay(store, downPublisher{}, settleCh)
+	if _, err := relay.drain(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if job := <-settleCh; job.TradeID != "trade-1" {
+		t.Fatalf("expected trade-1 submitted, got %q", job.TradeID)
+	}
+
+	// The publication is retried until its last attempt, then set aside
+	for i := 1; i < relayMaxAttempts; i++ {
+		if pending, _ := store.Outbox().Pending(ctx, topicPublish, 10); len(pending) != 1 || pending[0].Attempts != i {
+			t.Fatalf("expected the publication pending after %d attempts, got %+v", i, pending)
+		}
+		if _, err := relay.drain(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if pending, _ := store.Outbox().Pending(ctx, topicPublish, 10); len(pending) != 0 {
+		t.Fatalf("expected the publication dead, got %d pending", len(pending))
+	}
+}
+
+func TestOutboxRelay_OneRelayDeliversAtATime(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t, nil)
+	if _, _, err := env.svc.SubmitOrder(ctx, newTestMaker(t).order(t, domain.SideBuy, 100, 1)); err != nil {
+		t.Fatal(err)
+	}
+
+	// The first relay to drain holds the lease
+	if _, err := env.relay.drain(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := env.svc.SubmitOrder(ctx, newTestMaker(t).order(t, domain.SideBuy, 99, 1)); err != nil {
+		t.Fatal(err)
+	}
+	other := NewOutboxRelay(env.store, env.cache, make(chan blockchain.SettleJob))
+	if n, err := other.drain(ctx); err != nil || n != 0 {
+		t.Fatalf("expected the second relay to deliver nothing, got %d (%v)", n, err)
+	}
+	if n, err := env.relay.drain(ctx); err != nil || n == 0 {
+		t.Fatalf("expected the lease holder to deliver, got %d (%v)", n, err)
+	}
+}
@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"sort"
+
+	"github.com/nexus-orderbook-dex/backend/internal/domain"
+	ob "github.com/nexus-orderbook-dex/backend/internal/orderbook"
+)
+
+// pairActor is the single writer of one trading pair. Every operation on the
+// pair's book and trigger store, and on the orders they hold, runs as a
+// command on the actor's goroutine, one at a time and in arrival order.
+// Different pairs have their own actors and run in parallel.
+//
+// Commands may call back into the service for the same pair (placing a
+// released trigger or an activated bracket exit) as long as they do so
+// directly and never enqueue a new command on their own actor.
+type pairActor struct {
+	pair     string
+	book     *ob.OrderBook
+	triggers *ob.TriggerStore
+	cmds     chan func()
+
+	// trades are the trades of the running command, read once it commits
+	trades []*domain.Trade
+
+	// l2 is the published view of the committed book, which the next
+	// command's level changes are computed against
+	l2 ob.Snapshot
+
+	// l3 is the order-by-order state last published for the committed book
+	l3 *ob.L3Tracker
+
+	// prices are the committed trades within the circuit breaker's window
+	prices []pricePoint
+
+	// stale is set while the book may differ from the committed state,
+	// after a rolled back command whose reload failed
+	stale bool
+}
+
+const actorQueueSize = 256
+
+func newPairActor(book *ob.OrderBook) *pairActor {
+	a := &pairActor{
+		pair:     book.Pair(),
+		book:     book,
+		triggers: ob.NewTriggerStore(book.Pair()),
+		cmds:     make(chan func(), actorQueueSize),
+		l2:       book.GetSnapshot().View(PublishedBookView),
+		l3:       ob.NewL3Tracker(book),
+	}
+	go a.run()
+	return a
+}
+
+func (a *pairActor) run() {
+	for cmd := range a.cmds {
+		cmd()
+	}
+}
+
+// do runs fn on the actor and waits for it to finish. If ctx is done before
+// the command is queued, fn does not run and ctx's error is returned; once
+// queued, fn always runs to completion.
+func (a *pairActor) do(ctx context.Context, fn func()) error {
+	done := make(chan struct{})
+	cmd := func() {
+		defer close(done)
+		fn()
+	}
+	select {
+	case a.cmds <- cmd:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	<-done
+	return nil
+}
+
+// lookupActor returns the actor of pair without creating one.
+func (s *OrderService) lookupActor(pair string) (*pairActor, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	a, ok := s.actors[pair]
+	return a, ok
+}
+
+// actor returns the actor of pair, starting one with an empty book if the
+// pair has none yet.
+func (s *OrderService) actor(pair string) *pairActor {
+	if a, ok := s.lookupActor(pair); ok {
+		return a
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if a, ok := s.actors[pair]; ok {
+		return a
+	}
+	a := newPairActor(ob.NewOrderBook(pair))
+	s.actors[pair] = a
+	return a
+}
+
+// allActors returns the actors of every known pair, sorted by pair.
+func (s *OrderService) allActors() []*pairActor {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	actors := make([]*pairActor, 0, len(s.actors))
+	for _, a := range s.actors {
+		actors = append(actors, a)
+	}
+	sort.Slice(actors, func(i, j int) bool { return actors[i].pair < actors[j].pair })
+	return actors
+}
+
+// cloneMatches detaches match results from the book before they leave the actor.
+func cloneMatches(matches []ob.MatchResult) []ob.MatchResult {
+	if matches == nil {
+		return nil
+	}
+	out := make([]ob.MatchResult, len(matches))
+	for i, m := range matches {
+		out[i] = m.Clone()
+	}
+	return out
+}
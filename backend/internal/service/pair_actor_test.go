@@ -0,0 +1,215 @@
+package service
+
+import (
+	"context"
+	"math/big"
+	"math/rand"
+	"sync"
+	"testing"
+
+	"github.com/nexus-orderbook-dex/backend/internal/domain"
+	ob "github.com/nexus-orderbook-dex/backend/internal/orderbook"
+)
+
+// These tests are meant to run with -race.
+
+func newActorOnlyService() *OrderService {
+	return &OrderService{actors: make(map[string]*pairActor)}
+}
+
+func TestPairActor_LookupCreatesOnce(t *testing.T) {
+	s := newActorOnlyService()
+
+	const n = 64
+	actors := make([]*pairActor, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			actors[i] = s.actor("TKA-TKB")
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 1; i < n; i++ {
+		if actors[i] != actors[0] {
+			t.Fatal("concurrent lookups created more than one actor")
+		}
+	}
+	if len(s.allActors()) != 1 {
+		t.Fatalf("expected 1 actor, got %d", len(s.allActors()))
+	}
+}
+
+func TestPairActor_ConcurrentSubmitCancelSnapshot(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t, nil)
+
+	pairs := []string{"TKA-TKB", "TKC-TKD", "TKE-TKF", "TKG-TKH"}
+	const workers = 4
+	const opsPerWorker = 100
+
+	var mu sync.Mutex
+	filled := make(map[string]*big.Int) // orderID -> base filled, from the fills returned
+	sizes := make(map[string]*big.Int)  // orderID -> base size
+	byPair := make(map[string][]string) // pair -> orders submitted
+
+	// Orders are signed up front: helpers that may call t.Fatal must stay
+	// on the test goroutine
+	type signed struct {
+		sub  domain.OrderSubmission
+		base int64
+	}
+	orders := make(map[string][][]signed)
+	for _, pair := range pairs {
+		for w := 0; w < workers; w++ {
+			rng := rand.New(rand.NewSource(int64(w)))
+			maker := newTestMaker(t)
+			var subs []signed
+			for i := 0; i < opsPerWorker; i++ {
+				side := domain.SideBuy
+				if rng.Intn(2) == 0 {
+					side = domain.SideSell
+				}
+				base := int64(1 + rng.Intn(20))
+				sub := maker.order(t, side, int64(95+rng.Intn(11)), base)
+				sub.Pair = pair
+				subs = append(subs, signed{sub, base})
+			}
+			orders[pair] = append(orders[pair], subs)
+		}
+	}
+
+	var wg sync.WaitGroup
+	for _, pair := range pairs {
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func(pair string, w int, subs []signed) {
+				defer wg.Done()
+				rng := rand.New(rand.NewSource(int64(w)))
+
+				var mine []string
+				for i := 0; i < opsPerWorker; i++ {
+					switch op := rng.Intn(10); {
+					case op < 6:
+						order, matches, err := env.svc.SubmitOrder(ctx, subs[i].sub)
+						if err != nil {
+							t.Error(err)
+							return
+						}
+						mine = append(mine, order.ID)
+
+						// Returned fills must be safe to read while the actor keeps matching
+						mu.Lock()
+						sizes[order.ID] = big.NewInt(subs[i].base)
+						byPair[pair] = append(byPair[pair], order.ID)
+						for _, m := range matches {
+							for _, id := range []string{m.BuyOrder.ID, m.SellOrder.ID} {
+								if filled[id] == nil {
+									filled[id] = new(big.Int)
+								}
+								filled[id].Add(filled[id], m.FillAmount)
+							}
+							_ = m.BuyOrder.FilledBase.String() + m.SellOrder.FilledBase.String()
+						}
+						mu.Unlock()
+
+					case op < 8 && len(mine) > 0:
+						// Orders filled meanwhile cannot be cancelled
+						env.svc.CancelOrder(ctx, mine[rng.Intn(len(mine))])
+
+					case op < 9:
+						snap := env.svc.GetOrderbook(ctx, pair, ob.BookView{})
+						for _, lvl := range snap.Bids {
+							_ = lvl.Amount.String()
+						}
+
+					default:
+						l3, err := env.svc.GetL3Snapshot(ctx, pair)
+						if err != nil {
+							t.Error(err)
+							return
+						}
+						for _, o := range l3.Asks {
+							_ = o.Size.String()
+						}
+					}
+				}
+			}(pair, w, orders[pair][w])
+		}
+	}
+	wg.Wait()
+
+	for _, pair := range pairs {
+		l3, err := env.svc.GetL3Snapshot(ctx, pair)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resting := make(map[string]*big.Int)
+		for _, o := range append(l3.Bids, l3.Asks...) {
+			resting[o.ID] = o.Size
+		}
+		if len(l3.Bids) > 0 && len(l3.Asks) > 0 && l3.Bids[0].Price >= l3.Asks[0].Price {
+			t.Fatalf("%s: book is crossed, best bid %v >= best ask %v", pair, l3.Bids[0].Price, l3.Asks[0].Price)
+		}
+
+		// Every stored order agrees with its fills and with the book
+		for _, id := range byPair[pair] {
+			stored, err := env.store.Orders().GetByID(ctx, id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := filled[id]
+			if want == nil {
+				want = new(big.Int)
+			}
+			if stored.FilledBase.Cmp(want) != 0 {
+				t.Fatalf("%s: order %s stored with %s filled, its fills add up to %s", pair, id, stored.FilledBase, want)
+			}
+			left := new(big.Int).Sub(sizes[id], want)
+			size, inBook := resting[id]
+			switch {
+			case inBook:
+				if size.Cmp(left) != 0 || (stored.Status != domain.OrderStatusOpen && stored.Status != domain.OrderStatusPartiallyFilled) {
+					t.Fatalf("%s: order %s rests with %s but is stored %s with %s left", pair, id, size, stored.Status, left)
+				}
+			case left.Sign() == 0:
+				if stored.Status != domain.OrderStatusFilled {
+					t.Fatalf("%s: order %s is filled but stored %s", pair, id, stored.Status)
+				}
+			default:
+				if stored.Status != domain.OrderStatusCancelled {
+					t.Fatalf("%s: order %s left the book with %s unfilled but is stored %s", pair, id, left, stored.Status)
+				}
+			}
+		}
+	}
+}
+
+func TestPairActor_DoRespectsCancelledContext(t *testing.T) {
+	s := newActorOnlyService()
+	a := s.actor("TKA-TKB")
+
+	// Block the actor so that the queue fills up
+	started, release := make(chan struct{}), make(chan struct{})
+	a.cmds <- func() {
+		close(started)
+		<-release
+	}
+	<-started
+	for i := 0; i < actorQueueSize; i++ {
+		a.cmds <- func() {}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	ran := false
+	if err := a.do(ctx, func() { ran = true }); err != context.Canceled {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	close(release)
+	if ran {
+		t.Fatal("command ran after its context was cancelled")
+	}
+}
@@ -0,0 +1,199 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/nexus-orderbook-dex/backend/internal/domain"
+	"github.com/nexus-orderbook-dex/backend/internal/repository"
+)
+
+// RiskCode identifies the limit that rejected an order.
+type RiskCode string
+
+const (
+	RiskMaxOpenOrders    RiskCode = "max_open_orders"
+	RiskMaxOrderNotional RiskCode = "max_order_notional"
+	RiskMaxOpenNotional  RiskCode = "max_open_notional"
+	RiskPriceBand        RiskCode = "price_band"
+)
+
+// RiskError is an order rejected by a risk limit.
+type RiskError struct {
+	Code    RiskCode
+	Message string
+}
+
+func (e *RiskError) Error() string { return e.Message }
+
+func riskError(code RiskCode, format string, args ...interface{}) *RiskError {
+	return &RiskError{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// SetRiskLimits sets the limits of every address without an override. It
+// must be called before the service starts taking orders.
+func (s *OrderService) SetRiskLimits(limits domain.RiskLimits) {
+	s.riskLimits = limits
+}
+
+// GlobalRiskLimits returns the limits of every address without an override.
+func (s *OrderService) GlobalRiskLimits() domain.RiskLimits {
+	return s.riskLimits
+}
+
+// RiskLimits returns the limits in force for an address and its override,
+// nil if it has none.
+func (s *OrderService) RiskLimits(ctx context.Context, address string) (domain.RiskLimits, *domain.RiskOverride, error) {
+	if !common.IsHexAddress(address) {
+		return domain.RiskLimits{}, nil, fmt.Errorf("invalid address %q", address)
+	}
+	return s.riskLimitsOf(ctx, s.store, address)
+}
+
+func (s *OrderService) riskLimitsOf(ctx context.Context, repos repository.Repositories, address string) (domain.RiskLimits, *domain.RiskOverride, error) {
+	o, err := repos.Risk().GetOverride(ctx, common.HexToAddress(address).Hex())
+	if errors.Is(err, sql.ErrNoRows) {
+		return s.riskLimits, nil, nil
+	}
+	if err != nil {
+		return domain.RiskLimits{}, nil, fmt.Errorf("failed to load risk limits: %w", err)
+	}
+	return s.riskLimits.With(o), o, nil
+}
+
+// ListRiskOverrides returns every override, by address.
+func (s *OrderService) ListRiskOverrides(ctx context.Context) ([]*domain.RiskOverride, error) {
+	return s.store.Risk().ListOverrides(ctx)
+}
+
+// SetRiskOverride creates or replaces the override of o.Address. It applies
+// to the address's next order; resting orders are left alone.
+func (s *OrderService) SetRiskOverride(ctx context.Context, o *domain.RiskOverride) error {
+	if !common.IsHexAddress(o.Address) {
+		return fmt.Errorf("invalid address %q", o.Address)
+	}
+	o.Address = common.HexToAddress(o.Address).Hex()
+	if o.MaxOpenOrders != nil && *o.MaxOpenOrders < 0 {
+		return fmt.Errorf("maxOpenOrders must not be negative")
+	}
+	var err error
+	if o.MaxOrderNotional, err = tokenCaps("maxOrderNotional", o.MaxOrderNotional); err != nil {
+		return err
+	}
+	if o.MaxOpenNotional, err = tokenCaps("maxOpenNotional", o.MaxOpenNotional); err != nil {
+		return err
+	}
+	if o.PriceBand != nil && (*o.PriceBand < 0 || math.IsInf(*o.PriceBand, 0) || math.IsNaN(*o.PriceBand)) {
+		return fmt.Errorf("priceBand must be a non-negative number")
+	}
+	return s.store.Risk().PutOverride(ctx, o)
+}
+
+// tokenCaps checks the limits of a notional map and keys them by checksum
+// address.
+func tokenCaps(name string, caps map[string]*big.Int) (map[string]*big.Int, error) {
+	if caps == nil {
+		return nil, nil
+	}
+	checked := make(map[string]*big.Int, len(caps))
+	for token, limit := range caps {
+		if !common.IsHexAddress(token) {
+			return nil, fmt.Errorf("invalid %s token %q", name, token)
+		}
+		if limit == nil || limit.Sign() < 0 {
+			return nil, fmt.Errorf("%s of %s must not be negative", name, token)
+		}
+		checked[common.HexToAddress(token).Hex()] = limit
+	}
+	return checked, nil
+}
+
+// DeleteRiskOverride puts an address back on the global limits and
+// reports whether it had an override.
+func (s *OrderService) DeleteRiskOverride(ctx context.Context, address string) (bool, error) {
+	if !common.IsHexAddress(address) {
+		return false, fmt.Errorf("invalid address %q", address)
+	}
+	return s.store.Risk().DeleteOverride(ctx, common.HexToAddress(address).Hex())
+}
+
+// checkRisk returns the rejection of orders of one maker that would break
+// the maker's limits once added to its live orders, minus the order
+// replaced, if any. It runs on the pair's actor, so the pair's orders
+// cannot change under it; orders of other pairs count as last committed.
+// A rejection is not an error of the command: callers return nil from exec
+// so that the untouched book is not reloaded.
+func (s *OrderService) checkRisk(ctx context.Context, tx repository.Repositories, a *pairActor, orders []*domain.Order, replacedID string) (*RiskError, error) {
+	maker := orders[0].Maker
+	limits, _, err := s.riskLimitsOf(ctx, tx, maker)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, o := range orders {
+		// Like open notional, each quote token has its own limit
+		if limit := limits.OrderNotionalCap(o.QuoteToken()); limit != nil && limit.Sign() > 0 && o.QuoteAmount().Cmp(limit) > 0 {
+			return riskError(RiskMaxOrderNotional, "order notional %s of %s exceeds the limit of %s", o.QuoteAmount(), o.QuoteToken(), limit), nil
+		}
+		// Conditional orders are priced off their trigger, not the last trade
+		if last := a.triggers.LastPrice(); limits.PriceBand > 0 && last > 0 && !o.IsConditional() {
+			if math.Abs(o.Price()-last) > limits.PriceBand*last {
+				return riskError(RiskPriceBand, "price %g is more than %g%% away from the last trade at %g", o.Price(), limits.PriceBand*100, last), nil
+			}
+		}
+	}
+
+	checkOpen := false
+	for _, limit := range limits.MaxOpenNotional {
+		checkOpen = checkOpen || limit.Sign() > 0
+	}
+	if limits.MaxOpenOrders <= 0 && !checkOpen {
+		return nil, nil
+	}
+	live, err := tx.Orders().GetLiveByMaker(ctx, maker)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load open orders: %w", err)
+	}
+	count := len(orders)
+	offered := make(map[string]*big.Int)
+	offer := func(o *domain.Order) {
+		token := strings.ToLower(o.TokenSell)
+		if offered[token] == nil {
+			offered[token] = new(big.Int)
+		}
+		offered[token].Add(offered[token], o.RemainingSell())
+	}
+	for _, o := range live {
+		if o.ID == replacedID {
+			continue
+		}
+		if o.Pair == a.pair {
+			count++
+		}
+		offer(o)
+	}
+	if limits.MaxOpenOrders > 0 && count > limits.MaxOpenOrders {
+		return riskError(RiskMaxOpenOrders, "%s would have %d open orders on %s, the limit is %d", maker, count, a.pair, limits.MaxOpenOrders), nil
+	}
+	if !checkOpen {
+		return nil, nil
+	}
+	// Each token is held to its own limit, in its own base units
+	for _, o := range orders {
+		offer(o)
+		limit := limits.OpenNotionalCap(o.TokenSell)
+		if limit == nil || limit.Sign() <= 0 {
+			continue
+		}
+		if total := offered[strings.ToLower(o.TokenSell)]; total.Cmp(limit) > 0 {
+			return riskError(RiskMaxOpenNotional, "open orders would offer %s of %s, the limit is %s", total, o.TokenSell, limit), nil
+		}
+	}
+	return nil, nil
+}
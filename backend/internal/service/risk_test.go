@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/nexus-orderbook-dex/backend/internal/domain"
+)
+
+func riskCode(err error) RiskCode {
+	var risk *RiskError
+	if errors.As(err, &risk) {
+		return risk.Code
+	}
+	return ""
+}
+
+func TestRiskLimits_RejectWithDistinctCodes(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t, nil)
+	env.svc.SetRiskLimits(domain.RiskLimits{
+		MaxOpenOrders:    2,
+		MaxOrderNotional: map[string]*big.Int{testQuote.Hex(): big.NewInt(10000)},
+		PriceBand:        0.1,
+		MaxOpenNotional:  map[string]*big.Int{testBase.Hex(): big.NewInt(4)},
+	})
+
+	// A trade at 100 anchors the price band
+	seller, buyer, m := newTestMaker(t), newTestMaker(t), newTestMaker(t)
+	if _, _, err := env.svc.SubmitOrder(ctx, seller.order(t, domain.SideSell, 100, 1)); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := env.svc.SubmitOrder(ctx, buyer.order(t, domain.SideBuy, 100, 1)); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := env.svc.SubmitOrder(ctx, m.order(t, domain.SideBuy, 111, 1)); riskCode(err) != RiskPriceBand {
+		t.Fatalf("expected %s, got %v", RiskPriceBand, err)
+	}
+	if _, _, err := env.svc.SubmitOrder(ctx, m.order(t, domain.SideBuy, 100, 101)); riskCode(err) != RiskMaxOrderNotional {
+		t.Fatalf("expected %s, got %v", RiskMaxOrderNotional, err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, _, err := env.svc.SubmitOrder(ctx, m.order(t, domain.SideBuy, 95, 5)); err != nil {
+			t.Fatalf("order %d: %v", i, err)
+		}
+	}
+	// The maker's address in another case is the same maker
+	third := m.order(t, domain.SideBuy, 95, 1)
+	third.Maker = strings.ToLower(third.Maker)
+	if _, _, err := env.svc.SubmitOrder(ctx, third); riskCode(err) != RiskMaxOpenOrders {
+		t.Fatalf("expected %s, got %v", RiskMaxOpenOrders, err)
+	}
+
+	// Lift the order count for the maker and cap what it offers of the
+	// quote token instead: its two bids already offer 950 of it. Orders
+	// quoted in the base token get a cap of their own.
+	lifted := 0
+	if err := env.svc.SetRiskOverride(ctx, &domain.RiskOverride{
+		Address:          strings.ToLower(m.addr.Hex()),
+		MaxOpenOrders:    &lifted,
+		MaxOrderNotional: map[string]*big.Int{strings.ToLower(testBase.Hex()): big.NewInt(50)},
+		MaxOpenNotional:  map[string]*big.Int{strings.ToLower(testQuote.Hex()): big.NewInt(1200)},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	limits, override, err := env.svc.RiskLimits(ctx, m.addr.Hex())
+	if err != nil || override == nil || override.Address != m.addr.Hex() {
+		t.Fatalf("expected a checksummed override, got %+v (%v)", override, err)
+	}
+	if limits.MaxOpenOrders != 0 || limits.PriceBand != 0.1 {
+		t.Fatalf("expected the override over the global limits, got %+v", limits)
+	}
+	if q, b := limits.OrderNotionalCap(testQuote.Hex()), limits.OrderNotionalCap(testBase.Hex()); q.Int64() != 10000 || b.Int64() != 50 {
+		t.Fatalf("expected the quote token's order cap kept and the base token's added, got %s and %s", q, b)
+	}
+	if q, b := limits.OpenNotionalCap(testQuote.Hex()), limits.OpenNotionalCap(testBase.Hex()); q.Int64() != 1200 || b.Int64() != 4 {
+		t.Fatalf("expected the quote cap overridden and the base cap kept, got %s and %s", q, b)
+	}
+	if _, _, err := env.svc.SubmitOrder(ctx, m.order(t, domain.SideBuy, 95, 3)); riskCode(err) != RiskMaxOpenNotional {
+		t.Fatalf("expected %s, got %v", RiskMaxOpenNotional, err)
+	}
+	// Selling draws on the base token, held to its own cap in its own units
+	if _, _, err := env.svc.SubmitOrder(ctx, m.order(t, domain.SideSell, 105, 3)); err != nil {
+		t.Fatalf("expected the sell to pass: %v", err)
+	}
+	if _, _, err := env.svc.SubmitOrder(ctx, m.order(t, domain.SideSell, 105, 2)); riskCode(err) != RiskMaxOpenNotional {
+		t.Fatalf("expected %s, got %v", RiskMaxOpenNotional, err)
+	}
+	if _, _, err := env.svc.SubmitOrder(ctx, m.order(t, domain.SideBuy, 95, 2)); err != nil {
+		t.Fatalf("expected a bid within the cap to pass: %v", err)
+	}
+
+	// Rejected orders never reached the book or the store
+	live, err := env.store.Orders().GetLiveByMaker(ctx, m.addr.Hex())
+	if err != nil || len(live) != 4 {
+		t.Fatalf("expected 4 live orders, got %d (%v)", len(live), err)
+	}
+
+	if deleted, err := env.svc.DeleteRiskOverride(ctx, m.addr.Hex()); err != nil || !deleted {
+		t.Fatalf("expected the override deleted, got %v (%v)", deleted, err)
+	}
+	if _, _, err := env.svc.SubmitOrder(ctx, m.order(t, domain.SideBuy, 95, 1)); riskCode(err) != RiskMaxOpenOrders {
+		t.Fatalf("expected the global limits back, got %v", err)
+	}
+}
+
+func TestRiskLimits_ReplacementDoesNotCountTheReplacedOrder(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t, nil)
+	env.svc.SetRiskLimits(domain.RiskLimits{MaxOpenOrders: 1, MaxOpenNotional: map[string]*big.Int{testQuote.Hex(): big.NewInt(1000)}})
+	m := newTestMaker(t)
+
+	order, _, err := env.svc.SubmitOrder(ctx, m.order(t, domain.SideBuy, 100, 10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	replacement, _, _, err := env.svc.ReplaceOrder(ctx, order.ID, m.order(t, domain.SideBuy, 100, 9))
+	if err != nil {
+		t.Fatalf("expected the replacement to pass: %v", err)
+	}
+	if _, _, _, err := env.svc.ReplaceOrder(ctx, replacement.ID, m.order(t, domain.SideBuy, 100, 11)); riskCode(err) != RiskMaxOpenNotional {
+		t.Fatalf("expected %s, got %v", RiskMaxOpenNotional, err)
+	}
+	if stored, err := env.store.Orders().GetByID(ctx, replacement.ID); err != nil || stored.Status != domain.OrderStatusOpen {
+		t.Fatalf("expected the rejected replacement to leave the order open, got %+v (%v)", stored, err)
+	}
+}
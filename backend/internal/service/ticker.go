@@ -0,0 +1,232 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/nexus-orderbook-dex/backend/internal/domain"
+	ob "github.com/nexus-orderbook-dex/backend/internal/orderbook"
+	"github.com/nexus-orderbook-dex/backend/internal/repository"
+)
+
+// tickerWindow is the rolling period of ticker statistics, tracked to the
+// minute.
+const tickerWindow = 24 * time.Hour
+
+// pairTicker is the state behind a pair's ticker. It is never modified once
+// stored, so readers can use it without holding the lock.
+type pairTicker struct {
+	pair     string
+	minutes  []*domain.Candle // 1m bars of the window, oldest first
+	last     float64          // price of the latest trade, even outside the window
+	bid, ask *float64
+}
+
+// with returns a copy of p with trades folded in and the given top of book.
+func (p *pairTicker) with(trades []*domain.Trade, bid, ask *float64) *pairTicker {
+	next := &pairTicker{pair: p.pair, minutes: p.minutes, last: p.last, bid: bid, ask: ask}
+	if len(trades) > 0 {
+		next.minutes = append([]*domain.Candle(nil), p.minutes...)
+	}
+	for _, trade := range trades {
+		bar := domain.NewCandle(domain.Interval1m, trade)
+		if n := len(next.minutes); n > 0 && next.minutes[n-1].OpenTime.Equal(bar.OpenTime) {
+			merged := next.minutes[n-1].Clone()
+			merged.Merge(bar)
+			next.minutes[n-1] = merged
+		} else {
+			next.minutes = append(next.minutes, bar)
+		}
+		next.last = trade.MarketPrice()
+	}
+	return next
+}
+
+// prune drops the minutes that left the window by now.
+func (p *pairTicker) prune(now time.Time) {
+	start := now.Add(-tickerWindow)
+	i := 0
+	for i < len(p.minutes) && !p.minutes[i].OpenTime.Add(time.Minute).After(start) {
+		i++
+	}
+	p.minutes = p.minutes[i:]
+}
+
+func (p *pairTicker) ticker(now time.Time) domain.Ticker {
+	t := domain.Ticker{
+		Pair:        p.pair,
+		LastPrice:   p.last,
+		BestBid:     p.bid,
+		BestAsk:     p.ask,
+		BaseVolume:  new(big.Int),
+		QuoteVolume: new(big.Int),
+		Time:        now,
+	}
+	start := now.Add(-tickerWindow)
+	for _, bar := range p.minutes {
+		if !bar.OpenTime.Add(time.Minute).After(start) {
+			continue
+		}
+		if t.TradeCount == 0 {
+			t.Open, t.High, t.Low = bar.Open, bar.High, bar.Low
+		}
+		t.High = math.Max(t.High, bar.High)
+		t.Low = math.Min(t.Low, bar.Low)
+		t.BaseVolume.Add(t.BaseVolume, bar.BaseVolume)
+		t.QuoteVolume.Add(t.QuoteVolume, bar.QuoteVolume)
+		t.TradeCount += bar.TradeCount
+	}
+	if t.TradeCount > 0 {
+		t.PriceChange = t.LastPrice - t.Open
+		t.PriceChangePercent = t.PriceChange / t.Open * 100
+	}
+	return t
+}
+
+// tickerStats holds the tickers of every pair. Commands compute the next
+// state of their pair inside their transaction and store it once it
+// commits, so tickers only reflect committed trades.
+type tickerStats struct {
+	mu    sync.RWMutex
+	pairs map[string]*pairTicker
+}
+
+func newTickerStats() *tickerStats {
+	return &tickerStats{pairs: make(map[string]*pairTicker)}
+}
+
+func (ts *tickerStats) get(pair string) *pairTicker {
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+	if p, ok := ts.pairs[pair]; ok {
+		return p
+	}
+	return &pairTicker{pair: pair}
+}
+
+func (ts *tickerStats) set(p *pairTicker) {
+	p.prune(time.Now())
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	ts.pairs[p.pair] = p
+}
+
+// bookTop returns the best bid and ask of a book, nil for an empty side.
+func bookTop(book *ob.OrderBook) (bid, ask *float64) {
+	if price, ok := book.BestBid(); ok {
+		bid = &price
+	}
+	if price, ok := book.BestAsk(); ok {
+		ask = &price
+	}
+	return bid, ask
+}
+
+func samePrice(a, b *float64) bool {
+	return a == nil && b == nil || a != nil && b != nil && *a == *b
+}
+
+// nextTicker returns the ticker state of the actor's pair after the running
+// command, and queues a ticker update if the command traded or moved the
+// top of the book. It must run on the actor, inside exec.
+func (s *OrderService) nextTicker(ctx context.Context, tx repository.Repositories, a *pairActor) (*pairTicker, error) {
+	prev := s.tickers.get(a.pair)
+	bid, ask := bookTop(a.book)
+	next := prev.with(a.trades, bid, ask)
+	if len(a.trades) == 0 && samePrice(prev.bid, bid) && samePrice(prev.ask, ask) {
+		return next, nil
+	}
+	ticker := next.ticker(time.Now())
+	return next, publish(ctx, tx, repository.ChannelTicker, a.pair, map[string]interface{}{
+		"type":   "ticker",
+		"ticker": ticker,
+	})
+}
+
+// GetTicker returns the ticker of a pair, false if the pair has neither a
+// book nor trades.
+func (s *OrderService) GetTicker(pair string) (domain.Ticker, bool) {
+	s.tickers.mu.RLock()
+	p, ok := s.tickers.pairs[pair]
+	s.tickers.mu.RUnlock()
+	if !ok {
+		return domain.Ticker{}, false
+	}
+	return p.ticker(time.Now()), true
+}
+
+// GetTickers returns the ticker of every known pair, ordered by pair.
+func (s *OrderService) GetTickers() []domain.Ticker {
+	s.tickers.mu.RLock()
+	pairs := make([]*pairTicker, 0, len(s.tickers.pairs))
+	for _, p := range s.tickers.pairs {
+		pairs = append(pairs, p)
+	}
+	s.tickers.mu.RUnlock()
+
+	now := time.Now()
+	tickers := make([]domain.Ticker, len(pairs))
+	for i, p := range pairs {
+		tickers[i] = p.ticker(now)
+	}
+	sort.Slice(tickers, func(i, j int) bool { return tickers[i].Pair < tickers[j].Pair })
+	return tickers
+}
+
+// RestoreTickers rebuilds the tickers from the trades of the last 24 hours
+// and the restored books. It runs at startup, after RestoreBooks.
+func (s *OrderService) RestoreTickers(ctx context.Context) error {
+	var trades []*domain.Trade
+	q := domain.TradeQuery{From: time.Now().Add(-tickerWindow), Limit: MaxPageSize}
+	for {
+		page, err := s.store.Trades().List(ctx, q)
+		if err != nil {
+			return fmt.Errorf("load recent trades: %w", err)
+		}
+		trades = append(trades, page...)
+		if len(page) < q.Limit {
+			break
+		}
+		last := page[len(page)-1]
+		q.After = &domain.Cursor{CreatedAt: last.CreatedAt, ID: last.ID}
+	}
+
+	// Pages are newest first; fold each pair's trades oldest first
+	byPair := make(map[string][]*domain.Trade)
+	for i := len(trades) - 1; i >= 0; i-- {
+		byPair[trades[i].Pair] = append(byPair[trades[i].Pair], trades[i])
+	}
+	s.mu.RLock()
+	for pair := range s.actors {
+		if _, ok := byPair[pair]; !ok {
+			byPair[pair] = nil
+		}
+	}
+	s.mu.RUnlock()
+
+	for pair, pairTrades := range byPair {
+		p := (&pairTicker{pair: pair}).with(pairTrades, nil, nil)
+		if len(pairTrades) == 0 {
+			// Quiet for a day: the last price comes from the latest trade
+			latest, err := s.store.Trades().List(ctx, domain.TradeQuery{Pair: pair, Limit: 1})
+			if err != nil {
+				return fmt.Errorf("load last trade of %s: %w", pair, err)
+			}
+			if len(latest) > 0 {
+				p.last = latest[0].MarketPrice()
+			}
+		}
+		if a, ok := s.lookupActor(pair); ok {
+			if err := a.do(ctx, func() { p.bid, p.ask = bookTop(a.book) }); err != nil {
+				return err
+			}
+		}
+		s.tickers.set(p)
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/nexus-orderbook-dex/backend/internal/domain"
+	"github.com/nexus-orderbook-dex/backend/internal/repository"
+)
+
+func TestTicker_TracksTradesAndTopOfBook(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t, nil)
+	seller, buyer := newTestMaker(t), newTestMaker(t)
+
+	// Trades at 100, 120 and 90, then a resting bid at 80 and ask at 130
+	for _, price := range []int64{100, 120, 90} {
+		if _, _, err := env.svc.SubmitOrder(ctx, seller.order(t, domain.SideSell, price, 2)); err != nil {
+			t.Fatalf("submit sell: %v", err)
+		}
+		if _, _, err := env.svc.SubmitOrder(ctx, buyer.order(t, domain.SideBuy, price, 2)); err != nil {
+			t.Fatalf("submit buy: %v", err)
+		}
+	}
+	if _, _, err := env.svc.SubmitOrder(ctx, buyer.order(t, domain.SideBuy, 80, 1)); err != nil {
+		t.Fatalf("submit bid: %v", err)
+	}
+	if _, _, err := env.svc.SubmitOrder(ctx, seller.order(t, domain.SideSell, 130, 1)); err != nil {
+		t.Fatalf("submit ask: %v", err)
+	}
+
+	check := func(tk domain.Ticker) {
+		t.Helper()
+		if tk.LastPrice != 90 || tk.Open != 100 || tk.High != 120 || tk.Low != 90 {
+			t.Fatalf("unexpected prices %+v", tk)
+		}
+		if tk.TradeCount != 3 || tk.BaseVolume.Int64() != 6 || tk.QuoteVolume.Int64() != 620 {
+			t.Fatalf("unexpected volume %+v", tk)
+		}
+		if tk.PriceChange != -10 || tk.PriceChangePercent != -10 {
+			t.Fatalf("expected a 10%% drop, got %v (%v%%)", tk.PriceChange, tk.PriceChangePercent)
+		}
+		if tk.BestBid == nil || *tk.BestBid != 80 || tk.BestAsk == nil || *tk.BestAsk != 130 {
+			t.Fatalf("unexpected top of book %v / %v", tk.BestBid, tk.BestAsk)
+		}
+	}
+
+	tk, ok := env.svc.GetTicker(testPair)
+	if !ok {
+		t.Fatal("no ticker for the traded pair")
+	}
+	check(tk)
+	if all := env.svc.GetTickers(); len(all) != 1 || all[0].Pair != testPair {
+		t.Fatalf("expected one ticker, got %+v", all)
+	}
+	if _, ok := env.svc.GetTicker("NOPE-PAIR"); ok {
+		t.Fatal("expected no ticker for an unknown pair")
+	}
+
+	// The last update went out with the command that moved the ask
+	if _, err := env.relay.drain(ctx); err != nil {
+		t.Fatalf("drain: %v", err)
+	}
+	var last domain.Ticker
+	for _, raw := range env.cache.Published(repository.ChannelTicker, testPair) {
+		var msg struct {
+			Type   string        `json:"type"`
+			Ticker domain.Ticker `json:"ticker"`
+		}
+		if err := json.Unmarshal(raw, &msg); err != nil {
+			t.Fatal(err)
+		}
+		if msg.Type == "ticker" {
+			last = msg.Ticker
+		}
+	}
+	check(last)
+
+	// A restarted service rebuilds the same ticker from the store
+	restarted := newTestEnv(t, env.store)
+	if err := restarted.svc.RestoreBooks(ctx); err != nil {
+		t.Fatalf("restore books: %v", err)
+	}
+	if err := restarted.svc.RestoreTickers(ctx); err != nil {
+		t.Fatalf("restore tickers: %v", err)
+	}
+	tk, ok = restarted.svc.GetTicker(testPair)
+	if !ok {
+		t.Fatal("no ticker after restore")
+	}
+	check(tk)
+}
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/nexus-orderbook-dex/backend/internal/domain"
+	"github.com/nexus-orderbook-dex/backend/internal/repository"
+)
+
+// tradeMessage is one trade on its pair's public trades channel.
+type tradeMessage struct {
+	Type          string      `json:"type"`
+	ID            string      `json:"id"`
+	Pair          string      `json:"pair"`
+	Price         float64     `json:"price"`
+	Size          string      `json:"size"`                    // base token units
+	QuoteSize     string      `json:"quoteSize"`               // quote token units
+	TakerSide     domain.Side `json:"takerSide,omitempty"`     // none for auction fills
+	ClearingPrice float64     `json:"clearingPrice,omitempty"` // auction fills only
+	Time          time.Time   `json:"time"`
+}
+
+// tradeSettledMessage confirms that a published trade settled on chain.
+type tradeSettledMessage struct {
+	Type   string `json:"type"`
+	ID     string `json:"id"`
+	Pair   string `json:"pair"`
+	TxHash string `json:"txHash"`
+}
+
+// publishTrade queues a new trade for the pair's trades channel.
+func publishTrade(ctx context.Context, tx repository.Repositories, trade *domain.Trade, takerSide domain.Side) error {
+	return publish(ctx, tx, repository.ChannelTrades, trade.Pair, tradeMessage{
+		Type:          "trade",
+		ID:            trade.ID,
+		Pair:          trade.Pair,
+		Price:         trade.Price,
+		Size:          trade.BaseAmount.String(),
+		QuoteSize:     trade.QuoteAmount.String(),
+		TakerSide:     takerSide,
+		ClearingPrice: trade.ClearingPrice,
+		Time:          trade.CreatedAt,
+	})
+}
+
+// publishTradeSettled queues the settlement confirmation of a trade.
+func publishTradeSettled(ctx context.Context, tx repository.Repositories, pair, tradeID, txHash string) error {
+	return publish(ctx, tx, repository.ChannelTrades, pair, tradeSettledMessage{
+		Type:   "trade_settled",
+		ID:     tradeID,
+		Pair:   pair,
+		TxHash: txHash,
+	})
+}
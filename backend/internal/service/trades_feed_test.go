@@ -0,0 +1,57 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/nexus-orderbook-dex/backend/internal/domain"
+	"github.com/nexus-orderbook-dex/backend/internal/repository"
+)
+
+func TestTradesFeed_PublishesTradesThenSettlement(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t, nil)
+	seller, buyer := newTestMaker(t), newTestMaker(t)
+
+	if _, _, err := env.svc.SubmitOrder(ctx, seller.order(t, domain.SideSell, 100, 10)); err != nil {
+		t.Fatalf("submit: %v", err)
+	}
+	if _, _, err := env.svc.SubmitOrder(ctx, buyer.order(t, domain.SideBuy, 100, 4)); err != nil {
+		t.Fatalf("submit: %v", err)
+	}
+
+	var trade tradeMessage
+	var settled *tradeSettledMessage
+	deadline := time.Now().Add(5 * time.Second)
+	for settled == nil && time.Now().Before(deadline) {
+		if _, err := env.relay.drain(ctx); err != nil {
+			t.Fatalf("drain: %v", err)
+		}
+		for _, raw := range env.cache.Published(repository.ChannelTrades, testPair) {
+			var msg struct {
+				Type string `json:"type"`
+			}
+			if err := json.Unmarshal(raw, &msg); err != nil {
+				t.Fatal(err)
+			}
+			switch msg.Type {
+			case "trade":
+				json.Unmarshal(raw, &trade)
+			case "trade_settled":
+				settled = new(tradeSettledMessage)
+				json.Unmarshal(raw, settled)
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if trade.ID == "" || trade.Price != 100 || trade.Size != "4" || trade.QuoteSize != "400" ||
+		trade.TakerSide != domain.SideBuy || trade.Time.IsZero() {
+		t.Fatalf("unexpected trade %+v", trade)
+	}
+	if settled == nil || settled.ID != trade.ID || settled.TxHash != "0x_mock" {
+		t.Fatalf("expected the trade's settlement with its tx hash, got %+v", settled)
+	}
+}
@@ -0,0 +1,154 @@
+package service
+
+import (
+	"context"
+	"math/big"
+	"time"
+
+	"github.com/nexus-orderbook-dex/backend/internal/domain"
+	ob "github.com/nexus-orderbook-dex/backend/internal/orderbook"
+	"github.com/nexus-orderbook-dex/backend/internal/repository"
+)
+
+// orderEvent is what happened to an order, as told on its maker's user
+// channel.
+type orderEvent string
+
+const (
+	orderAccepted        orderEvent = "accepted"
+	orderTriggered       orderEvent = "triggered"
+	orderPartiallyFilled orderEvent = "partially_filled"
+	orderFilled          orderEvent = "filled"
+	orderCancelled       orderEvent = "cancelled"
+	orderExpired         orderEvent = "expired"
+	orderUpdated         orderEvent = "updated" // group leg resized, suspended or activated
+)
+
+// orderEventOf names the change that left an order in status.
+func orderEventOf(status domain.OrderStatus) orderEvent {
+	switch status {
+	case domain.OrderStatusPartiallyFilled:
+		return orderPartiallyFilled
+	case domain.OrderStatusFilled:
+		return orderFilled
+	case domain.OrderStatusCancelled:
+		return orderCancelled
+	case domain.OrderStatusExpired:
+		return orderExpired
+	}
+	return orderUpdated
+}
+
+type orderMessage struct {
+	Type  string        `json:"type"`
+	Event orderEvent    `json:"event"`
+	Order *domain.Order `json:"order"`
+}
+
+// Settlement states of a fill.
+const (
+	SettlementPending = "pending"
+	SettlementSettled = "settled"
+	SettlementFailed  = "failed"
+)
+
+// fillMessage is one side of a trade, sent to that side's maker when the
+// trade is made and again once its settlement is confirmed or fails.
+type fillMessage struct {
+	Type          string      `json:"type"`
+	TradeID       string      `json:"tradeId"`
+	OrderID       string      `json:"orderId"`
+	Pair          string      `json:"pair"`
+	Side          domain.Side `json:"side"`
+	Price         float64     `json:"price"`
+	ClearingPrice float64     `json:"clearingPrice,omitempty"` // auction fills only
+	BaseAmount    string      `json:"baseAmount"`
+	QuoteAmount   string      `json:"quoteAmount"`
+	Settlement    string      `json:"settlement"`
+	TxHash        string      `json:"txHash,omitempty"`
+	Error         string      `json:"error,omitempty"`
+	Time          time.Time   `json:"time"`
+}
+
+// Reasons of a balance change.
+const (
+	balanceTrade    = "trade"
+	balanceDeposit  = "deposit"
+	balanceWithdraw = "withdraw"
+)
+
+// balanceMessage is a change of a user's vault balance of one token.
+type balanceMessage struct {
+	Type    string `json:"type"`
+	Token   string `json:"token"`
+	Delta   string `json:"delta"` // signed, in token units
+	Reason  string `json:"reason"`
+	TradeID string `json:"tradeId,omitempty"`
+	TxHash  string `json:"txHash,omitempty"`
+}
+
+// publishUser queues a message for the user channel of an address.
+func publishUser(ctx context.Context, tx repository.Repositories, address string, data interface{}) error {
+	return publish(ctx, tx, repository.ChannelUser, repository.UserKey(address), data)
+}
+
+// publishOrder queues an order event for the order's maker. The order is
+// encoded right away, so later changes to it are not seen.
+func publishOrder(ctx context.Context, tx repository.Repositories, order *domain.Order, event orderEvent) error {
+	return publishUser(ctx, tx, order.Maker, orderMessage{Type: "order", Event: event, Order: order})
+}
+
+// publishFills queues the fill of a trade to both of its makers.
+func publishFills(ctx context.Context, tx repository.Repositories, tradeID string, match ob.MatchResult, settlement, txHash, errMsg string) error {
+	now := time.Now()
+	for _, order := range []*domain.Order{match.BuyOrder, match.SellOrder} {
+		fill := fillMessage{
+			Type:          "fill",
+			TradeID:       tradeID,
+			OrderID:       order.ID,
+			Pair:          order.Pair,
+			Side:          order.Side,
+			Price:         match.Price,
+			ClearingPrice: match.ClearingPrice,
+			BaseAmount:    match.FillAmount.String(),
+			QuoteAmount:   match.QuoteAmount.String(),
+			Settlement:    settlement,
+			TxHash:        txHash,
+			Error:         errMsg,
+			Time:          now,
+		}
+		if err := publishUser(ctx, tx, order.Maker, fill); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// publishTradeBalances queues the vault balance changes of a settled trade:
+// the buyer pays quote for base and the seller the reverse.
+func publishTradeBalances(ctx context.Context, tx repository.Repositories, tradeID string, match ob.MatchResult, txHash string) error {
+	base, quote := match.BuyOrder.TokenBuy, match.BuyOrder.TokenSell
+	changes := []struct {
+		maker, token string
+		delta        *big.Int
+	}{
+		{match.BuyOrder.Maker, base, match.FillAmount},
+		{match.BuyOrder.Maker, quote, new(big.Int).Neg(match.QuoteAmount)},
+		{match.SellOrder.Maker, base, new(big.Int).Neg(match.FillAmount)},
+		{match.SellOrder.Maker, quote, match.QuoteAmount},
+	}
+	for _, c := range changes {
+		msg := balanceMessage{
+			Type:    "balance",
+			Token:   c.token,
+			Delta:   c.delta.String(),
+			Reason:  balanceTrade,
+			TradeID: tradeID,
+			TxHash:  txHash,
+		}
+		if err := publishUser(ctx, tx, c.maker, msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
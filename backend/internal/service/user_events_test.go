@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/nexus-orderbook-dex/backend/internal/domain"
+	"github.com/nexus-orderbook-dex/backend/internal/repository"
+	"github.com/nexus-orderbook-dex/backend/pkg/eip712"
+)
+
+// userMessages returns the messages of an address's user channel so far.
+func userMessages(t *testing.T, env *testEnv, m *testMaker) []map[string]interface{} {
+	t.Helper()
+	var msgs []map[string]interface{}
+	for _, raw := range env.cache.Published(repository.ChannelUser, repository.UserKey(m.addr.Hex())) {
+		var msg map[string]interface{}
+		if err := json.Unmarshal(raw, &msg); err != nil {
+			t.Fatal(err)
+		}
+		msgs = append(msgs, msg)
+	}
+	return msgs
+}
+
+func TestUserChannel_OrdersFillsAndBalances(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t, nil)
+	seller, buyer := newTestMaker(t), newTestMaker(t)
+
+	ask, _, err := env.svc.SubmitOrder(ctx, seller.order(t, domain.SideSell, 100, 10))
+	if err != nil {
+		t.Fatalf("submit: %v", err)
+	}
+	if _, _, err := env.svc.SubmitOrder(ctx, buyer.order(t, domain.SideBuy, 100, 4)); err != nil {
+		t.Fatalf("submit: %v", err)
+	}
+	if err := env.svc.CancelOrder(ctx, ask.ID); err != nil {
+		t.Fatalf("cancel: %v", err)
+	}
+
+	// The relay queues the confirmed fills once the settlement worker
+	// answers, then delivers them on its next drain
+	var msgs []map[string]interface{}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err := env.relay.drain(ctx); err != nil {
+			t.Fatalf("drain: %v", err)
+		}
+		msgs = userMessages(t, env, seller)
+		if len(msgs) >= 7 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	var got []string
+	for _, msg := range msgs {
+		switch msg["type"] {
+		case "order":
+			got = append(got, "order:"+msg["event"].(string))
+		case "fill":
+			got = append(got, "fill:"+msg["settlement"].(string))
+			if msg["settlement"] == SettlementSettled && msg["txHash"] != "0x_mock" {
+				t.Fatalf("settled fill without its tx hash: %v", msg)
+			}
+		case "balance":
+			got = append(got, "balance:"+msg["delta"].(string))
+		}
+	}
+	want := []string{
+		"order:accepted",
+		"order:partially_filled",
+		"fill:pending",
+		"order:cancelled",
+		"fill:settled",
+		"balance:-4",
+		"balance:400",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, got)
+		}
+	}
+
+	// The buyer hears only of its own order
+	for _, msg := range userMessages(t, env, buyer) {
+		if order, ok := msg["order"].(map[string]interface{}); ok && order["id"] == ask.ID {
+			t.Fatalf("buyer was told about the seller's order: %v", msg)
+		}
+	}
+}
+
+func TestVerifyLogin(t *testing.T) {
+	env := newTestEnv(t, nil)
+	user := newTestMaker(t)
+	nonce := crypto.Keccak256Hash([]byte("challenge"))
+
+	domainSep := eip712.NewDomainSeparator(testChainID, testContract)
+	digest := eip712.HashTypedData(domainSep.Hash(), eip712.HashLogin(eip712.LoginData{Account: user.addr, Nonce: nonce}))
+	sig, err := crypto.Sign(digest.Bytes(), user.key)
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	sig[64] += 27
+	signature := "0x" + hex.EncodeToString(sig)
+
+	if err := env.svc.VerifyLogin(user.addr, nonce, signature); err != nil {
+		t.Fatalf("expected a valid login, got %v", err)
+	}
+	if err := env.svc.VerifyLogin(newTestMaker(t).addr, nonce, signature); err == nil {
+		t.Fatal("expected another account's login to fail")
+	}
+	if err := env.svc.VerifyLogin(user.addr, crypto.Keccak256Hash([]byte("replayed")), signature); err == nil {
+		t.Fatal("expected a login for another nonce to fail")
+	}
+}
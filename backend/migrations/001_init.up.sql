@@ -17,9 +17,9 @@ CREATE TABLE IF NOT EXISTS orders (
     updated_at      TIMESTAMPTZ NOT NULL DEFAULT NOW()
 );
 
-CREATE INDEX idx_orders_maker ON orders(maker);
-CREATE INDEX idx_orders_pair_status ON orders(pair, status);
-CREATE INDEX idx_orders_pair_side_status ON orders(pair, side, status);
+CREATE INDEX IF NOT EXISTS idx_orders_maker ON orders(maker);
+CREATE INDEX IF NOT EXISTS idx_orders_pair_status ON orders(pair, status);
+CREATE INDEX IF NOT EXISTS idx_orders_pair_side_status ON orders(pair, side, status);
 
 CREATE TABLE IF NOT EXISTS trades (
     id              TEXT PRIMARY KEY,
@@ -36,5 +36,5 @@ CREATE TABLE IF NOT EXISTS trades (
     created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW()
 );
 
-CREATE INDEX idx_trades_pair ON trades(pair);
-CREATE INDEX idx_trades_created_at ON trades(created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_trades_pair ON trades(pair);
+CREATE INDEX IF NOT EXISTS idx_trades_created_at ON trades(created_at DESC);
@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS idx_orders_pair_pending;
+
+ALTER TABLE orders DROP CONSTRAINT IF EXISTS orders_status_check;
+ALTER TABLE orders ADD CONSTRAINT orders_status_check CHECK (status IN ('open', 'partially_filled', 'filled', 'cancelled'));
+
+ALTER TABLE orders DROP CONSTRAINT IF EXISTS orders_order_type_check;
+ALTER TABLE orders DROP COLUMN IF EXISTS triggered_at;
+ALTER TABLE orders DROP COLUMN IF EXISTS trigger_price;
+ALTER TABLE orders DROP COLUMN IF EXISTS order_type;
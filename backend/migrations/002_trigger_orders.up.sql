@@ -0,0 +1,11 @@
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS order_type TEXT NOT NULL DEFAULT 'limit';
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS trigger_price DOUBLE PRECISION NOT NULL DEFAULT 0;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS triggered_at TIMESTAMPTZ;
+
+ALTER TABLE orders DROP CONSTRAINT IF EXISTS orders_order_type_check;
+ALTER TABLE orders ADD CONSTRAINT orders_order_type_check CHECK (order_type IN ('limit', 'stop_loss', 'take_profit'));
+
+ALTER TABLE orders DROP CONSTRAINT IF EXISTS orders_status_check;
+ALTER TABLE orders ADD CONSTRAINT orders_status_check CHECK (status IN ('open', 'partially_filled', 'filled', 'cancelled', 'pending'));
+
+CREATE INDEX IF NOT EXISTS idx_orders_pair_pending ON orders(pair) WHERE status = 'pending';
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_orders_group;
+
+ALTER TABLE orders DROP COLUMN IF EXISTS base_cap;
+ALTER TABLE orders DROP COLUMN IF EXISTS group_role;
+ALTER TABLE orders DROP COLUMN IF EXISTS group_id;
+
+DROP TABLE IF EXISTS order_groups;
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS order_groups (
+    id              TEXT PRIMARY KEY,
+    type            TEXT NOT NULL CHECK (type IN ('oco', 'bracket')),
+    pair            TEXT NOT NULL,
+    maker           TEXT NOT NULL,
+    status          TEXT NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'completed', 'cancelled')),
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at      TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_groups_pair_status ON order_groups(pair, status);
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS group_id TEXT REFERENCES order_groups(id);
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS group_role TEXT CHECK (group_role IN ('leg', 'entry', 'exit'));
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS base_cap NUMERIC(78,0);
+
+CREATE INDEX IF NOT EXISTS idx_orders_group ON orders(group_id) WHERE group_id IS NOT NULL;
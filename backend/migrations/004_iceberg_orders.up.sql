@@ -0,0 +1 @@
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS display_base NUMERIC(78,0);
@@ -0,0 +1 @@
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS replaces_id TEXT REFERENCES orders(id);
@@ -0,0 +1,4 @@
+ALTER TABLE orders DROP CONSTRAINT IF EXISTS orders_status_check;
+ALTER TABLE orders ADD CONSTRAINT orders_status_check CHECK (status IN ('open', 'partially_filled', 'filled', 'cancelled', 'pending'));
+
+DROP TABLE IF EXISTS order_events;
@@ -0,0 +1,15 @@
+-- Append-only journal of matching engine events, numbered per pair
+CREATE TABLE IF NOT EXISTS order_events (
+    pair       TEXT NOT NULL,
+    seq        BIGINT NOT NULL,
+    type       TEXT NOT NULL,
+    order_id   TEXT,
+    payload    JSONB NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (pair, seq)
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_events_order ON order_events(order_id) WHERE order_id IS NOT NULL;
+
+ALTER TABLE orders DROP CONSTRAINT IF EXISTS orders_status_check;
+ALTER TABLE orders ADD CONSTRAINT orders_status_check CHECK (status IN ('open', 'partially_filled', 'filled', 'cancelled', 'pending', 'expired'));
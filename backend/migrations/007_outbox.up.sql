@@ -0,0 +1,13 @@
+-- Side effects (settlement jobs, WebSocket publications) written in the same
+-- transaction as the engine command that caused them, drained by a relay
+CREATE TABLE IF NOT EXISTS outbox (
+    id           BIGSERIAL PRIMARY KEY,
+    topic        TEXT NOT NULL,
+    payload      JSONB NOT NULL,
+    attempts     INT NOT NULL DEFAULT 0,
+    last_error   TEXT,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    processed_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_outbox_pending ON outbox(id) WHERE processed_at IS NULL;
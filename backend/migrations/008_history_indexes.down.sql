@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_trades_seller_created;
+DROP INDEX IF EXISTS idx_trades_buyer_created;
+DROP INDEX IF EXISTS idx_trades_pair_created;
+DROP INDEX IF EXISTS idx_orders_maker_created;
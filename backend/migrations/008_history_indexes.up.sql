@@ -0,0 +1,5 @@
+-- Keyset pagination of order and trade history, newest first
+CREATE INDEX IF NOT EXISTS idx_orders_maker_created ON orders(maker, created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_trades_pair_created ON trades(pair, created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_trades_buyer_created ON trades(buyer, created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_trades_seller_created ON trades(seller, created_at DESC, id DESC);
@@ -0,0 +1,16 @@
+-- OHLCV bars per pair and interval, merged as trades are created
+CREATE TABLE IF NOT EXISTS candles (
+    pair         TEXT NOT NULL,
+    resolution   TEXT NOT NULL,
+    open_time    TIMESTAMPTZ NOT NULL,
+    open         DOUBLE PRECISION NOT NULL,
+    high         DOUBLE PRECISION NOT NULL,
+    low          DOUBLE PRECISION NOT NULL,
+    close        DOUBLE PRECISION NOT NULL,
+    base_volume  NUMERIC(78,0) NOT NULL,
+    quote_volume NUMERIC(78,0) NOT NULL,
+    trade_count  INT NOT NULL,
+    PRIMARY KEY (pair, resolution, open_time)
+);
+
+CREATE INDEX IF NOT EXISTS idx_candles_resolution_open_time ON candles(resolution, open_time DESC);
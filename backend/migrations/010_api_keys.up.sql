@@ -0,0 +1,12 @@
+-- HMAC API keys bound to an address, with the scopes they grant
+CREATE TABLE IF NOT EXISTS api_keys (
+    id         TEXT PRIMARY KEY,
+    address    TEXT NOT NULL,
+    secret     TEXT NOT NULL,
+    label      TEXT NOT NULL DEFAULT '',
+    scopes     TEXT[] NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    revoked_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_address ON api_keys(address, created_at DESC);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_orders_maker_live;
+DROP TABLE IF EXISTS risk_overrides;
@@ -0,0 +1,13 @@
+-- Per-address overrides of the global risk limits; NULL keeps the global value
+CREATE TABLE IF NOT EXISTS risk_overrides (
+    address            TEXT PRIMARY KEY,
+    max_open_orders    INTEGER,
+    max_order_notional NUMERIC(78,0),
+    max_open_notional  NUMERIC(78,0),
+    price_band         DOUBLE PRECISION,
+    updated_at         TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Risk checks load a maker's live orders on every submission
+CREATE INDEX IF NOT EXISTS idx_orders_maker_live ON orders(LOWER(maker))
+    WHERE status IN ('open', 'partially_filled', 'pending');
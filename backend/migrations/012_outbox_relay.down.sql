@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS outbox_lease;
+
+DROP INDEX IF EXISTS idx_outbox_pending;
+CREATE INDEX IF NOT EXISTS idx_outbox_pending ON outbox(id) WHERE processed_at IS NULL;
+
+ALTER TABLE outbox DROP COLUMN IF EXISTS dead_at;
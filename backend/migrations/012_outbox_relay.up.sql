@@ -0,0 +1,14 @@
+-- Messages that failed their last attempt are set aside instead of
+-- blocking the messages of their topic behind them
+ALTER TABLE outbox ADD COLUMN IF NOT EXISTS dead_at TIMESTAMPTZ;
+
+DROP INDEX IF EXISTS idx_outbox_pending;
+CREATE INDEX IF NOT EXISTS idx_outbox_pending ON outbox(topic, id)
+    WHERE processed_at IS NULL AND dead_at IS NULL;
+
+-- Only the relay holding the lease delivers, so that messages leave in order
+CREATE TABLE IF NOT EXISTS outbox_lease (
+    id         INTEGER PRIMARY KEY CHECK (id = 1),
+    holder     TEXT NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL
+);
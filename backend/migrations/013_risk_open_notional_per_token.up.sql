@@ -0,0 +1,6 @@
+-- Open notional caps are per token, each in its own base units: a JSON
+-- object of token address to amount. A single cap cannot be assigned to a
+-- token, so existing ones are dropped and those addresses fall back to the
+-- global caps.
+ALTER TABLE risk_overrides
+    ALTER COLUMN max_open_notional TYPE JSONB USING NULL;
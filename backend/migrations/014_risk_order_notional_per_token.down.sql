@@ -0,0 +1,2 @@
+ALTER TABLE risk_overrides
+    ALTER COLUMN max_order_notional TYPE NUMERIC(78,0) USING NULL;
@@ -0,0 +1,6 @@
+-- Order notional caps are per quote token, each in its own base units, like
+-- open notional caps. A single cap cannot be assigned to a token, so
+-- existing ones are dropped and those addresses fall back to the global
+-- caps.
+ALTER TABLE risk_overrides
+    ALTER COLUMN max_order_notional TYPE JSONB USING NULL;
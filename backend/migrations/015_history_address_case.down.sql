@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_trades_seller_created;
+DROP INDEX IF EXISTS idx_trades_buyer_created;
+DROP INDEX IF EXISTS idx_orders_maker_created;
+CREATE INDEX IF NOT EXISTS idx_orders_maker_created ON orders(maker, created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_trades_buyer_created ON trades(buyer, created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_trades_seller_created ON trades(seller, created_at DESC, id DESC);
@@ -0,0 +1,8 @@
+-- History filters match addresses in any case, so the keyset indexes are
+-- on the lowercased addresses
+DROP INDEX IF EXISTS idx_orders_maker_created;
+DROP INDEX IF EXISTS idx_trades_buyer_created;
+DROP INDEX IF EXISTS idx_trades_seller_created;
+CREATE INDEX IF NOT EXISTS idx_orders_maker_created ON orders(LOWER(maker), created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_trades_buyer_created ON trades(LOWER(buyer), created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_trades_seller_created ON trades(LOWER(seller), created_at DESC, id DESC);
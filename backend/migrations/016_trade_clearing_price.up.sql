@@ -0,0 +1,3 @@
+-- Auction fills settle at their seller's price; the clearing price of the
+-- auction is kept beside it for candles and the ticker
+ALTER TABLE trades ADD COLUMN IF NOT EXISTS clearing_price DOUBLE PRECISION;
@@ -0,0 +1,8 @@
+// Package migrations embeds the SQL schema migrations. Each version has a
+// NNN_name.up.sql file and a matching NNN_name.down.sql file that reverts it.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var Files embed.FS
@@ -11,6 +11,12 @@ var OrderTypeHash = crypto.Keccak256Hash([]byte(
 	"Order(address maker,address tokenSell,address tokenBuy,uint256 amountSell,uint256 amountBuy,uint256 expiry,uint256 nonce,uint256 salt)",
 ))
 
+// LoginTypeHash is the type of the message a wallet signs to authenticate a
+// WebSocket connection; the nonce is a challenge issued by the server.
+var LoginTypeHash = crypto.Keccak256Hash([]byte(
+	"Login(address account,bytes32 nonce)",
+))
+
 type DomainSeparator struct {
 	Name              string
 	Version           string
@@ -65,6 +71,19 @@ func HashOrder(order OrderData) common.Hash {
 	)
 }
 
+type LoginData struct {
+	Account common.Address
+	Nonce   common.Hash
+}
+
+func HashLogin(login LoginData) common.Hash {
+	return crypto.Keccak256Hash(
+		LoginTypeHash.Bytes(),
+		common.LeftPadBytes(login.Account.Bytes(), 32),
+		login.Nonce.Bytes(),
+	)
+}
+
 func HashTypedData(domainSep common.Hash, structHash common.Hash) common.Hash {
 	return crypto.Keccak256Hash(
 		[]byte("\x19\x01"),
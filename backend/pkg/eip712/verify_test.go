@@ -107,3 +107,34 @@ func TestVerifyOrderSignature_WrongMaker(t *testing.T) {
 		t.Fatal("signature should NOT be valid (wrong maker)")
 	}
 }
+
+func TestRecoverSigner_Login(t *testing.T) {
+	privateKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	account := crypto.PubkeyToAddress(privateKey.PublicKey)
+	domain := NewDomainSeparator(big.NewInt(31337), common.HexToAddress("0x5FbDB2315678afecb367f032d93F642f64180aa3"))
+
+	login := LoginData{Account: account, Nonce: crypto.Keccak256Hash([]byte("challenge"))}
+	digest := HashTypedData(domain.Hash(), HashLogin(login))
+	sig, err := crypto.Sign(digest.Bytes(), privateKey)
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	sig[64] += 27 // wallets return v as 27/28
+
+	signer, err := RecoverSigner(digest, sig)
+	if err != nil {
+		t.Fatalf("recover failed: %v", err)
+	}
+	if signer != account {
+		t.Fatalf("expected signer %s, got %s", account.Hex(), signer.Hex())
+	}
+
+	// The same signature does not log in with another challenge
+	other := HashTypedData(domain.Hash(), HashLogin(LoginData{Account: account, Nonce: crypto.Keccak256Hash([]byte("other"))}))
+	if signer, err := RecoverSigner(other, sig); err == nil && signer == account {
+		t.Fatal("signature should not match another nonce")
+	}
+}
@@ -0,0 +1,166 @@
+// Package siwe parses and verifies Sign-In with Ethereum (EIP-4361)
+// messages.
+package siwe
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/nexus-orderbook-dex/backend/pkg/eip712"
+)
+
+const header = " wants you to sign in with your Ethereum account:"
+
+// Message is a parsed EIP-4361 message. Optional times are zero when absent.
+type Message struct {
+	Domain         string
+	Address        common.Address
+	Statement      string
+	URI            string
+	Version        string
+	ChainID        int64
+	Nonce          string
+	IssuedAt       time.Time
+	ExpirationTime time.Time
+	NotBefore      time.Time
+	RequestID      string
+	Resources      []string
+}
+
+// Parse reads a message in the EIP-4361 text format.
+func Parse(text string) (*Message, error) {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	if len(lines) < 4 || !strings.HasSuffix(lines[0], header) {
+		return nil, fmt.Errorf("not a sign-in message")
+	}
+	m := &Message{Domain: strings.TrimSuffix(lines[0], header)}
+	if m.Domain == "" {
+		return nil, fmt.Errorf("missing domain")
+	}
+	if !common.IsHexAddress(lines[1]) {
+		return nil, fmt.Errorf("invalid address %q", lines[1])
+	}
+	m.Address = common.HexToAddress(lines[1])
+	if lines[2] != "" {
+		return nil, fmt.Errorf("expected an empty line after the address")
+	}
+
+	// An optional statement sits between two empty lines
+	rest := lines[3:]
+	if rest[0] != "" {
+		if len(rest) < 2 || rest[1] != "" {
+			return nil, fmt.Errorf("expected an empty line after the statement")
+		}
+		m.Statement = rest[0]
+		rest = rest[2:]
+	} else {
+		rest = rest[1:]
+	}
+
+	var err error
+	for i := 0; i < len(rest); i++ {
+		line := rest[i]
+		if line == "Resources:" {
+			for _, r := range rest[i+1:] {
+				if !strings.HasPrefix(r, "- ") {
+					return nil, fmt.Errorf("invalid resource %q", r)
+				}
+				m.Resources = append(m.Resources, strings.TrimPrefix(r, "- "))
+			}
+			break
+		}
+		key, value, ok := strings.Cut(line, ": ")
+		if !ok {
+			return nil, fmt.Errorf("invalid line %q", line)
+		}
+		switch key {
+		case "URI":
+			m.URI = value
+		case "Version":
+			m.Version = value
+		case "Chain ID":
+			if m.ChainID, err = strconv.ParseInt(value, 10, 64); err != nil {
+				return nil, fmt.Errorf("invalid chain ID %q", value)
+			}
+		case "Nonce":
+			m.Nonce = value
+		case "Issued At":
+			m.IssuedAt, err = time.Parse(time.RFC3339, value)
+		case "Expiration Time":
+			m.ExpirationTime, err = time.Parse(time.RFC3339, value)
+		case "Not Before":
+			m.NotBefore, err = time.Parse(time.RFC3339, value)
+		case "Request ID":
+			m.RequestID = value
+		default:
+			return nil, fmt.Errorf("unknown field %q", key)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", key, err)
+		}
+	}
+
+	switch {
+	case m.URI == "":
+		return nil, fmt.Errorf("missing URI")
+	case m.Version != "1":
+		return nil, fmt.Errorf("unsupported version %q", m.Version)
+	case m.ChainID == 0:
+		return nil, fmt.Errorf("missing chain ID")
+	case len(m.Nonce) < 8:
+		return nil, fmt.Errorf("nonce must have at least 8 characters")
+	case m.IssuedAt.IsZero():
+		return nil, fmt.Errorf("missing issued at")
+	}
+	return m, nil
+}
+
+// String formats the message as the text the wallet signs.
+func (m *Message) String() string {
+	var b strings.Builder
+	b.WriteString(m.Domain + header + "\n")
+	b.WriteString(m.Address.Hex() + "\n\n")
+	if m.Statement != "" {
+		b.WriteString(m.Statement + "\n")
+	}
+	b.WriteString("\n")
+	fmt.Fprintf(&b, "URI: %s\nVersion: %s\nChain ID: %d\nNonce: %s\nIssued At: %s",
+		m.URI, m.Version, m.ChainID, m.Nonce, m.IssuedAt.UTC().Format(time.RFC3339))
+	if !m.ExpirationTime.IsZero() {
+		b.WriteString("\nExpiration Time: " + m.ExpirationTime.UTC().Format(time.RFC3339))
+	}
+	if !m.NotBefore.IsZero() {
+		b.WriteString("\nNot Before: " + m.NotBefore.UTC().Format(time.RFC3339))
+	}
+	if m.RequestID != "" {
+		b.WriteString("\nRequest ID: " + m.RequestID)
+	}
+	if len(m.Resources) > 0 {
+		b.WriteString("\nResources:")
+		for _, r := range m.Resources {
+			b.WriteString("\n- " + r)
+		}
+	}
+	return b.String()
+}
+
+// Valid reports whether the message may be used at now.
+func (m *Message) Valid(now time.Time) error {
+	if !m.ExpirationTime.IsZero() && !now.Before(m.ExpirationTime) {
+		return fmt.Errorf("message expired")
+	}
+	if !m.NotBefore.IsZero() && now.Before(m.NotBefore) {
+		return fmt.Errorf("message not valid yet")
+	}
+	return nil
+}
+
+// RecoverSigner returns the address that signed text with personal_sign.
+func RecoverSigner(text string, signature []byte) (common.Address, error) {
+	return eip712.RecoverSigner(common.BytesToHash(accounts.TextHash([]byte(text))), signature)
+}
@@ -0,0 +1,95 @@
+package siwe
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const sample = `localhost:3000 wants you to sign in with your Ethereum account:
+0x70997970C51812dc3A010C7d01b50e0d17dc79C8
+
+Sign in to Nexus.
+
+URI: http://localhost:3000
+Version: 1
+Chain ID: 31337
+Nonce: 4f3c9b2a1d8e7f60
+Issued At: 2026-01-02T03:04:05Z
+Expiration Time: 2026-01-03T03:04:05Z`
+
+func TestParse_RoundTrips(t *testing.T) {
+	m, err := Parse(sample)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if m.Domain != "localhost:3000" || m.ChainID != 31337 || m.Nonce != "4f3c9b2a1d8e7f60" || m.Statement != "Sign in to Nexus." {
+		t.Fatalf("unexpected message %+v", m)
+	}
+	if got := m.String(); got != sample {
+		t.Fatalf("expected the original text, got\n%s", got)
+	}
+
+	m.Statement = ""
+	again, err := Parse(m.String())
+	if err != nil || again.Statement != "" || again.Nonce != m.Nonce {
+		t.Fatalf("message without statement: %+v (%v)", again, err)
+	}
+
+	if err := m.Valid(m.ExpirationTime); err == nil {
+		t.Fatal("expected the message to expire at its expiration time")
+	}
+	if err := m.Valid(m.IssuedAt.Add(time.Hour)); err != nil {
+		t.Fatalf("expected the message to be valid: %v", err)
+	}
+}
+
+func TestParse_RejectsMalformedMessages(t *testing.T) {
+	for name, text := range map[string]string{
+		"header": "hello",
+		"version": `localhost wants you to sign in with your Ethereum account:
+0x70997970C51812dc3A010C7d01b50e0d17dc79C8
+
+
+URI: http://localhost
+Version: 2
+Chain ID: 1
+Nonce: 12345678
+Issued At: 2026-01-02T03:04:05Z`,
+		"nonce": `localhost wants you to sign in with your Ethereum account:
+0x70997970C51812dc3A010C7d01b50e0d17dc79C8
+
+
+URI: http://localhost
+Version: 1
+Chain ID: 1
+Nonce: 123
+Issued At: 2026-01-02T03:04:05Z`,
+	} {
+		if _, err := Parse(text); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestRecoverSigner(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := crypto.Sign(accounts.TextHash([]byte(sample)), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig[64] += 27 // as wallets return it
+
+	signer, err := RecoverSigner(sample, sig)
+	if err != nil {
+		t.Fatalf("recover: %v", err)
+	}
+	if signer != crypto.PubkeyToAddress(key.PublicKey) {
+		t.Fatalf("recovered %s", signer.Hex())
+	}
+}
@@ -49,6 +49,9 @@ func main() {
 	fmt.Println("Domain Separator:", domainSep.Hex())
 	fmt.Println()
 
+	// Private endpoints act for the signed-in address
+	sellerToken, buyerToken := signIn(sellerKey), signIn(buyerKey)
+
 	// 1. Submit a sell order (seller sells 50 TKA for 100 TKB, price = 2)
 	fmt.Println("1. Submitting sell order (50 TKA @ 2 TKB/TKA)...")
 	sellOrder := createOrder(
@@ -60,7 +63,7 @@ func main() {
 		1,
 	)
 	sellSig := signOrder(sellOrder, sellerKey, domainSep)
-	resp := submitOrder(sellOrder, sellSig, "sell")
+	resp := submitOrder(sellOrder, sellSig, "sell", sellerToken)
 	fmt.Println("   Response:", resp)
 	fmt.Println()
 
@@ -75,7 +78,7 @@ func main() {
 		2,
 	)
 	sellSig2 := signOrder(sellOrder2, sellerKey, domainSep)
-	resp = submitOrder(sellOrder2, sellSig2, "sell")
+	resp = submitOrder(sellOrder2, sellSig2, "sell", sellerToken)
 	fmt.Println("   Response:", resp)
 	fmt.Println()
 
@@ -97,7 +100,7 @@ func main() {
 		1,
 	)
 	buySig := signOrder(buyOrder, buyerKey, domainSep)
-	resp = submitOrder(buyOrder, buySig, "buy")
+	resp = submitOrder(buyOrder, buySig, "buy", buyerToken)
 	fmt.Println("   Response:", resp)
 	fmt.Println()
 
@@ -118,7 +121,7 @@ func main() {
 
 	// 7. Check user orders
 	fmt.Println("7. User orders for seller...")
-	orders := getUserOrders(seller.Hex())
+	orders := getUserOrders(seller.Hex(), sellerToken)
 	for _, o := range orders {
 		m := o.(map[string]interface{})
 		fmt.Printf("   Order ID=%s, side=%s, status=%s\n", m["id"], m["side"], m["status"])
@@ -193,7 +196,7 @@ func computeDomainSeparator() common.Hash {
 	)
 }
 
-func submitOrder(o orderData, sig, side string) string {
+func submitOrder(o orderData, sig, side, token string) string {
 	body := map[string]interface{}{
 		"maker":      o.maker.Hex(),
 		"tokenSell":  o.tokenSell.Hex(),
@@ -208,7 +211,7 @@ func submitOrder(o orderData, sig, side string) string {
 		"pair":       "TKA-TKB",
 	}
 	jsonBody, _ := json.Marshal(body)
-	resp, err := http.Post(apiURL+"/api/orders", "application/json", bytes.NewReader(jsonBody))
+	resp, err := authorized("POST", apiURL+"/api/orders", token, bytes.NewReader(jsonBody))
 	if err != nil {
 		return "error: " + err.Error()
 	}
@@ -225,6 +228,49 @@ func getOrderbook() map[string]interface{} {
 	return result
 }
 
+// signIn logs key in with Sign-In with Ethereum and returns the session
+// token that private endpoints require.
+func signIn(key *ecdsa.PrivateKey) string {
+	resp, err := http.Post(apiURL+"/api/auth/nonce", "application/json", nil)
+	if err != nil {
+		panic(err)
+	}
+	var nonce struct{ Nonce string }
+	json.NewDecoder(resp.Body).Decode(&nonce)
+	resp.Body.Close()
+
+	message := fmt.Sprintf("localhost:3000 wants you to sign in with your Ethereum account:\n%s\n\n\n"+
+		"URI: http://localhost:3000\nVersion: 1\nChain ID: 31337\nNonce: %s\nIssued At: %s",
+		crypto.PubkeyToAddress(key.PublicKey).Hex(), nonce.Nonce, time.Now().UTC().Format(time.RFC3339))
+	prefixed := fmt.Sprintf("\x19Ethereum Signed Message:\n%d%s", len(message), message)
+	sig, _ := crypto.Sign(crypto.Keccak256([]byte(prefixed)), key)
+	sig[64] += 27
+
+	body, _ := json.Marshal(map[string]string{"message": message, "signature": "0x" + hex.EncodeToString(sig)})
+	resp, err = http.Post(apiURL+"/api/auth/login", "application/json", bytes.NewReader(body))
+	if err != nil {
+		panic(err)
+	}
+	defer resp.Body.Close()
+	var session struct{ Token string }
+	json.NewDecoder(resp.Body).Decode(&session)
+	if session.Token == "" {
+		panic("sign-in failed")
+	}
+	return session.Token
+}
+
+// authorized sends a request with a session token.
+func authorized(method, url, token string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	return http.DefaultClient.Do(req)
+}
+
 func getTrades() []interface{} {
 	resp, _ := http.Get(apiURL + "/api/trades?pair=TKA-TKB")
 	defer resp.Body.Close()
@@ -233,8 +279,8 @@ func getTrades() []interface{} {
 	return result
 }
 
-func getUserOrders(addr string) []interface{} {
-	resp, _ := http.Get(apiURL + "/api/orders/" + addr)
+func getUserOrders(addr, token string) []interface{} {
+	resp, _ := authorized("GET", apiURL+"/api/orders/"+addr, token, nil)
 	defer resp.Body.Close()
 	var result []interface{}
 	json.NewDecoder(resp.Body).Decode(&result)
@@ -40,6 +40,7 @@ func main() {
 	seller := crypto.PubkeyToAddress(sellerKey.PublicKey)
 
 	domainSep := computeDomainSeparator()
+	sellerToken, buyerToken := signIn(sellerKey), signIn(buyerKey)
 
 	// Use unique salts for new orders
 	salt := time.Now().UnixNano()
@@ -48,7 +49,7 @@ func main() {
 	fmt.Println("1. Seller submits: Sell 100 TKA @ 2 TKB/TKA")
 	sellOrder := createOrder(seller, common.HexToAddress(tokenA), common.HexToAddress(tokenB), parseEther("100"), parseEther("200"), salt)
 	sellSig := signOrder(sellOrder, sellerKey, domainSep)
-	resp := submitOrder(sellOrder, sellSig, "sell")
+	resp := submitOrder(sellOrder, sellSig, "sell", sellerToken)
 	fmt.Println("   Response:", truncate(resp, 100))
 	fmt.Println()
 
@@ -56,7 +57,7 @@ func main() {
 	fmt.Println("2. Buyer submits: Buy 100 TKA @ 2 TKB/TKA")
 	buyOrder := createOrder(buyer, common.HexToAddress(tokenB), common.HexToAddress(tokenA), parseEther("200"), parseEther("100"), salt+1)
 	buySig := signOrder(buyOrder, buyerKey, domainSep)
-	resp = submitOrder(buyOrder, buySig, "buy")
+	resp = submitOrder(buyOrder, buySig, "buy", buyerToken)
 	fmt.Println("   Response:", truncate(resp, 100))
 	fmt.Println()
 
@@ -169,7 +170,7 @@ func computeDomainSeparator() common.Hash {
 	)
 }
 
-func submitOrder(o orderData, sig, side string) string {
+func submitOrder(o orderData, sig, side, token string) string {
 	body := map[string]interface{}{
 		"maker":      o.maker.Hex(),
 		"tokenSell":  o.tokenSell.Hex(),
@@ -184,7 +185,7 @@ func submitOrder(o orderData, sig, side string) string {
 		"pair":       "TKA-TKB",
 	}
 	jsonBody, _ := json.Marshal(body)
-	resp, err := http.Post(apiURL+"/api/orders", "application/json", bytes.NewReader(jsonBody))
+	resp, err := authorized("POST", apiURL+"/api/orders", token, bytes.NewReader(jsonBody))
 	if err != nil {
 		return "error: " + err.Error()
 	}
@@ -193,6 +194,49 @@ func submitOrder(o orderData, sig, side string) string {
 	return string(data)
 }
 
+// signIn logs key in with Sign-In with Ethereum and returns the session
+// token that private endpoints require.
+func signIn(key *ecdsa.PrivateKey) string {
+	resp, err := http.Post(apiURL+"/api/auth/nonce", "application/json", nil)
+	if err != nil {
+		panic(err)
+	}
+	var nonce struct{ Nonce string }
+	json.NewDecoder(resp.Body).Decode(&nonce)
+	resp.Body.Close()
+
+	message := fmt.Sprintf("localhost:3000 wants you to sign in with your Ethereum account:\n%s\n\n\n"+
+		"URI: http://localhost:3000\nVersion: 1\nChain ID: 31337\nNonce: %s\nIssued At: %s",
+		crypto.PubkeyToAddress(key.PublicKey).Hex(), nonce.Nonce, time.Now().UTC().Format(time.RFC3339))
+	prefixed := fmt.Sprintf("\x19Ethereum Signed Message:\n%d%s", len(message), message)
+	sig, _ := crypto.Sign(crypto.Keccak256([]byte(prefixed)), key)
+	sig[64] += 27
+
+	body, _ := json.Marshal(map[string]string{"message": message, "signature": "0x" + hex.EncodeToString(sig)})
+	resp, err = http.Post(apiURL+"/api/auth/login", "application/json", bytes.NewReader(body))
+	if err != nil {
+		panic(err)
+	}
+	defer resp.Body.Close()
+	var session struct{ Token string }
+	json.NewDecoder(resp.Body).Decode(&session)
+	if session.Token == "" {
+		panic("sign-in failed")
+	}
+	return session.Token
+}
+
+// authorized sends a request with a session token.
+func authorized(method, url, token string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	return http.DefaultClient.Do(req)
+}
+
 func getTrades() []interface{} {
 	resp, _ := http.Get(apiURL + "/api/trades?pair=TKA-TKB")
 	defer resp.Body.Close()
@@ -4,11 +4,13 @@ import { useAccount } from "wagmi";
 import { formatEther } from "viem";
 import { useUserOrdersQuery } from "@/hooks/useOrderbook";
 import { cancelOrder } from "@/lib/api";
+import { useSession } from "@/hooks/useSession";
 import { useState } from "react";
 
 export function UserOrders() {
   const { address } = useAccount();
   const { data: orders, refetch } = useUserOrdersQuery(address);
+  const { session, ensureSession } = useSession();
   const [cancelling, setCancelling] = useState<string | null>(null);
 
   if (!address) return null;
@@ -16,6 +18,7 @@ export function UserOrders() {
   const handleCancel = async (id: string) => {
     setCancelling(id);
     try {
+      await ensureSession();
       await cancelOrder(id);
       refetch();
     } catch (err) {
@@ -31,7 +34,17 @@ export function UserOrders() {
 
   return (
     <div className="bg-gray-800 rounded-xl p-4">
-      <h2 className="text-lg font-semibold mb-3">Your Orders</h2>
+      <div className="flex items-center justify-between mb-3">
+        <h2 className="text-lg font-semibold">Your Orders</h2>
+        {!session && (
+          <button
+            onClick={() => ensureSession().catch(console.error)}
+            className="text-xs text-blue-400 hover:text-blue-300"
+          >
+            Sign in to view
+          </button>
+        )}
+      </div>
 
       <div className="overflow-x-auto">
         <table className="w-full text-sm">
@@ -2,9 +2,11 @@
 
 import { formatEther } from "viem";
 import { useTradesQuery } from "@/hooks/useOrderbook";
+import { useTradeStream } from "@/hooks/useTradeStream";
 
 export function TradeHistory() {
   const { data: trades } = useTradesQuery();
+  useTradeStream();
 
   return (
     <div className="bg-gray-800 rounded-xl p-4">
@@ -2,6 +2,7 @@
 
 import { useQuery } from "@tanstack/react-query";
 import { getOrderbook, getTrades, getUserOrders } from "@/lib/api";
+import { useSession } from "@/hooks/useSession";
 
 export function useOrderbookQuery(pair: string = "TKA-TKB") {
   return useQuery({
@@ -15,15 +16,16 @@ export function useTradesQuery(pair: string = "TKA-TKB") {
   return useQuery({
     queryKey: ["trades", pair],
     queryFn: () => getTrades(pair),
-    refetchInterval: 5000,
   });
 }
 
+// Order history is private, so it loads once the wallet has signed in.
 export function useUserOrdersQuery(address: string | undefined) {
+  const { session } = useSession();
   return useQuery({
-    queryKey: ["userOrders", address],
+    queryKey: ["userOrders", address, session?.token],
     queryFn: () => getUserOrders(address!),
-    enabled: !!address,
+    enabled: !!address && !!session,
     refetchInterval: 5000,
   });
 }
@@ -0,0 +1,45 @@
+"use client";
+
+import { useCallback, useSyncExternalStore } from "react";
+import { useAccount, useSignMessage } from "wagmi";
+import { createSiweMessage } from "viem/siwe";
+import { getNonce, login } from "@/lib/api";
+import { getSession, setSession, subscribeSession } from "@/lib/session";
+
+const CHAIN_ID = Number(process.env.NEXT_PUBLIC_CHAIN_ID || 31337);
+
+// useSession signs the connected wallet in with Sign-In with Ethereum.
+// Private endpoints need the session; ensureSession asks the wallet to sign
+// only when there is no live session for the connected address.
+export function useSession() {
+  const { address } = useAccount();
+  const { signMessageAsync } = useSignMessage();
+  const session = useSyncExternalStore(
+    subscribeSession,
+    () => getSession(address),
+    () => null
+  );
+
+  const ensureSession = useCallback(async () => {
+    if (!address) throw new Error("Wallet not connected");
+    const existing = getSession(address);
+    if (existing) return existing.token;
+
+    const { nonce } = await getNonce();
+    const message = createSiweMessage({
+      address,
+      chainId: CHAIN_ID,
+      domain: window.location.host,
+      nonce,
+      uri: window.location.origin,
+      version: "1",
+      statement: "Sign in to Nexus Orderbook DEX.",
+    });
+    const signature = await signMessageAsync({ message });
+    const { token, expiresAt } = await login(message, signature);
+    setSession({ address, token, expiresAt });
+    return token;
+  }, [address, signMessageAsync]);
+
+  return { session, ensureSession };
+}
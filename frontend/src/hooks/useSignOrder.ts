@@ -6,6 +6,7 @@ import { parseEther } from "viem";
 import { EIP712_DOMAIN, ORDER_TYPES } from "@/lib/eip712";
 import { ADDRESSES } from "@/lib/contracts";
 import { submitOrder } from "@/lib/api";
+import { useSession } from "@/hooks/useSession";
 import type { Side, OrderSubmission } from "@/types";
 
 interface SignOrderParams {
@@ -18,10 +19,12 @@ interface SignOrderParams {
 export function useSignOrder() {
   const { address } = useAccount();
   const { signTypedDataAsync } = useSignTypedData();
+  const { ensureSession } = useSession();
 
   const signAndSubmit = useCallback(
     async ({ side, price, amount, pair = "TKA-TKB" }: SignOrderParams) => {
       if (!address) throw new Error("Wallet not connected");
+      await ensureSession();
 
       const baseAmount = parseEther(amount);
       const priceNum = parseFloat(price);
@@ -73,7 +76,7 @@ export function useSignOrder() {
 
       return submitOrder(submission);
     },
-    [address, signTypedDataAsync]
+    [address, signTypedDataAsync, ensureSession]
   );
 
   return { signAndSubmit };
@@ -0,0 +1,87 @@
+"use client";
+
+import { useEffect } from "react";
+import { useQueryClient } from "@tanstack/react-query";
+import type { Trade } from "@/types";
+
+const WS_URL = process.env.NEXT_PUBLIC_WS_URL || "ws://localhost:8080";
+const MAX_TRADES = 50;
+
+// Keeps the trades query of a pair up to date from its trades channel.
+export function useTradeStream(pair: string = "TKA-TKB") {
+  const queryClient = useQueryClient();
+
+  useEffect(() => {
+    let ws: WebSocket | null = null;
+    let reconnect: ReturnType<typeof setTimeout> | null = null;
+    let closed = false;
+
+    const update = (fn: (trades: Trade[]) => Trade[]) =>
+      queryClient.setQueryData<Trade[]>(["trades", pair], (trades) =>
+        fn(trades || [])
+      );
+
+    const connect = () => {
+      ws = new WebSocket(`${WS_URL}/ws?pair=${pair}&channel=trades`);
+
+      ws.onopen = () => {
+        // Catch up on trades made while disconnected
+        queryClient.invalidateQueries({ queryKey: ["trades", pair] });
+      };
+
+      ws.onmessage = (event) => {
+        try {
+          const data = JSON.parse(event.data);
+          if (data.type === "trade") {
+            update((trades) =>
+              trades.some((t) => t.id === data.id)
+                ? trades
+                : [
+                    {
+                      id: data.id,
+                      buyOrderId: "",
+                      sellOrderId: "",
+                      buyer: "",
+                      seller: "",
+                      pair: data.pair,
+                      baseAmount: data.size,
+                      quoteAmount: data.quoteSize,
+                      price: data.price,
+                      txHash: "",
+                      settledOnChain: false,
+                      createdAt: data.time,
+                    },
+                    ...trades,
+                  ].slice(0, MAX_TRADES)
+            );
+          } else if (data.type === "trade_settled") {
+            update((trades) =>
+              trades.map((t) =>
+                t.id === data.id
+                  ? { ...t, txHash: data.txHash, settledOnChain: true }
+                  : t
+              )
+            );
+          }
+        } catch {
+          // ignore parse errors
+        }
+      };
+
+      ws.onclose = () => {
+        if (!closed) reconnect = setTimeout(connect, 2000);
+      };
+
+      ws.onerror = () => {
+        ws?.close();
+      };
+    };
+
+    connect();
+    return () => {
+      closed = true;
+      if (reconnect) clearTimeout(reconnect);
+      ws?.close();
+    };
+  }, [pair, queryClient]);
+}
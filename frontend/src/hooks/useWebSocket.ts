@@ -1,10 +1,26 @@
 "use client";
 
 import { useEffect, useRef, useCallback, useState } from "react";
-import type { OrderbookSnapshot } from "@/types";
+import type { OrderbookSnapshot, PriceLevel } from "@/types";
 
 const WS_URL = process.env.NEXT_PUBLIC_WS_URL || "ws://localhost:8080";
 
+// Replaces each changed level by price; an amount of "0" removes it.
+function applyLevels(
+  levels: PriceLevel[],
+  changes: PriceLevel[],
+  descending: boolean
+): PriceLevel[] {
+  const byPrice = new Map(levels.map((l) => [l.price, l]));
+  for (const change of changes) {
+    if (change.amount === "0") byPrice.delete(change.price);
+    else byPrice.set(change.price, change);
+  }
+  return Array.from(byPrice.values()).sort((a, b) =>
+    descending ? b.price - a.price : a.price - b.price
+  );
+}
+
 export function useWebSocket(pair: string = "TKA-TKB") {
   const wsRef = useRef<WebSocket | null>(null);
   const reconnectTimeout = useRef<ReturnType<typeof setTimeout> | null>(null);
@@ -13,6 +29,8 @@ export function useWebSocket(pair: string = "TKA-TKB") {
     asks: [],
   });
   const [connected, setConnected] = useState(false);
+  // Sequence the book is at; null while waiting for a snapshot
+  const seqRef = useRef<number | null>(null);
 
   const connect = useCallback(() => {
     if (wsRef.current?.readyState === WebSocket.OPEN) return;
@@ -21,17 +39,33 @@ export function useWebSocket(pair: string = "TKA-TKB") {
     wsRef.current = ws;
 
     ws.onopen = () => {
+      seqRef.current = null;
       setConnected(true);
     };
 
     ws.onmessage = (event) => {
       try {
         const data = JSON.parse(event.data);
-        if (data.type === "snapshot" || data.type === "orderbook") {
+        if (data.type === "snapshot") {
+          seqRef.current = data.seq;
           setOrderbook({
             bids: data.bids || [],
             asks: data.asks || [],
           });
+        } else if (data.type === "l2") {
+          const seq = seqRef.current;
+          if (seq === null || data.seq <= seq) return;
+          if (data.prevSeq !== seq) {
+            // Missed an update: wait for a fresh snapshot
+            seqRef.current = null;
+            ws.send(JSON.stringify({ type: "resync" }));
+            return;
+          }
+          seqRef.current = data.seq;
+          setOrderbook((book) => ({
+            bids: applyLevels(book.bids, data.bids || [], true),
+            asks: applyLevels(book.asks, data.asks || [], false),
+          }));
         }
       } catch {
         // ignore parse errors
@@ -1,12 +1,17 @@
 import type { Order, OrderSubmission, OrderbookSnapshot, Trade } from "@/types";
+import { getSession, setSession } from "@/lib/session";
 
 const API_URL = process.env.NEXT_PUBLIC_API_URL || "http://localhost:8080";
 
 async function fetchJSON<T>(path: string, options?: RequestInit): Promise<T> {
-  const res = await fetch(`${API_URL}${path}`, {
-    headers: { "Content-Type": "application/json" },
-    ...options,
-  });
+  const headers: Record<string, string> = { "Content-Type": "application/json" };
+  const session = getSession();
+  if (session) headers.Authorization = `Bearer ${session.token}`;
+  const res = await fetch(`${API_URL}${path}`, { headers, ...options });
+  if (res.status === 401 && session) {
+    // The session expired or was revoked; sign in again on the next action
+    setSession(null);
+  }
   if (!res.ok) {
     const body = await res.json().catch(() => ({ error: res.statusText }));
     throw new Error(body.error || res.statusText);
@@ -14,6 +19,20 @@ async function fetchJSON<T>(path: string, options?: RequestInit): Promise<T> {
   return res.json();
 }
 
+export async function getNonce(): Promise<{ nonce: string }> {
+  return fetchJSON("/api/auth/nonce", { method: "POST" });
+}
+
+export async function login(
+  message: string,
+  signature: string
+): Promise<{ token: string; address: string; expiresAt: string }> {
+  return fetchJSON("/api/auth/login", {
+    method: "POST",
+    body: JSON.stringify({ message, signature }),
+  });
+}
+
 export async function submitOrder(
   order: OrderSubmission
 ): Promise<{ order: Order; matches: number }> {
@@ -0,0 +1,50 @@
+// The signed-in session, shared by every component and kept across reloads.
+
+export interface Session {
+  address: string;
+  token: string;
+  expiresAt: string;
+}
+
+const STORAGE_KEY = "nexus.session";
+
+let current: Session | null = load();
+const listeners = new Set<() => void>();
+
+function load(): Session | null {
+  if (typeof window === "undefined") return null;
+  try {
+    const raw = window.localStorage.getItem(STORAGE_KEY);
+    return raw ? (JSON.parse(raw) as Session) : null;
+  } catch {
+    return null;
+  }
+}
+
+/** Returns the live session, of `address` when given. */
+export function getSession(address?: string): Session | null {
+  if (!current || Date.parse(current.expiresAt) <= Date.now()) return null;
+  if (address && current.address.toLowerCase() !== address.toLowerCase()) {
+    return null;
+  }
+  return current;
+}
+
+export function setSession(session: Session | null) {
+  current = session;
+  if (typeof window !== "undefined") {
+    if (session) {
+      window.localStorage.setItem(STORAGE_KEY, JSON.stringify(session));
+    } else {
+      window.localStorage.removeItem(STORAGE_KEY);
+    }
+  }
+  listeners.forEach((listener) => listener());
+}
+
+export function subscribeSession(listener: () => void) {
+  listeners.add(listener);
+  return () => {
+    listeners.delete(listener);
+  };
+}